@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Only explicit references are expanded, so that a literal $ in a value is kept
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+type manifestChange struct {
+	Action  string `json:"action"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Details string `json:"details"`
+	Result  string `json:"result"`
+}
+
+type manifestApplyResponse struct {
+	Message string           `json:"message"`
+	Applied bool             `json:"applied"`
+	Changes []manifestChange `json:"changes"`
+}
+
+// runApply sends a project manifest to the server. References to environment
+// variables in the manifest (e.g. ${PULL_SECRET_PASSWORD}) are expanded, so that
+// secrets don't have to be stored in Git.
+func runApply(args []string, sso ssoConfig, loginMethod string) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	plan := fs.Bool("plan", false, "Only show the changes, don't apply them")
+	prune := fs.Bool("prune", false, "Remove admins, service accounts and pull secrets not listed in the manifest")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	manifest, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	manifest, err = expandEnvReferences(manifest)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	u := getURL(fs.Arg(1))
+	u.Path = "/api/ose/project/apply"
+	u.RawQuery = fmt.Sprintf("plan=%v&prune=%v", *plan, *prune)
+
+	sso, err = getSSOConfig(u, sso)
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := getToken(u, sso, loginMethod)
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := request(u, "POST", token.AccessToken, bytes.NewReader(manifest))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var resp manifestApplyResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		log.Fatal(string(body))
+	}
+	for _, c := range resp.Changes {
+		line := fmt.Sprintf("%-7v %-15v %v", c.Action, c.Kind, c.Name)
+		if c.Details != "" {
+			line += " (" + c.Details + ")"
+		}
+		if c.Result != "" {
+			line += ": " + c.Result
+		}
+		fmt.Println(line)
+	}
+	fmt.Println(resp.Message)
+	if !resp.Applied && !*plan && len(resp.Changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+// expandEnvReferences replaces ${VAR} with the value of the environment variable.
+// It fails if a referenced variable isn't set.
+func expandEnvReferences(manifest []byte) ([]byte, error) {
+	var missing []string
+	expanded := envReference.ReplaceAllFunc(manifest, func(ref []byte) []byte {
+		name := string(envReference.FindSubmatch(ref)[1])
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+			return ref
+		}
+		return []byte(value)
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("The manifest references unset environment variables: %v", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
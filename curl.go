@@ -1,47 +1,48 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
-	"golang.org/x/crypto/ssh/terminal"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
-	"syscall"
-	"time"
 )
 
-type Token struct {
-	Expire string `json:"expire"`
-	Token  string `json:"token"`
-}
-
 func main() {
 	flag.Usage = printUsage
 	method := flag.String("X", "GET", "The request method (GET,POST)")
+	loginMethod := flag.String("login", "device", "The login method (device,browser)")
+	var sso ssoConfig
+	flag.StringVar(&sso.URL, "sso-url", os.Getenv("SSP_SSO_URL"), "The SSO url. Defaults to the value provided by the server")
+	flag.StringVar(&sso.Realm, "sso-realm", os.Getenv("SSP_SSO_REALM"), "The SSO realm. Defaults to the value provided by the server")
+	flag.StringVar(&sso.ClientId, "client-id", os.Getenv("SSP_SSO_CLIENT_ID"), "The SSO client id. Defaults to the value provided by the server")
 	flag.Parse()
+	if flag.NArg() > 0 && flag.Arg(0) == "apply" {
+		runApply(flag.Args()[1:], sso, *loginMethod)
+		return
+	}
 	if flag.NArg() != 1 {
 		flag.Usage()
 		os.Exit(1)
 	}
 	u := getURL(flag.Arg(0))
-	token, err := getToken()
+	sso, err := getSSOConfig(u, sso)
 	if err != nil {
-		token, err = login(u)
-		if err != nil {
-			log.Fatal(err)
-		}
+		log.Fatal(err)
 	}
-	request(u, *method, token.Token)
+	token, err := getToken(u, sso, *loginMethod)
 	if err != nil {
 		log.Fatal(err)
 	}
+	body, err := request(u, *method, token.AccessToken, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(body))
 }
 
 func getURL(u string) *url.URL {
@@ -54,78 +55,21 @@ func getURL(u string) *url.URL {
 
 func printUsage() {
 	fmt.Printf("Usage: %s [OPTIONS] <url>\n", os.Args[0])
+	fmt.Printf("       %s [OPTIONS] apply [-plan] [-prune] <manifest> <server>\n", os.Args[0])
 	flag.PrintDefaults()
 }
 
-func request(u *url.URL, method string, token string) error {
+func request(u *url.URL, method string, token string, data io.Reader) ([]byte, error) {
 	client := &http.Client{}
-	req, err := http.NewRequest(method, u.String(), nil)
-	req.Header.Add("Authorization", "Bearer "+token)
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	fmt.Println(string(body))
-	return nil
-}
-
-func promptUsernamePassword() (string, string) {
-	fmt.Print("Enter username: ")
-	var username string
-	fmt.Scanln(&username)
-	fmt.Print("Enter password: ")
-	bytePassword, err := terminal.ReadPassword(int(syscall.Stdin))
-	if err != nil {
-		log.Fatal(err)
-	}
-	fmt.Println() // newline
-	password := string(bytePassword)
-	return username, password
-}
-
-func login(u *url.URL) (*Token, error) {
-	username, password := promptUsernamePassword()
-	values := map[string]string{"Username": username, "Password": password}
-	jsonValue, _ := json.Marshal(values)
-	loginURL := fmt.Sprintf("%s://%s/login", u.Scheme, u.Host)
-	resp, err := http.Post(loginURL, "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	err = ioutil.WriteFile("token.json", body, 0600)
-	if err != nil {
-		return nil, err
-	}
-	var token Token
-	err = json.Unmarshal(body, &token)
-	if err != nil {
-		return nil, err
-	}
-	return &token, nil
-}
-
-func getToken() (*Token, error) {
-	raw, err := ioutil.ReadFile("token.json")
+	req, err := http.NewRequest(method, u.String(), data)
 	if err != nil {
 		return nil, err
 	}
-	var token Token
-	err = json.Unmarshal(raw, &token)
+	req.Header.Add("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	expire, _ := time.Parse(time.RFC3339, token.Expire)
-	if expire.Before(time.Now()) {
-		return nil, errors.New("The token has expired")
-	}
-	return &token, nil
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
 }
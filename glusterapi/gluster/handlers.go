@@ -123,6 +123,19 @@ func VolumeInfoHandler(c *gin.Context) {
 	}
 }
 
+func PoolInfoHandler(c *gin.Context) {
+	poolInfo, err := getPoolUsage()
+	if err != nil {
+		log.Print("Error getting pool information", err.Error())
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	} else {
+		c.JSON(http.StatusOK, poolInfo)
+	}
+}
+
 func CheckVolumeHandler(c *gin.Context) {
 	pvName := c.Param("pvname")
 	threshold := c.Query("threshold")
@@ -131,14 +144,19 @@ func CheckVolumeHandler(c *gin.Context) {
 		return
 	}
 
-	err := checkVolumeUsage(pvName, threshold)
+	volInfo, err := checkVolumeUsage(pvName, threshold)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": err.Error(),
-		})
+		response := gin.H{"message": err.Error()}
+		if volInfo != nil {
+			response["totalKiloBytes"] = volInfo.TotalKiloBytes
+			response["usedKiloBytes"] = volInfo.UsedKiloBytes
+		}
+		c.JSON(http.StatusBadRequest, response)
 	} else {
 		c.JSON(http.StatusOK, gin.H{
-			"message": "Usage is below threshold",
+			"message":        "Usage is below threshold",
+			"totalKiloBytes": volInfo.TotalKiloBytes,
+			"usedKiloBytes":  volInfo.UsedKiloBytes,
 		})
 	}
 }
@@ -70,21 +70,62 @@ func parseOutput(stdOut string) (*models.VolInfo, error) {
 	}, nil
 }
 
-func checkVolumeUsage(pvName string, threshold string) error {
+// checkVolumeUsage returns an error if the usage is above the threshold.
+// The usage is returned as well if it could be read.
+func checkVolumeUsage(pvName string, threshold string) (*models.VolInfo, error) {
 	t, err := strconv.ParseFloat(threshold, 64)
 	if err != nil {
-		return errors.New("Wrong threshold. Is not a valid integer")
+		return nil, errors.New("Wrong threshold. Is not a valid integer")
 	}
 
 	volInfo, err := getVolumeUsage(pvName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	usedPercentage := 100 / float64(volInfo.TotalKiloBytes) * float64(volInfo.UsedKiloBytes)
 	if usedPercentage > t {
-		return fmt.Errorf("Error used %v is bigger than threshold: %v", usedPercentage, t)
+		return volInfo, fmt.Errorf("Error used %v is bigger than threshold: %v", usedPercentage, t)
 	}
 
-	return nil
+	return volInfo, nil
+}
+
+func getPoolUsage() (*models.PoolInfo, error) {
+	cmd := fmt.Sprintf("lvs --noheadings --units k --nosuffix -o lv_size,data_percent %v/%v", VgName, PoolName)
+
+	out, err := ExecRunner.Run("bash", "-c", cmd)
+	if err != nil {
+		msg := "Could not get pool usage: " + err.Error()
+		log.Println(msg)
+		return nil, errors.New(msg)
+	}
+	return parsePoolOutput(string(out))
+}
+
+func parsePoolOutput(stdOut string) (*models.PoolInfo, error) {
+	// Example output
+	//   104853504.00   12.34
+	fields := strings.Fields(stdOut)
+	if len(fields) != 2 {
+		log.Println("Unable to parse lvs output", stdOut)
+		return nil, errors.New(commandExecutionError)
+	}
+
+	size, err := strconv.ParseFloat(strings.Replace(fields[0], ",", ".", -1), 64)
+	if err != nil {
+		log.Println("Unable to parse size value of lvs output", stdOut)
+		return nil, errors.New(commandExecutionError)
+	}
+
+	percent, err := strconv.ParseFloat(strings.Replace(fields[1], ",", ".", -1), 64)
+	if err != nil {
+		log.Println("Unable to parse data percent value of lvs output", stdOut)
+		return nil, errors.New(commandExecutionError)
+	}
+
+	return &models.PoolInfo{
+		TotalKiloBytes: int(size),
+		UsedKiloBytes:  int(size * percent / 100),
+	}, nil
 }
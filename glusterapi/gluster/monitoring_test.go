@@ -36,13 +36,32 @@ func TestGetVolumeUsage_LongProjectHighNumber(t *testing.T) {
 func TestCheckVolumeUsage_OK(t *testing.T) {
 	output = []string{"    49664    2864 /dev/mapper/vg_mylv_project_pv1"}
 
-	err := checkVolumeUsage("gl-project-pv1", "20")
+	volInfo, err := checkVolumeUsage("gl-project-pv1", "20")
 	ok(t, err)
+	equals(t, 2864, volInfo.UsedKiloBytes)
 }
 
 func TestCheckVolumeUsage_Error(t *testing.T) {
 	output = []string{"    49664    49555 /dev/mapper/vg_mylv_project_pv1"}
 
-	err := checkVolumeUsage("gl-project-pv1", "20")
+	volInfo, err := checkVolumeUsage("gl-project-pv1", "20")
 	assert(t, err != nil, "Should return error as bigger than threshold")
+	equals(t, 49555, volInfo.UsedKiloBytes)
+}
+
+func TestGetPoolUsage(t *testing.T) {
+	output = []string{"  104857600.00 25.00\n"}
+
+	poolInfo, err := getPoolUsage()
+	ok(t, err)
+
+	equals(t, 104857600, poolInfo.TotalKiloBytes)
+	equals(t, 26214400, poolInfo.UsedKiloBytes)
+}
+
+func TestGetPoolUsage_InvalidOutput(t *testing.T) {
+	output = []string{"  Volume group not found"}
+
+	_, err := getPoolUsage()
+	assert(t, err != nil, "Should return error for invalid output")
 }
@@ -35,6 +35,7 @@ func main() {
 	// Public endpoint for volume monitoring
 	r.GET("/volume/:pvname", gluster.VolumeInfoHandler)
 	r.GET("/volume/:pvname/check", gluster.CheckVolumeHandler)
+	r.GET("/pool", gluster.PoolInfoHandler)
 
 	// Secured endpoints with basic auth
 	sec := r.Group("/sec", gin.BasicAuth(gin.Accounts{
@@ -28,3 +28,9 @@ type VolInfo struct {
 	TotalKiloBytes int `json:"totalKiloBytes"`
 	UsedKiloBytes  int `json:"usedKiloBytes"`
 }
+
+// PoolInfo is the response model for the pool usage endpoint
+type PoolInfo struct {
+	TotalKiloBytes int `json:"totalKiloBytes"`
+	UsedKiloBytes  int `json:"usedKiloBytes"`
+}
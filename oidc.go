@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// Tokens are refreshed when they expire within this duration,
+	// so that a request doesn't fail because the token expired in flight
+	refreshSkew  = 60 * time.Second
+	loginTimeout = 5 * time.Minute
+	deviceGrant  = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// ssoConfig is the SSO configuration published by the server on /sso.
+// The CLI authenticates against the same realm the server validates tokens with.
+type ssoConfig struct {
+	URL      string `json:"url"`
+	Realm    string `json:"realm"`
+	ClientId string `json:"clientId"`
+}
+
+func (s ssoConfig) endpoint(path string) string {
+	return fmt.Sprintf("%v/realms/%v/protocol/openid-connect/%v", strings.TrimSuffix(s.URL, "/"), s.Realm, path)
+}
+
+func (s ssoConfig) oauth2Config(redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:    s.ClientId,
+		RedirectURL: redirectURL,
+		Scopes:      []string{"openid", "offline_access"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   s.endpoint("auth"),
+			TokenURL:  s.endpoint("token"),
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+}
+
+type deviceAuthResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// getSSOConfig loads the SSO configuration from the server.
+// Flags take precedence over the values returned by the server.
+func getSSOConfig(u *url.URL, override ssoConfig) (ssoConfig, error) {
+	var cfg ssoConfig
+	resp, fetchErr := http.Get(fmt.Sprintf("%s://%s/sso", u.Scheme, u.Host))
+	if fetchErr == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&cfg); err != nil {
+				return cfg, err
+			}
+		} else {
+			fetchErr = fmt.Errorf("%v returned %v", resp.Request.URL, resp.Status)
+		}
+	}
+	if fetchErr != nil && override == (ssoConfig{}) {
+		return cfg, fmt.Errorf("Could not load the SSO configuration from the server: %v", fetchErr)
+	}
+	if override.URL != "" {
+		cfg.URL = override.URL
+	}
+	if override.Realm != "" {
+		cfg.Realm = override.Realm
+	}
+	if override.ClientId != "" {
+		cfg.ClientId = override.ClientId
+	}
+	if cfg.URL == "" || cfg.Realm == "" || cfg.ClientId == "" {
+		return cfg, errors.New("SSO configuration incomplete. Please specify -sso-url, -sso-realm and -client-id")
+	}
+	return cfg, nil
+}
+
+// getToken returns a valid token for the server. A stored token is refreshed
+// shortly before it expires. If that's not possible, a new login is started.
+func getToken(u *url.URL, sso ssoConfig, method string) (*oauth2.Token, error) {
+	token, err := loadToken(u)
+	if err == nil {
+		if token.Expiry.After(time.Now().Add(refreshSkew)) {
+			return token, nil
+		}
+		if token.RefreshToken != "" {
+			refreshed, err := refreshToken(sso, token)
+			if err == nil {
+				return refreshed, saveToken(u, refreshed)
+			}
+			fmt.Fprintf(os.Stderr, "Could not refresh token (%v). Please log in again.\n", err)
+		}
+	}
+	return login(u, sso, method)
+}
+
+func login(u *url.URL, sso ssoConfig, method string) (*oauth2.Token, error) {
+	var token *oauth2.Token
+	var err error
+	switch method {
+	case "device":
+		token, err = deviceLogin(sso)
+	case "browser":
+		token, err = browserLogin(sso)
+	default:
+		return nil, fmt.Errorf("Invalid login method: %v. Must be either device or browser", method)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, saveToken(u, token)
+}
+
+func refreshToken(sso ssoConfig, token *oauth2.Token) (*oauth2.Token, error) {
+	// Only pass the refresh token, otherwise the token source
+	// would return the (still valid) access token unchanged
+	ts := sso.oauth2Config("").TokenSource(context.Background(), &oauth2.Token{RefreshToken: token.RefreshToken})
+	refreshed, err := ts.Token()
+	if err != nil {
+		return nil, err
+	}
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	return refreshed, nil
+}
+
+// deviceLogin implements the OAuth 2.0 device authorization grant (RFC 8628)
+func deviceLogin(sso ssoConfig) (*oauth2.Token, error) {
+	resp, err := http.PostForm(sso.endpoint("auth/device"), url.Values{
+		"client_id": {sso.ClientId},
+		"scope":     {"openid offline_access"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Device authorization failed: StatusCode: %v, Message: %v", resp.StatusCode, string(body))
+	}
+	var auth deviceAuthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return nil, err
+	}
+
+	verificationURI := auth.VerificationURIComplete
+	if verificationURI == "" {
+		verificationURI = auth.VerificationURI
+	}
+	fmt.Fprintf(os.Stderr, "Open %v in your browser and enter the code %v\n", verificationURI, auth.UserCode)
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		token, err := postTokenRequest(sso, url.Values{
+			"grant_type":  {deviceGrant},
+			"client_id":   {sso.ClientId},
+			"device_code": {auth.DeviceCode},
+		})
+		if err == nil {
+			return token, nil
+		}
+		switch err.Error() {
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		}
+		return nil, err
+	}
+	return nil, errors.New("The device code has expired. Please try again")
+}
+
+func postTokenRequest(sso ssoConfig, values url.Values) (*oauth2.Token, error) {
+	resp, err := http.PostForm(sso.endpoint("token"), values)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var t tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return nil, err
+	}
+	if t.Error != "" {
+		// The error code is returned as is, because the device flow
+		// needs it to decide whether to continue polling
+		return nil, errors.New(t.Error)
+	}
+	return &oauth2.Token{
+		AccessToken:  t.AccessToken,
+		TokenType:    t.TokenType,
+		RefreshToken: t.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(t.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// browserLogin implements the authorization code flow with PKCE (RFC 7636).
+// The redirect is received by a temporary server on the loopback interface.
+func browserLogin(sso ssoConfig) (*oauth2.Token, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	redirectURL := fmt.Sprintf("http://%v/callback", listener.Addr().String())
+	conf := sso.oauth2Config(redirectURL)
+	verifier := randomString(32)
+	state := randomString(16)
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authURL := conf.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"))
+
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			http.Error(w, "Invalid state", http.StatusBadRequest)
+			return
+		}
+		if e := q.Get("error"); e != "" {
+			http.Error(w, e, http.StatusBadRequest)
+			errs <- fmt.Errorf("Login failed: %v %v", e, q.Get("error_description"))
+			return
+		}
+		fmt.Fprintln(w, "Login successful. You can close this window.")
+		codes <- q.Get("code")
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Fprintf(os.Stderr, "Opening browser for login. If it doesn't open, visit:\n%v\n", authURL)
+	openBrowser(authURL)
+
+	select {
+	case code := <-codes:
+		return conf.Exchange(context.Background(), code, oauth2.SetAuthURLParam("code_verifier", verifier))
+	case err := <-errs:
+		return nil, err
+	case <-time.After(loginTimeout):
+		return nil, errors.New("Login timed out")
+	}
+}
+
+func openBrowser(u string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	// The URL is printed as well, so failing to open a browser is fine
+	cmd.Start()
+}
+
+func randomString(length int) string {
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// tokenPath returns the path of the token file for the server.
+// Tokens are stored per server in the user config directory.
+func tokenPath(u *url.URL) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	name := strings.Replace(u.Host, ":", "_", -1)
+	return filepath.Join(dir, "ssp", "token-"+name+".json"), nil
+}
+
+func loadToken(u *url.URL) (*oauth2.Token, error) {
+	path, err := tokenPath(u)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(raw, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func saveToken(u *url.URL, token *oauth2.Token) error {
+	path, err := tokenPath(u)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+		return err
+	}
+	// WriteFile doesn't change the permissions of an existing file
+	return os.Chmod(path, 0600)
+}
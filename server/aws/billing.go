@@ -0,0 +1,119 @@
+package aws
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const accountingNumberTag = "Accounting_Number"
+
+// FindS3BucketsByBilling returns the buckets of both accounts with the accounting number.
+// Accounts which can't be queried are returned separately.
+func FindS3BucketsByBilling(billing string) ([]common.BillingResource, []common.BillingSourceError) {
+	resources := []common.BillingResource{}
+	failed := []common.BillingSourceError{}
+	for _, account := range []string{accountNonProd, accountProd} {
+		buckets, err := findS3BucketsByBillingForAccount(billing, account)
+		if err != nil {
+			failed = append(failed, common.BillingSourceError{Source: "aws-" + account, Message: err.Error()})
+			continue
+		}
+		resources = append(resources, buckets...)
+	}
+	return resources, failed
+}
+
+func findS3BucketsByBillingForAccount(billing, account string) ([]common.BillingResource, error) {
+	svc, err := GetS3Client(getStageForAccount(account))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := svc.ListBuckets(nil)
+	if err != nil {
+		log.Print("Unable to list buckets (ListBuckets API call): " + err.Error())
+		return nil, errors.New(s3ListError)
+	}
+
+	buckets := []common.BillingResource{}
+	for _, b := range result.Buckets {
+		tagging, err := svc.GetBucketTagging(&s3.GetBucketTaggingInput{Bucket: b.Name})
+		if err != nil {
+			// Buckets without tags are skipped
+			continue
+		}
+		if value := getTagValue(tagging.TagSet, accountingNumberTag); strings.EqualFold(value, billing) {
+			buckets = append(buckets, common.BillingResource{
+				Type:     common.BillingResourceBucket,
+				Location: account,
+				Name:     *b.Name,
+				Billing:  value,
+			})
+		}
+	}
+	return buckets, nil
+}
+
+// UpdateS3BucketBilling changes the Accounting_Number tag. The other tags are kept.
+func UpdateS3BucketBilling(account, bucket, billing, username string) error {
+	svc, err := GetS3Client(getStageForAccount(account))
+	if err != nil {
+		return err
+	}
+
+	tagging, err := svc.GetBucketTagging(&s3.GetBucketTaggingInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		log.Print("Unable to get tags for bucket " + bucket + ": " + err.Error())
+		return errors.New(genericAwsAPIError)
+	}
+
+	_, err = svc.PutBucketTagging(&s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucket),
+		Tagging: &s3.Tagging{TagSet: setTagValue(tagging.TagSet, accountingNumberTag, billing)},
+	})
+	if err != nil {
+		log.Print("Tagging bucket " + bucket + " failed: " + err.Error())
+		return errors.New(genericAwsAPIError)
+	}
+	log.Print(username + " changed the accounting number of bucket " + bucket + " to " + billing)
+	return nil
+}
+
+func getStageForAccount(account string) string {
+	if account == accountProd {
+		return stageProd
+	}
+	return stageDev
+}
+
+func getTagValue(tags []*s3.Tag, key string) string {
+	for _, tag := range tags {
+		if tag.Key != nil && *tag.Key == key && tag.Value != nil {
+			return *tag.Value
+		}
+	}
+	return ""
+}
+
+// setTagValue replaces the value of the tag or adds the tag
+func setTagValue(tags []*s3.Tag, key, value string) []*s3.Tag {
+	result := []*s3.Tag{}
+	found := false
+	for _, tag := range tags {
+		if tag.Key != nil && *tag.Key == key {
+			found = true
+			result = append(result, &s3.Tag{Key: aws.String(key), Value: aws.String(value)})
+			continue
+		}
+		result = append(result, tag)
+	}
+	if !found {
+		result = append(result, &s3.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestGetTagValue(t *testing.T) {
+	tags := []*s3.Tag{
+		{Key: aws.String("Project"), Value: aws.String("app")},
+		{Key: aws.String(accountingNumberTag), Value: aws.String("1234")},
+		{Key: nil, Value: aws.String("invalid")},
+	}
+	var tests = []struct {
+		key      string
+		expected string
+	}{
+		{accountingNumberTag, "1234"},
+		{"Project", "app"},
+		{"Unknown", ""},
+	}
+	for _, test := range tests {
+		if value := getTagValue(tags, test.key); value != test.expected {
+			t.Errorf("getTagValue(%v): expected %v, got %v", test.key, test.expected, value)
+		}
+	}
+}
+
+func TestSetTagValue(t *testing.T) {
+	tags := []*s3.Tag{
+		{Key: aws.String("Project"), Value: aws.String("app")},
+		{Key: aws.String(accountingNumberTag), Value: aws.String("1234")},
+	}
+
+	replaced := setTagValue(tags, accountingNumberTag, "5678")
+	if len(replaced) != 2 || getTagValue(replaced, accountingNumberTag) != "5678" || getTagValue(replaced, "Project") != "app" {
+		t.Errorf("Expected the accounting number to be replaced, got %v", replaced)
+	}
+	if getTagValue(tags, accountingNumberTag) != "1234" {
+		t.Errorf("Expected the original tags to be unchanged, got %v", tags)
+	}
+
+	added := setTagValue(tags[:1], accountingNumberTag, "5678")
+	if len(added) != 2 || getTagValue(added, accountingNumberTag) != "5678" {
+		t.Errorf("Expected the accounting number to be added, got %v", added)
+	}
+}
@@ -189,7 +189,7 @@ func createNewS3Bucket(username string, projectname string, bucketname string, b
 			TagSet: []*s3.Tag{
 				{Key: aws.String("Creator"), Value: aws.String(username)},
 				{Key: aws.String("Project"), Value: aws.String(projectname)},
-				{Key: aws.String("Accounting_Number"), Value: aws.String(billing)},
+				{Key: aws.String(accountingNumberTag), Value: aws.String(billing)},
 				{Key: aws.String("Stage"), Value: aws.String(stage)},
 			},
 		}})
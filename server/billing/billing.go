@@ -0,0 +1,127 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/aws"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/openshift"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/sematext"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	wrongAPIUsageError = "Invalid API request: Arguments don't match the definition. Please open a Jira issue"
+	noOperatorError    = "Only operators of all clusters can change the billing of other projects"
+)
+
+func RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/billing/bulk", bulkBillingUpdateHandler)
+}
+
+// bulkBillingUpdateHandler finds all projects, S3 buckets and Logsene apps with the
+// accounting number or MEGAID and updates them. With plan=true, they are only listed.
+func bulkBillingUpdateHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	planOnly := c.Query("plan") == "true"
+
+	var data common.BulkBillingCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateBulkBillingCommand(data); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if !openshift.IsOperatorOfAllClusters(username) {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: noOperatorError})
+		return
+	}
+
+	resources, failed := findBillingResources(data)
+	if planOnly || len(resources) == 0 {
+		c.JSON(http.StatusOK, common.BulkBillingResponse{
+			Message:       fmt.Sprintf("%v resources found", len(resources)),
+			Resources:     resources,
+			FailedSources: failed,
+		})
+		return
+	}
+
+	log.Printf("%v is changing the billing of %v resources: %+v", username, len(resources), data)
+	updated := updateBillingResources(resources, data, username)
+	c.JSON(http.StatusOK, common.BulkBillingResponse{
+		Message:       fmt.Sprintf("%v of %v resources have been updated", updated, len(resources)),
+		Applied:       true,
+		Resources:     resources,
+		FailedSources: failed,
+	})
+}
+
+func validateBulkBillingCommand(data common.BulkBillingCommand) error {
+	if (data.Billing == "") == (data.MegaId == "") {
+		return errors.New("Either the accounting number or the MEGAID must be provided")
+	}
+	if data.NewBilling == "" && data.NewMegaId == "" {
+		return errors.New("The new accounting number or MEGAID must be provided")
+	}
+	if data.MegaId != "" && data.NewMegaId == "" {
+		return errors.New("The new MEGAID must be provided")
+	}
+	// The values are validated once for projects, S3 buckets and Logsene apps
+	return openshift.ValidateBilling(data.NewBilling, data.NewMegaId)
+}
+
+// findBillingResources searches all sources. S3 buckets and Logsene apps
+// only have an accounting number, so they are only searched by it.
+func findBillingResources(data common.BulkBillingCommand) ([]common.BillingResource, []common.BillingSourceError) {
+	resources, failed := openshift.FindProjectsByBilling(data.Billing, data.MegaId)
+	if data.Billing == "" || data.NewBilling == "" {
+		return resources, failed
+	}
+
+	buckets, failedAccounts := aws.FindS3BucketsByBilling(data.Billing)
+	resources = append(resources, buckets...)
+	failed = append(failed, failedAccounts...)
+
+	apps, err := sematext.FindLogseneAppsByBilling(data.Billing)
+	if err != nil {
+		failed = append(failed, common.BillingSourceError{Source: "sematext", Message: err.Error()})
+	} else {
+		resources = append(resources, apps...)
+	}
+	return resources, failed
+}
+
+// updateBillingResources updates every resource and sets its result.
+// A failed resource doesn't stop the others.
+func updateBillingResources(resources []common.BillingResource, data common.BulkBillingCommand, username string) int {
+	updated := 0
+	for i, r := range resources {
+		var err error
+		switch r.Type {
+		case common.BillingResourceProject:
+			err = openshift.UpdateProjectBilling(r.Location, r.Name, data.NewBilling, data.NewMegaId, username)
+		case common.BillingResourceBucket:
+			err = aws.UpdateS3BucketBilling(r.Location, r.Name, data.NewBilling, username)
+		case common.BillingResourceLogseneApp:
+			err = sematext.UpdateLogseneAppBilling(username, r.Id, data.NewBilling)
+		default:
+			err = fmt.Errorf("Unknown resource type %v", r.Type)
+		}
+		if err != nil {
+			log.Printf("Error changing the billing of %v %v (%v): %v", r.Type, r.Name, r.Location, err)
+			resources[i].Result = err.Error()
+			continue
+		}
+		resources[i].Result = "ok"
+		updated++
+	}
+	return updated
+}
@@ -0,0 +1,40 @@
+package billing
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestValidateBulkBillingCommand(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("openshift_project_metadata", []map[string]interface{}{
+		{"name": "billing", "annotation": "openshift.io/kontierung-element", "required": true, "regex": "^[0-9]{4}$"},
+		{"name": "megaid", "annotation": "openshift.io/MEGAID"},
+	})
+	defer config.Init("bla")
+
+	var tests = []struct {
+		data  common.BulkBillingCommand
+		valid bool
+	}{
+		{common.BulkBillingCommand{Billing: "1111", NewBilling: "2222"}, true},
+		{common.BulkBillingCommand{MegaId: "M1", NewMegaId: "M2"}, true},
+		{common.BulkBillingCommand{MegaId: "M1", NewBilling: "2222", NewMegaId: "M2"}, true},
+		{common.BulkBillingCommand{Billing: "1111", MegaId: "M1", NewBilling: "2222"}, false},
+		{common.BulkBillingCommand{NewBilling: "2222"}, false},
+		{common.BulkBillingCommand{Billing: "1111"}, false},
+		{common.BulkBillingCommand{MegaId: "M1", NewBilling: "2222"}, false},
+		{common.BulkBillingCommand{Billing: "1111", NewBilling: "22222"}, false},
+	}
+	for _, test := range tests {
+		err := validateBulkBillingCommand(test.data)
+		if test.valid && err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", test.data, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected %+v to be invalid", test.data)
+		}
+	}
+}
@@ -35,8 +35,15 @@ type GrowVolumeCommand struct {
 
 type NewProjectCommand struct {
 	OpenshiftBase
-	Billing string `json:"billing"`
-	MegaId  string `json:"megaId"`
+	Billing   string            `json:"billing"`
+	MegaId    string            `json:"megaId"`
+	Metadata  map[string]string `json:"metadata"`
+	Blueprint string            `json:"blueprint"`
+}
+
+type ApplyBlueprintCommand struct {
+	OpenshiftBase
+	Blueprint string `json:"blueprint"`
 }
 
 type NewTestProjectCommand struct {
@@ -50,8 +57,9 @@ type EditLogseneBillingDataCommand struct {
 
 type UpdateProjectInformationCommand struct {
 	OpenshiftBase
-	Billing string `json:"billing"`
-	MegaID  string `json:"megaid"`
+	Billing  string            `json:"billing"`
+	MegaID   string            `json:"megaid"`
+	Metadata map[string]string `json:"metadata"`
 }
 
 type AddProjectAdminCommand struct {
@@ -59,6 +67,38 @@ type AddProjectAdminCommand struct {
 	Username string `json:"username"`
 }
 
+type ProjectAdminGroupCommand struct {
+	OpenshiftBase
+	Group string `json:"group"`
+}
+
+// ProjectRoleCommand gives a user or group a role in the project
+type ProjectRoleCommand struct {
+	OpenshiftBase
+	Role string `json:"role"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+type ProjectRoleBinding struct {
+	Name     string               `json:"name"`
+	Role     string               `json:"role"`
+	RoleKind string               `json:"roleKind"`
+	Subjects []RoleBindingSubject `json:"subjects"`
+}
+
+type RoleBindingSubject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type TransferProjectOwnershipCommand struct {
+	OpenshiftBase
+	Username            string `json:"username"`
+	RemovePreviousOwner bool   `json:"removePreviousOwner"`
+}
+
 type CreateLogseneAppCommand struct {
 	AppName      string `json:"appName"`
 	DiscountCode string `json:"discountCode"`
@@ -77,16 +117,226 @@ type EditQuotasCommand struct {
 	Memory int `json:"memory"`
 }
 
+// ProjectQuotasCommand sets the hard quotas (e.g. limits.cpu, pods or
+// requests.storage) and the default container resources of a project
+type ProjectQuotasCommand struct {
+	OpenshiftBase
+	Hard     map[string]string   `json:"hard"`
+	Defaults *LimitRangeDefaults `json:"defaults"`
+}
+
+type ProjectQuotas struct {
+	Hard     map[string]string   `json:"hard"`
+	Used     map[string]string   `json:"used"`
+	Maximum  map[string]string   `json:"maximum"`
+	Defaults *LimitRangeDefaults `json:"defaults"`
+}
+
+// LimitRangeDefaults are the resources of containers without requests or limits
+type LimitRangeDefaults struct {
+	DefaultCPU           string `json:"defaultCpu"`
+	DefaultMemory        string `json:"defaultMemory"`
+	DefaultRequestCPU    string `json:"defaultRequestCpu"`
+	DefaultRequestMemory string `json:"defaultRequestMemory"`
+}
+
+type QuotaUsageResponse struct {
+	ClusterId string               `json:"clusterid"`
+	Projects  []ProjectQuotaUsage  `json:"projects"`
+	Cluster   *ClusterQuotaSummary `json:"cluster,omitempty"`
+}
+
+// ProjectQuotaUsage contains the quotas and the actual consumption of a project.
+// CPU is in cores, memory in GiB.
+type ProjectQuotaUsage struct {
+	Project          string            `json:"project"`
+	Hard             map[string]string `json:"hard"`
+	Used             map[string]string `json:"used"`
+	MetricsAvailable bool              `json:"metricsAvailable"`
+	UsageCPU         float64           `json:"usageCpu"`
+	UsageMemory      float64           `json:"usageMemory"`
+}
+
+// ClusterQuotaSummary compares the allocated quotas with the node capacity.
+// CPU is in cores, memory in GiB.
+type ClusterQuotaSummary struct {
+	Projects            int     `json:"projects"`
+	Nodes               int     `json:"nodes"`
+	QuotaRequestsCPU    float64 `json:"quotaRequestsCpu"`
+	QuotaLimitsCPU      float64 `json:"quotaLimitsCpu"`
+	QuotaRequestsMemory float64 `json:"quotaRequestsMemory"`
+	QuotaLimitsMemory   float64 `json:"quotaLimitsMemory"`
+	AllocatableCPU      float64 `json:"allocatableCpu"`
+	AllocatableMemory   float64 `json:"allocatableMemory"`
+	UsageCPU            float64 `json:"usageCpu"`
+	UsageMemory         float64 `json:"usageMemory"`
+}
+
 type NewServiceAccountCommand struct {
 	OpenshiftBase
 	ServiceAccount  string `json:"serviceAccount"`
 	OrganizationKey string `json:"organizationKey"`
 }
 
+type ServiceAccountRoleCommand struct {
+	OpenshiftBase
+	ServiceAccount string `json:"serviceAccount"`
+	Role           string `json:"role"`
+}
+
+type RotateServiceAccountTokenCommand struct {
+	OpenshiftBase
+	ServiceAccount string `json:"serviceAccount"`
+	// If set, the new token is stored as Jenkins credential
+	OrganizationKey string `json:"organizationKey"`
+}
+
+type ServiceAccount struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
 type NewPullSecretCommand struct {
 	OpenshiftBase
 	Username string
 	Password string
+	// Defaults to external-registry
+	Name string `json:"name"`
+	// Defaults to the docker_repository
+	Registry string `json:"registry"`
+	// Defaults to the default service account
+	ServiceAccounts []string `json:"serviceAccounts"`
+}
+
+type LinkPullSecretCommand struct {
+	OpenshiftBase
+	Name           string `json:"name"`
+	ServiceAccount string `json:"serviceAccount"`
+}
+
+type PullSecret struct {
+	Name            string   `json:"name"`
+	Registries      []string `json:"registries"`
+	Username        string   `json:"username"`
+	ServiceAccounts []string `json:"serviceAccounts"`
+}
+
+type NetworkPolicyCommand struct {
+	OpenshiftBase
+	Preset string `json:"preset"`
+	// Only used by the allow-from-project preset
+	SourceProject string `json:"sourceProject"`
+}
+
+type NetworkPolicy struct {
+	Name string `json:"name"`
+	// Empty if the policy was not created by the SSP
+	Preset        string `json:"preset"`
+	SourceProject string `json:"sourceProject,omitempty"`
+}
+
+type ProjectSecretCommand struct {
+	OpenshiftBase
+	Name string `json:"name"`
+	// Opaque or kubernetes.io/tls
+	Type string            `json:"type"`
+	Data map[string]string `json:"data"`
+	// PEM encoded certificate (chain) and key of TLS secrets
+	Certificate string `json:"certificate"`
+	Key         string `json:"key"`
+}
+
+// ProjectSecret never contains the values of the secret
+type ProjectSecret struct {
+	Name        string           `json:"name"`
+	Type        string           `json:"type"`
+	Keys        []string         `json:"keys"`
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
+}
+
+type CertificateInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dnsNames"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+}
+
+type CertificateExpiry struct {
+	Project  string `json:"project"`
+	Secret   string `json:"secret"`
+	DaysLeft int    `json:"daysLeft"`
+	CertificateInfo
+}
+
+type AllProjectsResponse struct {
+	Projects []ClusterProject `json:"projects"`
+	// Clusters which could not be queried
+	FailedClusters []ClusterError `json:"failedClusters"`
+}
+
+type ClusterProject struct {
+	ClusterId   string            `json:"clusterid"`
+	ClusterName string            `json:"clusterName"`
+	Name        string            `json:"name"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+type ClusterError struct {
+	ClusterId   string `json:"clusterid"`
+	ClusterName string `json:"clusterName"`
+	Message     string `json:"message"`
+}
+
+type RouteCommand struct {
+	OpenshiftBase
+	Name string `json:"name"`
+	// Generated by OpenShift if empty
+	Host       string `json:"host"`
+	Path       string `json:"path"`
+	Service    string `json:"service"`
+	TargetPort string `json:"targetPort"`
+	// Empty (no TLS), edge or reencrypt
+	Termination string `json:"termination"`
+	// PEM encoded. Without certificate the default certificate of the router is used.
+	Certificate              string `json:"certificate"`
+	Key                      string `json:"key"`
+	CACertificate            string `json:"caCertificate"`
+	DestinationCACertificate string `json:"destinationCaCertificate"`
+}
+
+type RouteInfo struct {
+	Project     string           `json:"project"`
+	Name        string           `json:"name"`
+	Host        string           `json:"host"`
+	Path        string           `json:"path"`
+	Service     string           `json:"service"`
+	Termination string           `json:"termination"`
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
+}
+
+type ProjectEvent struct {
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// ProjectProblem is a warning event or container status, which
+// usually prevents the application from running
+type ProjectProblem struct {
+	Project  string    `json:"project"`
+	Category string    `json:"category"`
+	Kind     string    `json:"kind"`
+	Name     string    `json:"name"`
+	Reason   string    `json:"reason"`
+	Message  string    `json:"message"`
+	Count    int       `json:"count"`
+	LastSeen time.Time `json:"lastSeen"`
 }
 
 type CreateSnapshotCommand struct {
@@ -152,6 +402,17 @@ type NewVolumeResponse struct {
 	JobId  int
 }
 
+// DeleteVolumeResponse describes the deleted volume. JobId is set
+// if the nfs volume is still being deleted.
+type DeleteVolumeResponse struct {
+	Message    string `json:"message"`
+	PvcName    string `json:"pvcName"`
+	PvName     string `json:"pvName"`
+	Technology string `json:"technology"`
+	Backend    string `json:"backend"`
+	JobId      int    `json:"jobId,omitempty"`
+}
+
 type InstanceListResponse struct {
 	Instances []Instance `json:"instances"`
 }
@@ -183,8 +444,82 @@ type S3CredentialsResponse struct {
 	Password    string `json:"password"`
 }
 
+type ProjectDeletionResponse struct {
+	Message    string          `json:"message"`
+	Token      string          `json:"token,omitempty"`
+	SoftDelete bool            `json:"softDelete"`
+	Volumes    []ProjectVolume `json:"volumes"`
+}
+
+// VolumeStatus is the binding and resize status of a PVC
+type VolumeStatus struct {
+	PvcName       string `json:"pvcName"`
+	Phase         string `json:"phase"`
+	PvName        string `json:"pvName"`
+	StorageClass  string `json:"storageClass"`
+	RequestedSize string `json:"requestedSize"`
+	Capacity      string `json:"capacity"`
+	Resizing      bool   `json:"resizing"`
+	Message       string `json:"message"`
+}
+
+type ProjectVolume struct {
+	PvcName     string       `json:"pvcName"`
+	PvName      string       `json:"pvName"`
+	Size        string       `json:"size"`
+	Capacity    string       `json:"capacity"`
+	AccessModes []string     `json:"accessModes"`
+	Technology  string       `json:"technology"`
+	Backend     string       `json:"backend"`
+	Usage       *VolumeUsage `json:"usage,omitempty"`
+}
+
+// VolumeUsageSample is the usage of a volume at a point in time
+type VolumeUsageSample struct {
+	Time           time.Time `json:"time"`
+	TotalKiloBytes int       `json:"totalKiloBytes"`
+	UsedKiloBytes  int       `json:"usedKiloBytes"`
+	UsedPercent    float64   `json:"usedPercent"`
+}
+
+// VolumeUsageReport is the usage history and the alerting settings of a volume.
+// NotifiedThreshold is the highest threshold the admins have been notified about.
+type VolumeUsageReport struct {
+	PvName            string              `json:"pvName"`
+	Project           string              `json:"project"`
+	Thresholds        []int               `json:"thresholds"`
+	SilencedUntil     *time.Time          `json:"silencedUntil,omitempty"`
+	NotifiedThreshold int                 `json:"notifiedThreshold"`
+	History           []VolumeUsageSample `json:"history"`
+}
+
+// VolumeAlertingCommand overrides the usage thresholds of a volume and silences its alerts.
+// Without thresholds, the default thresholds are used again.
+type VolumeAlertingCommand struct {
+	ClusterId    string `json:"clusterid"`
+	PvName       string `json:"pvName"`
+	Thresholds   []int  `json:"thresholds"`
+	SilenceHours int    `json:"silenceHours"`
+}
+
+// VolumeUsage is the live usage of a gluster volume
+type VolumeUsage struct {
+	TotalKiloBytes int     `json:"totalKiloBytes"`
+	UsedKiloBytes  int     `json:"usedKiloBytes"`
+	UsedPercent    float64 `json:"usedPercent"`
+}
+
 type AdminList struct {
-	Admins []string `json:"admins"`
+	Admins  []string       `json:"admins"`
+	Details []ProjectAdmin `json:"details"`
+}
+
+// ProjectAdmin is an admin of a project and where the permission comes from.
+// Source is one of user, operator, functional-account or group
+type ProjectAdmin struct {
+	Username string `json:"username"`
+	Source   string `json:"source"`
+	Group    string `json:"group,omitempty"`
 }
 
 type SematextAppList struct {
@@ -222,3 +557,110 @@ type JsonPatch struct {
 	Path      string      `json:"path"`
 	Value     interface{} `json:"value"`
 }
+
+// ProjectManifest describes the desired state of an OpenShift project.
+// It is applied with POST /ose/project/apply
+type ProjectManifest struct {
+	ClusterId       string                   `yaml:"cluster" json:"cluster"`
+	Project         string                   `yaml:"name" json:"name"`
+	Billing         string                   `yaml:"billing" json:"billing"`
+	MegaId          string                   `yaml:"megaId" json:"megaId"`
+	Metadata        map[string]string        `yaml:"metadata" json:"metadata"`
+	Blueprint       string                   `yaml:"blueprint" json:"blueprint"`
+	Admins          []string                 `yaml:"admins" json:"admins"`
+	Quotas          *ManifestQuotas          `yaml:"quotas" json:"quotas"`
+	ServiceAccounts []ManifestServiceAccount `yaml:"serviceAccounts" json:"serviceAccounts"`
+	PullSecret      *ManifestPullSecret      `yaml:"pullSecret" json:"pullSecret"`
+	Volumes         []ManifestVolume         `yaml:"volumes" json:"volumes"`
+}
+
+type ManifestQuotas struct {
+	CPU    int `yaml:"cpu" json:"cpu"`
+	Memory int `yaml:"memory" json:"memory"`
+}
+
+type ManifestServiceAccount struct {
+	Name            string `yaml:"name" json:"name"`
+	OrganizationKey string `yaml:"organizationKey" json:"organizationKey"`
+}
+
+type ManifestPullSecret struct {
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+}
+
+type ManifestVolume struct {
+	PvcName    string `yaml:"name" json:"name"`
+	Size       string `yaml:"size" json:"size"`
+	Mode       string `yaml:"mode" json:"mode"`
+	Technology string `yaml:"technology" json:"technology"`
+	// Only for the technology dynamic
+	StorageClass string `yaml:"storageClass" json:"storageClass"`
+}
+
+// ManifestChange is a single change needed to bring a project to the
+// state described in the manifest
+type ManifestChange struct {
+	Action  string `json:"action"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Details string `json:"details"`
+	Result  string `json:"result,omitempty"`
+}
+
+type ManifestApplyResponse struct {
+	Message string           `json:"message"`
+	Applied bool             `json:"applied"`
+	Changes []ManifestChange `json:"changes"`
+}
+
+// MigrateProjectCommand copies a project to another cluster.
+// Without TargetProject, the project keeps its name.
+type MigrateProjectCommand struct {
+	Project         string `json:"project"`
+	SourceClusterId string `json:"sourceClusterid"`
+	TargetClusterId string `json:"targetClusterid"`
+	TargetProject   string `json:"targetProject"`
+	CopyConfigMaps  bool   `json:"copyConfigMaps"`
+	CopySecrets     bool   `json:"copySecrets"`
+}
+
+// BulkBillingCommand moves all resources with the accounting number or
+// MEGAID to the new values
+type BulkBillingCommand struct {
+	Billing    string `json:"billing"`
+	MegaId     string `json:"megaId"`
+	NewBilling string `json:"newBilling"`
+	NewMegaId  string `json:"newMegaId"`
+}
+
+const (
+	BillingResourceProject    = "openshift-project"
+	BillingResourceBucket     = "s3-bucket"
+	BillingResourceLogseneApp = "logsene-app"
+)
+
+// BillingResource is an OpenShift project, S3 bucket or Logsene app
+type BillingResource struct {
+	Type string `json:"type"`
+	// Cluster, AWS account or sematext
+	Location string `json:"location"`
+	Name     string `json:"name"`
+	Id       string `json:"id,omitempty"`
+	Billing  string `json:"billing"`
+	MegaId   string `json:"megaId,omitempty"`
+	Result   string `json:"result,omitempty"`
+}
+
+// BillingSourceError is a cluster or account which couldn't be searched
+type BillingSourceError struct {
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+type BulkBillingResponse struct {
+	Message       string               `json:"message"`
+	Applied       bool                 `json:"applied"`
+	Resources     []BillingResource    `json:"resources"`
+	FailedSources []BillingSourceError `json:"failedSources"`
+}
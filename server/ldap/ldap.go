@@ -18,9 +18,11 @@ type LDAPClient struct {
 	BindPassword string `mapstructure:"password"`
 	GroupFilter  string // e.g. "(memberUid=%s)"
 	UserFilter   string // e.g. "(uid=%s)"
-	Base         string
-	Attributes   []string
-	ADDomainName string // ActiveDirectory domain name "example.com"
+	// Used to look up groups by name, e.g. "(&(objectClass=group)(cn=%s))"
+	GroupSearchFilter string
+	Base              string
+	Attributes        []string
+	ADDomainName      string // ActiveDirectory domain name "example.com"
 
 	UseSSL             bool
 	InsecureSkipVerify bool
@@ -39,6 +41,7 @@ func New() (*LDAPClient, error) {
 	l.SetDefault("UseSSL", false)
 	l.SetDefault("SkipTLS", true)
 	l.SetDefault("UserFilter", "(cn=%s)")
+	l.SetDefault("GroupSearchFilter", "(&(objectClass=group)(cn=%s))")
 
 	if !(l.IsSet("host") && l.IsSet("base") && l.IsSet("dn") && l.IsSet("password")) {
 		return nil, fmt.Errorf("LDAP configuration incomplete. Must set host, base, dn and password!")
@@ -125,7 +128,7 @@ func (lc *LDAPClient) GetUser(username string) (*ldap.Entry, error) {
 		lc.Base,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		fmt.Sprintf(lc.UserFilter, username),
-		[]string{"memberOf"},
+		[]string{"memberOf", "mail"},
 		nil,
 	)
 	sr, err := lc.Conn.Search(searchRequest)
@@ -135,6 +138,9 @@ func (lc *LDAPClient) GetUser(username string) (*ldap.Entry, error) {
 	if len(sr.Entries) > 1 {
 		return nil, fmt.Errorf("Something went wrong. Multiple LDAP users returned")
 	}
+	if len(sr.Entries) == 0 {
+		return nil, fmt.Errorf("LDAP user %v not found", username)
+	}
 	return sr.Entries[0], nil
 }
 
@@ -174,3 +180,80 @@ func (lc *LDAPClient) GetGroupsOfUser(username string) ([]string, error) {
 	}
 	return groups, nil
 }
+
+// GetEmailOfUser returns the mail address of the user
+func (lc *LDAPClient) GetEmailOfUser(username string) (string, error) {
+	user, err := lc.GetUser(username)
+	if err != nil {
+		return "", err
+	}
+	mail := user.GetAttributeValue("mail")
+	if mail == "" {
+		return "", fmt.Errorf("LDAP user %v has no mail address", username)
+	}
+	return mail, nil
+}
+
+// getGroup returns the ldap entry of the group or nil if it doesn't exist
+func (lc *LDAPClient) getGroup(group string) (*ldap.Entry, error) {
+	err := lc.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	if lc.BindDN != "" && lc.BindPassword != "" {
+		err = lc.Conn.Bind(lc.BindDN, lc.BindPassword)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		lc.Base,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(lc.GroupSearchFilter, ldap.EscapeFilter(group)),
+		[]string{"cn", "member"},
+		nil,
+	)
+	sr, err := lc.Conn.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	if len(sr.Entries) > 1 {
+		return nil, fmt.Errorf("Something went wrong. Multiple LDAP groups returned")
+	}
+	if len(sr.Entries) == 0 {
+		return nil, nil
+	}
+	return sr.Entries[0], nil
+}
+
+// GroupExists checks if the group exists and is not blacklisted
+func (lc *LDAPClient) GroupExists(group string) (bool, error) {
+	if common.ContainsStringI(getGroupBlacklist(), group) {
+		return false, nil
+	}
+	entry, err := lc.getGroup(group)
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+// GetMembersOfGroup returns the CNs of the direct members of the group
+func (lc *LDAPClient) GetMembersOfGroup(group string) ([]string, error) {
+	var members []string
+	entry, err := lc.getGroup(group)
+	if err != nil {
+		return members, err
+	}
+	if entry == nil {
+		return members, fmt.Errorf("LDAP group %v not found", group)
+	}
+	for _, dn := range entry.GetAttributeValues("member") {
+		if cn := getCN(dn); cn != "" {
+			members = append(members, cn)
+		}
+	}
+	return members, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/aws"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/billing"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/kafka"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/keycloak"
@@ -40,6 +41,7 @@ func main() {
 
 	// Public routes
 	router.GET("/features", featuresHandler)
+	router.GET("/sso", ssoConfigHandler)
 
 	// Protected routes
 	auth := router.Group("/api/")
@@ -63,10 +65,16 @@ func main() {
 		// Kafka routes
 		kafka.RegisterRoutes(auth)
 
+		// Billing routes
+		billing.RegisterRoutes(auth)
+
 		// LDAP routes
 		ldap.RegisterRoutes(auth)
 	}
 
+	// Background jobs
+	openshift.StartScheduledJobs()
+
 	log.Println("Cloud SSP is running")
 
 	port := config.Config().GetString("port")
@@ -95,3 +103,20 @@ func featuresHandler(c *gin.Context) {
 		Kafka:     kafka.GetFeatures(),
 	})
 }
+
+type ssoConfigResponse struct {
+	URL      string `json:"url"`
+	Realm    string `json:"realm"`
+	ClientId string `json:"clientId"`
+}
+
+// ssoConfigHandler publishes the SSO realm the tokens are validated against.
+// The CLI uses this to log in against the same realm.
+func ssoConfigHandler(c *gin.Context) {
+	cfg := config.Config()
+	c.JSON(http.StatusOK, ssoConfigResponse{
+		URL:      cfg.GetString("sso_url"),
+		Realm:    cfg.GetString("sso_realm"),
+		ClientId: cfg.GetString("sso_cli_client_id"),
+	})
+}
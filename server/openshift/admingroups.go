@@ -0,0 +1,202 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/ldap"
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+)
+
+const adminSourceGroup = "group"
+
+// The LDAP groups of a user are cached, because they are
+// needed for every permission check on projects with admin groups
+var ldapGroupsCache = cache.New(5*time.Minute, 10*time.Minute)
+
+func addProjectAdminGroupHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ProjectAdminGroupCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if data.Group == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Group must be provided"})
+		return
+	}
+
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := validateAdminGroupGrant(data.ClusterId, username, data.Group); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := changeProjectGroupPermission(data.ClusterId, data.Project, data.Group, true); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v added the group %v to the admins of project %v on cluster %v", username, data.Group, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The group %v has been sucessfully added to the %v project", data.Group, data.Project),
+	})
+}
+
+func removeProjectAdminGroupHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+	group := params.Get("group")
+
+	if group == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Group must be provided"})
+		return
+	}
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := changeProjectGroupPermission(clusterId, project, group, false); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v removed the group %v from the admins of project %v on cluster %v", username, group, project, clusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The group %v has been removed from the admins of the project %v", group, project),
+	})
+}
+
+func validateLdapGroup(group string) error {
+	if strings.ToLower(group) == "operator" {
+		return errors.New("The operator group can't be added")
+	}
+
+	l, err := ldap.New()
+	if err != nil {
+		log.Printf("Error creating LDAP client: %v", err)
+		return errors.New(genericAPIError)
+	}
+	defer l.Close()
+
+	exists, err := l.GroupExists(group)
+	if err != nil {
+		log.Printf("Error looking up LDAP group %v: %v", group, err)
+		return errors.New(genericAPIError)
+	}
+	if !exists {
+		return fmt.Errorf("The LDAP group %v doesn't exist or can't be used", group)
+	}
+	return nil
+}
+
+// validateAdminGroupGrant checks that the group exists and that the user is a member of it,
+// so that nobody can give admin permissions to people they don't know. Operators may add any group.
+func validateAdminGroupGrant(clusterId, username, group string) error {
+	if err := validateLdapGroup(group); err != nil {
+		return err
+	}
+
+	operator, err := isOperator(clusterId, username)
+	if err != nil {
+		return err
+	}
+	if operator {
+		return nil
+	}
+
+	groups, err := getLdapGroupsOfUser(username)
+	if err != nil {
+		log.Printf("Error getting LDAP groups of user %v: %v", username, err)
+		return errors.New(genericAPIError)
+	}
+	if !common.ContainsStringI(groups, group) {
+		return fmt.Errorf("You must be a member of the LDAP group %v to make it admin", group)
+	}
+	return nil
+}
+
+// changeProjectGroupPermission adds (add=true) or removes the group
+// as Group subject on the admin rolebindings. The group is removed
+// from every admin rolebinding and only added if no admin rolebinding
+// contains it yet.
+func changeProjectGroupPermission(clusterId, project, group string, add bool) error {
+	if add {
+		return addProjectRoleSubject(clusterId, project, "admin", subjectKindGroup, group)
+	}
+	return removeProjectRoleSubject(clusterId, project, "admin", subjectKindGroup, group)
+}
+
+func getLdapGroupsOfUser(username string) ([]string, error) {
+	if groups, ok := ldapGroupsCache.Get(username); ok {
+		return groups.([]string), nil
+	}
+
+	l, err := ldap.New()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	groups, err := l.GetGroupsOfUser(username)
+	if err != nil {
+		return nil, err
+	}
+	ldapGroupsCache.Set(username, groups, cache.DefaultExpiration)
+	return groups, nil
+}
+
+// getGroupAdminDetails returns the members of the admin groups.
+// If the members can't be resolved, only the group is returned.
+func getGroupAdminDetails(groups []string) []common.ProjectAdmin {
+	admins := []common.ProjectAdmin{}
+	if len(groups) == 0 {
+		return admins
+	}
+
+	l, err := ldap.New()
+	if err != nil {
+		log.Printf("Error creating LDAP client: %v", err)
+	} else {
+		defer l.Close()
+	}
+
+	for _, g := range groups {
+		var members []string
+		if l != nil {
+			members, err = l.GetMembersOfGroup(g)
+			if err != nil {
+				log.Printf("Error getting members of LDAP group %v: %v", g, err)
+			}
+		}
+		if len(members) == 0 {
+			admins = append(admins, common.ProjectAdmin{Source: adminSourceGroup, Group: g})
+			continue
+		}
+		for _, m := range members {
+			admins = append(admins, common.ProjectAdmin{
+				Username: strings.ToLower(m),
+				Source:   adminSourceGroup,
+				Group:    g,
+			})
+		}
+	}
+	return admins
+}
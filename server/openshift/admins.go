@@ -0,0 +1,232 @@
+package openshift
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	adminSourceUser              = "user"
+	adminSourceOperator          = "operator"
+	adminSourceFunctionalAccount = "functional-account"
+)
+
+func removeProjectAdminHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+	admin := params.Get("username")
+
+	if admin == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Username must be provided"})
+		return
+	}
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := removeProjectAdmin(clusterId, project, admin); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v removed %v from the admins of project %v on cluster %v", username, admin, project, clusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The user %v has been removed from the admins of the project %v", admin, project),
+	})
+}
+
+func transferProjectOwnershipHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.TransferProjectOwnershipCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if data.Username == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Username must be provided"})
+		return
+	}
+
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := transferProjectOwnership(data.ClusterId, data.Project, data.Username, data.RemovePreviousOwner); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v transferred the ownership of project %v on cluster %v to %v", username, data.Project, data.ClusterId, data.Username)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The user %v is now the owner of the project %v", data.Username, data.Project),
+	})
+}
+
+// removeProjectAdmin removes an admin, unless it is the last user with
+// admin permissions. Operators and the functional account don't count,
+// because they don't own the project.
+func removeProjectAdmin(clusterId, project, username string) error {
+	admins, err := getProjectAdminDetails(clusterId, project)
+	if err != nil {
+		return err
+	}
+
+	isAdmin, remaining := countRemainingAdmins(admins, username)
+	if !isAdmin {
+		return fmt.Errorf("The user %v is not admin of the project %v", username, project)
+	}
+	if remaining == 0 {
+		return fmt.Errorf("The user %v is the last admin of the project %v and can't be removed", username, project)
+	}
+
+	return removeProjectPermission(clusterId, project, username)
+}
+
+// countRemainingAdmins returns whether the user is a direct admin and how many
+// admins remain without the user. Members of admin groups count as remaining admins,
+// but groups without members or whose members can't be resolved don't.
+func countRemainingAdmins(admins []common.ProjectAdmin, username string) (bool, int) {
+	isAdmin := false
+	remaining := 0
+	for _, a := range admins {
+		if a.Source == adminSourceGroup {
+			if a.Username != "" {
+				remaining++
+			}
+			continue
+		}
+		if a.Source != adminSourceUser {
+			continue
+		}
+		if a.Username == strings.ToLower(username) {
+			isAdmin = true
+			continue
+		}
+		remaining++
+	}
+	return isAdmin, remaining
+}
+
+// transferProjectOwnership makes the user admin and requester of the project.
+// The previous requester can optionally be removed from the admins.
+func transferProjectOwnership(clusterId, project, username string, removePreviousOwner bool) error {
+	admins, err := getProjectAdminDetails(clusterId, project)
+	if err != nil {
+		return err
+	}
+
+	if !isDirectAdmin(admins, username) {
+		if err := changeProjectPermission(clusterId, project, username); err != nil {
+			return err
+		}
+	}
+
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return err
+	}
+	previousOwner := getAnnotation(namespace, requesterAnnotation)
+	namespace.Set(strings.ToLower(username), "metadata", "annotations", requesterAnnotation)
+	if err := updateNamespace(clusterId, project, namespace); err != nil {
+		return err
+	}
+
+	if removePreviousOwner && previousOwner != "" && strings.ToLower(previousOwner) != strings.ToLower(username) &&
+		isDirectAdmin(admins, previousOwner) {
+		if err := removeProjectPermission(clusterId, project, previousOwner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isDirectAdmin(admins []common.ProjectAdmin, username string) bool {
+	for _, a := range admins {
+		if (a.Source == adminSourceUser || a.Source == adminSourceFunctionalAccount) && a.Username == strings.ToLower(username) {
+			return true
+		}
+	}
+	return false
+}
+
+// getProjectAdminDetails returns all admins of the project with their source
+func getProjectAdminDetails(clusterId, project string) ([]common.ProjectAdmin, error) {
+	adminRoleBinding, err := getAdminRoleBinding(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+
+	var operators []string
+	if hasOperatorGroup(adminRoleBinding) {
+		group, err := getOperatorGroup(clusterId)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range group.Path("users").Children() {
+			operators = append(operators, u.Data().(string))
+		}
+	}
+
+	functionalAccount := config.Config().GetString("openshift_additional_project_admin_account")
+	admins := classifyProjectAdmins(adminRoleBinding, operators, functionalAccount)
+	return append(admins, getGroupAdminDetails(getAdminGroups(adminRoleBinding))...), nil
+}
+
+func hasOperatorGroup(adminRoleBinding *gabs.Container) bool {
+	for _, g := range adminRoleBinding.Path("groupNames").Children() {
+		if strings.ToLower(g.Data().(string)) == "operator" {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyProjectAdmins returns the users and operators of the
+// admin rolebindings. Users are added in lower- and uppercase, so they
+// are deduplicated.
+func classifyProjectAdmins(adminRoleBinding *gabs.Container, operators []string, functionalAccount string) []common.ProjectAdmin {
+	seen := map[string]bool{}
+	admins := []common.ProjectAdmin{}
+	add := func(username, source string) {
+		username = strings.ToLower(username)
+		if username == "" || seen[username] {
+			return
+		}
+		seen[username] = true
+		admins = append(admins, common.ProjectAdmin{Username: username, Source: source})
+	}
+
+	// userNames contains the users of all admin rolebindings
+	for _, u := range adminRoleBinding.S("userNames").Children() {
+		name, _ := u.Data().(string)
+		if functionalAccount != "" && strings.ToLower(name) == strings.ToLower(functionalAccount) {
+			add(name, adminSourceFunctionalAccount)
+			continue
+		}
+		add(name, adminSourceUser)
+	}
+	for _, o := range operators {
+		add(o, adminSourceOperator)
+	}
+
+	sort.SliceStable(admins, func(i, j int) bool {
+		return admins[i].Username < admins[j].Username
+	})
+	return admins
+}
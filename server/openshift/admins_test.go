@@ -0,0 +1,221 @@
+package openshift
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestClassifyProjectAdmins(t *testing.T) {
+	roleBinding, err := gabs.ParseJSON([]byte(`{"userNames":["u123456","U123456","fkt-account","e654321"],"groupNames":["operator"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admins := classifyProjectAdmins(roleBinding, []string{"OP111111", "u123456"}, "FKT-ACCOUNT")
+	expected := []common.ProjectAdmin{
+		{Username: "e654321", Source: adminSourceUser},
+		{Username: "fkt-account", Source: adminSourceFunctionalAccount},
+		{Username: "op111111", Source: adminSourceOperator},
+		{Username: "u123456", Source: adminSourceUser},
+	}
+	if !reflect.DeepEqual(admins, expected) {
+		t.Errorf("Expected %v, got %v", expected, admins)
+	}
+
+	if !isDirectAdmin(admins, "U123456") {
+		t.Error("Expected U123456 to be a direct admin")
+	}
+	if isDirectAdmin(admins, "op111111") {
+		t.Error("Expected op111111 not to be a direct admin")
+	}
+}
+
+func TestGetAdminGroups(t *testing.T) {
+	roleBinding, err := gabs.ParseJSON([]byte(`{"groupNames":["operator","team-a","Team-B"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := getAdminGroups(roleBinding)
+	expected := []string{"team-a", "Team-B"}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected %v, got %v", expected, groups)
+	}
+}
+
+func TestGetAdminRoleBindingIgnoresServiceAccounts(t *testing.T) {
+	config.Init("bla")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items": [
+			{"metadata": {"name": "admin"}, "roleRef": {"name": "admin"}, "subjects": [
+				{"kind": "User", "name": "U123456"},
+				{"kind": "Group", "name": "team"},
+				{"kind": "ServiceAccount", "name": "deployer", "namespace": "app"}]},
+			{"metadata": {"name": "view"}, "roleRef": {"name": "view"}, "subjects": [{"kind": "User", "name": "u999999"}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "url": server.URL, "token": "token"}})
+	defer config.Config().Set("openshift", nil)
+
+	adminRoleBinding, err := getAdminRoleBinding("c1", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users := adminRoleBinding.S("userNames").Data(); !reflect.DeepEqual(users, []interface{}{"u123456"}) {
+		t.Errorf("Expected only the user u123456, got %v", users)
+	}
+	if groups := adminRoleBinding.S("groupNames").Data(); !reflect.DeepEqual(groups, []interface{}{"team"}) {
+		t.Errorf("Expected only the group team, got %v", groups)
+	}
+}
+
+func TestGetProjectAdminDetailsOfAllBindings(t *testing.T) {
+	config.Init("bla")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items": [
+			{"metadata": {"name": "admin"}, "roleRef": {"name": "admin"}, "subjects": [{"kind": "User", "name": "u123456"}]},
+			{"metadata": {"name": "admin-0"}, "roleRef": {"name": "admin"}, "subjects": [{"kind": "User", "name": "u654321"}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "url": server.URL, "token": "token"}})
+	defer config.Config().Set("openshift", nil)
+
+	admins, err := getProjectAdminDetails("c1", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isDirectAdmin(admins, "u654321") {
+		t.Errorf("Expected the admin of the second rolebinding to be found, got %v", admins)
+	}
+	if isAdmin, remaining := countRemainingAdmins(admins, "u123456"); !isAdmin || remaining != 1 {
+		t.Errorf("Expected u123456 to be admin with 1 remaining admin, got %v, %v", isAdmin, remaining)
+	}
+}
+
+func TestRemoveProjectPermission(t *testing.T) {
+	config.Init("bla")
+
+	saved := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			body, _ := ioutil.ReadAll(r.Body)
+			saved[r.URL.Path] = string(body)
+			w.Write(body)
+			return
+		}
+		w.Write([]byte(`{"items": [
+			{"metadata": {"name": "admin"}, "roleRef": {"kind": "ClusterRole", "name": "admin"}, "subjects": [
+				{"kind": "User", "name": "u123"}, {"kind": "User", "name": "U123"}, {"kind": "User", "name": "u456"}]},
+			{"metadata": {"name": "admin-0"}, "roleRef": {"kind": "ClusterRole", "name": "admin"}, "subjects": [
+				{"kind": "User", "name": "u123"}, {"kind": "Group", "name": "devops"}]},
+			{"metadata": {"name": "view"}, "roleRef": {"kind": "ClusterRole", "name": "view"}, "subjects": [{"kind": "User", "name": "u123"}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "url": server.URL, "token": "token"}})
+	defer config.Config().Set("openshift", nil)
+
+	if err := removeProjectPermission("c1", "app", "u123"); err != nil {
+		t.Fatal(err)
+	}
+	url := "/apis/rbac.authorization.k8s.io/v1/namespaces/app/rolebindings/"
+	if len(saved) != 2 {
+		t.Fatalf("expected both admin rolebindings to be saved, got %v", saved)
+	}
+	admin, admin0 := saved[url+"admin"], saved[url+"admin-0"]
+	if strings.Contains(strings.ToLower(admin), "u123") || !strings.Contains(admin, "u456") || strings.Contains(admin, "devops") {
+		t.Errorf("unexpected admin rolebinding %v", admin)
+	}
+	if strings.Contains(admin0, "u123") || !strings.Contains(admin0, "devops") {
+		t.Errorf("unexpected admin-0 rolebinding %v", admin0)
+	}
+}
+
+func TestChangeProjectGroupPermission(t *testing.T) {
+	config.Init("bla")
+
+	saved := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			body, _ := ioutil.ReadAll(r.Body)
+			saved[r.URL.Path] = string(body)
+			w.Write(body)
+			return
+		}
+		w.Write([]byte(`{"items": [
+			{"metadata": {"name": "project-admin"}, "roleRef": {"kind": "ClusterRole", "name": "admin"}, "subjects": [
+				{"kind": "User", "name": "u123"}]},
+			{"metadata": {"name": "admin-0"}, "roleRef": {"kind": "ClusterRole", "name": "admin"}, "subjects": [
+				{"kind": "Group", "name": "DevOps-Team"}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "url": server.URL, "token": "token"}})
+	defer config.Config().Set("openshift", nil)
+
+	adminRoleBinding, err := getAdminRoleBinding("c1", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups := getAdminGroups(adminRoleBinding); !reflect.DeepEqual(groups, []string{"DevOps-Team"}) {
+		t.Errorf("Expected the group name to keep its case, got %v", groups)
+	}
+
+	if err := changeProjectGroupPermission("c1", "app", "devops-team", true); err == nil {
+		t.Error("Expected an error when adding a group of another admin rolebinding")
+	}
+	if len(saved) != 0 {
+		t.Errorf("Expected nothing to be saved, got %v", saved)
+	}
+
+	url := "/apis/rbac.authorization.k8s.io/v1/namespaces/app/rolebindings/"
+	if err := changeProjectGroupPermission("c1", "app", "DevOps-Team", false); err != nil {
+		t.Fatal(err)
+	}
+	admin0, ok := saved[url+"admin-0"]
+	if !ok || len(saved) != 1 {
+		t.Fatalf("Expected only the rolebinding admin-0 to be saved, got %v", saved)
+	}
+	if strings.Contains(admin0, "DevOps-Team") || strings.Contains(admin0, "groupNames") || strings.Contains(admin0, "userNames") {
+		t.Errorf("unexpected admin-0 rolebinding %v", admin0)
+	}
+}
+
+func TestCountRemainingAdmins(t *testing.T) {
+	var tests = []struct {
+		admins    []common.ProjectAdmin
+		isAdmin   bool
+		remaining int
+	}{
+		{[]common.ProjectAdmin{{Username: "u123", Source: adminSourceUser}}, true, 0},
+		{[]common.ProjectAdmin{{Username: "u123", Source: adminSourceUser}, {Username: "u456", Source: adminSourceUser}}, true, 1},
+		// Operators and functional accounts don't own the project
+		{[]common.ProjectAdmin{{Username: "u123", Source: adminSourceUser}, {Username: "op1", Source: adminSourceOperator}, {Username: "fkt", Source: adminSourceFunctionalAccount}}, true, 0},
+		// A group with members
+		{[]common.ProjectAdmin{{Username: "u123", Source: adminSourceUser}, {Username: "u789", Source: adminSourceGroup, Group: "devops"}}, true, 1},
+		// An empty group or one whose members can't be resolved
+		{[]common.ProjectAdmin{{Username: "u123", Source: adminSourceUser}, {Source: adminSourceGroup, Group: "devops"}}, true, 0},
+		{[]common.ProjectAdmin{{Username: "u456", Source: adminSourceUser}}, false, 1},
+	}
+	for _, test := range tests {
+		isAdmin, remaining := countRemainingAdmins(test.admins, "U123")
+		if isAdmin != test.isAdmin || remaining != test.remaining {
+			t.Errorf("countRemainingAdmins(%+v): expected %v/%v, got %v/%v", test.admins, test.isAdmin, test.remaining, isAdmin, remaining)
+		}
+	}
+}
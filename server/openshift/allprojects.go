@@ -0,0 +1,129 @@
+package openshift
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultClusterTimeoutSeconds = 10
+
+type clusterProjects struct {
+	cluster  OpenshiftCluster
+	projects *gabs.Container
+	err      error
+}
+
+// getAllProjectsHandler returns the projects the user administers on all clusters.
+// Clusters which can't be queried are reported, but don't fail the request.
+func getAllProjectsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	params := c.Request.URL.Query()
+
+	log.Printf("%v has queried all his projects on all clusters", username)
+
+	results := queryAllClusters(getOpenshiftClusters(""), username)
+	schema := getProjectMetadataSchema()
+	for i := range results {
+		if results[i].err == nil {
+			results[i].projects = filterProjects(results[i].projects, params)
+		}
+	}
+	c.JSON(http.StatusOK, mergeClusterProjects(results, schema))
+}
+
+// queryAllClusters gets the projects of the user on the clusters concurrently.
+// Each cluster has to answer within openshift_cluster_timeout_seconds.
+func queryAllClusters(clusters []OpenshiftCluster, username string) []clusterProjects {
+	timeout := time.Duration(getConfigIntOrDefault("openshift_cluster_timeout_seconds", defaultClusterTimeoutSeconds)) * time.Second
+
+	results := make([]clusterProjects, len(clusters))
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		wg.Add(1)
+		go func(i int, cluster OpenshiftCluster) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			projects, err := getAdministeredProjectObjects(ctx, cluster.ID, username)
+			if err != nil && ctx.Err() == context.DeadlineExceeded {
+				err = errors.New("The cluster didn't answer in time")
+			}
+			if err != nil {
+				log.Printf("Error getting projects of cluster %v: %v", cluster.ID, err)
+			}
+			results[i] = clusterProjects{cluster: cluster, projects: projects, err: err}
+		}(i, cluster)
+	}
+	wg.Wait()
+	return results
+}
+
+// getAdministeredProjectObjects returns the projects of the cluster,
+// where the user is admin directly or through an LDAP group
+func getAdministeredProjectObjects(ctx context.Context, clusterId, username string) (*gabs.Container, error) {
+	projects, err := getProjectsWithContext(ctx, clusterId, username)
+	if err != nil {
+		return nil, err
+	}
+	administered, err := getAdministeredProjectsWithContext(ctx, clusterId, username)
+	if err != nil {
+		return nil, err
+	}
+	return filterProjectsByName(projects, administered), nil
+}
+
+func filterProjectsByName(projects *gabs.Container, names []string) *gabs.Container {
+	filtered, _ := gabs.New().Array()
+	for _, project := range projects.Children() {
+		if name, _ := project.Path("metadata.name").Data().(string); common.ContainsStringI(names, name) {
+			filtered.ArrayAppend(project.Data())
+		}
+	}
+	return filtered
+}
+
+func mergeClusterProjects(results []clusterProjects, schema []MetadataField) common.AllProjectsResponse {
+	response := common.AllProjectsResponse{
+		Projects:       []common.ClusterProject{},
+		FailedClusters: []common.ClusterError{},
+	}
+	for _, r := range results {
+		if r.err != nil {
+			response.FailedClusters = append(response.FailedClusters, common.ClusterError{
+				ClusterId:   r.cluster.ID,
+				ClusterName: r.cluster.Name,
+				Message:     r.err.Error(),
+			})
+			continue
+		}
+		for _, project := range r.projects.Children() {
+			name, ok := project.Path("metadata.name").Data().(string)
+			if !ok {
+				continue
+			}
+			response.Projects = append(response.Projects, common.ClusterProject{
+				ClusterId:   r.cluster.ID,
+				ClusterName: r.cluster.Name,
+				Name:        name,
+				Metadata:    getMetadataFromAnnotations(schema, project),
+			})
+		}
+	}
+	sort.SliceStable(response.Projects, func(i, j int) bool {
+		if response.Projects[i].Name != response.Projects[j].Name {
+			return response.Projects[i].Name < response.Projects[j].Name
+		}
+		return response.Projects[i].ClusterId < response.Projects[j].ClusterId
+	})
+	return response
+}
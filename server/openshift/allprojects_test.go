@@ -0,0 +1,67 @@
+package openshift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestQueryAllClusters(t *testing.T) {
+	config.Init("bla")
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/apis/rbac.authorization.k8s.io/v1/rolebindings" {
+			w.Write([]byte(`{"items": [
+				{"metadata": {"namespace": "a-project"}, "roleRef": {"name": "admin"}, "subjects": [{"kind": "User", "name": "USER"}]},
+				{"metadata": {"namespace": "b-project"}, "roleRef": {"name": "admin"}, "subjects": [{"kind": "User", "name": "user"}]},
+				{"metadata": {"namespace": "other-project"}, "roleRef": {"name": "view"}, "subjects": [{"kind": "User", "name": "user"}]}
+			]}`))
+			return
+		}
+		w.Write([]byte(`{"items": [
+			{"metadata": {"name": "b-project", "annotations": {"openshift.io/kontierung-element": "1234"}}},
+			{"metadata": {"name": "a-project"}},
+			{"metadata": {"name": "other-project"}}
+		]}`))
+	}))
+	defer fast.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(3 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	clusters := []map[string]interface{}{
+		{"id": "fast", "name": "Fast", "url": fast.URL, "token": "token"},
+		{"id": "slow", "name": "Slow", "url": slow.URL, "token": "token"},
+		{"id": "unconfigured", "name": "Unconfigured", "url": fast.URL},
+	}
+	config.Config().Set("openshift", clusters)
+	config.Config().Set("openshift_cluster_timeout_seconds", 1)
+	defer config.Config().Set("openshift", nil)
+	defer config.Config().Set("openshift_cluster_timeout_seconds", nil)
+
+	results := queryAllClusters(getOpenshiftClusters(""), "user")
+	response := mergeClusterProjects(results, defaultMetadataSchema)
+
+	if len(response.Projects) != 2 {
+		t.Fatalf("Expected the 2 administered projects, got %v", response.Projects)
+	}
+	if response.Projects[0].Name != "a-project" || response.Projects[0].ClusterId != "fast" {
+		t.Errorf("Expected a-project on fast first, got %+v", response.Projects[0])
+	}
+	if response.Projects[1].Metadata[metadataBilling] != "1234" {
+		t.Errorf("Expected the accounting number 1234, got %v", response.Projects[1].Metadata)
+	}
+	if len(response.FailedClusters) != 2 {
+		t.Fatalf("Expected 2 failed clusters, got %v", response.FailedClusters)
+	}
+	if response.FailedClusters[0].ClusterId != "slow" || response.FailedClusters[0].Message != "The cluster didn't answer in time" {
+		t.Errorf("Expected a timeout of the cluster slow, got %+v", response.FailedClusters[0])
+	}
+}
@@ -0,0 +1,94 @@
+package openshift
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+// IsOperatorOfAllClusters checks that the user is operator on every cluster.
+// Changes across all clusters are only allowed for them.
+func IsOperatorOfAllClusters(username string) bool {
+	clusters := getOpenshiftClusters("")
+	if len(clusters) == 0 {
+		return false
+	}
+	for _, cluster := range clusters {
+		operator, err := isOperator(cluster.ID, username)
+		if err != nil {
+			log.Printf("Error checking operator permissions on cluster %v: %v", cluster.ID, err)
+			return false
+		}
+		if !operator {
+			return false
+		}
+	}
+	return true
+}
+
+// FindProjectsByBilling returns the projects of all clusters with the
+// accounting number or MEGAID. Clusters which can't be queried are returned separately.
+func FindProjectsByBilling(billing, megaId string) ([]common.BillingResource, []common.BillingSourceError) {
+	schema := getProjectMetadataSchema()
+	resources := []common.BillingResource{}
+	failed := []common.BillingSourceError{}
+	for _, cluster := range getOpenshiftClusters("") {
+		namespaces, err := getNamespaces(cluster.ID)
+		if err != nil {
+			log.Printf("Error getting namespaces of cluster %v: %v", cluster.ID, err)
+			failed = append(failed, common.BillingSourceError{Source: cluster.ID, Message: err.Error()})
+			continue
+		}
+		resources = append(resources, getProjectsByBilling(cluster.ID, schema, namespaces, billing, megaId)...)
+	}
+	return resources, failed
+}
+
+func getProjectsByBilling(clusterId string, schema []MetadataField, namespaces []*gabs.Container, billing, megaId string) []common.BillingResource {
+	resources := []common.BillingResource{}
+	for _, ns := range namespaces {
+		metadata := getMetadataFromAnnotations(schema, ns)
+		if billing != "" && !strings.EqualFold(metadata[metadataBilling], billing) {
+			continue
+		}
+		if megaId != "" && !strings.EqualFold(metadata[metadataMegaId], megaId) {
+			continue
+		}
+		name, _ := ns.Path("metadata.name").Data().(string)
+		resources = append(resources, common.BillingResource{
+			Type:     common.BillingResourceProject,
+			Location: clusterId,
+			Name:     name,
+			Billing:  metadata[metadataBilling],
+			MegaId:   metadata[metadataMegaId],
+		})
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Name < resources[j].Name })
+	return resources
+}
+
+// ValidateBilling checks the new accounting number and MEGAID against the
+// project metadata schema. Empty values are left unchanged, so they aren't required.
+// Values of fields, which aren't in the schema, are ignored.
+func ValidateBilling(billing, megaId string) error {
+	return validateMetadata(getProjectMetadataSchema(), withLegacyMetadata(nil, billing, megaId), true)
+}
+
+// UpdateProjectBilling sets the non empty values on the project.
+// The requester of the project stays unchanged.
+func UpdateProjectBilling(clusterId, project, billing, megaId, username string) error {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return err
+	}
+	metadata := map[string]string{metadataBilling: billing, metadataMegaId: megaId}
+	setMetadataAnnotations(getProjectMetadataSchema(), namespace, metadata)
+	if err := updateNamespace(clusterId, project, namespace); err != nil {
+		return err
+	}
+	log.Printf("%v changed the billing of project %v on cluster %v: %v", username, project, clusterId, formatMetadata(metadata))
+	return nil
+}
@@ -0,0 +1,45 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+func TestGetProjectsByBilling(t *testing.T) {
+	namespace := func(name, billing, megaId string) *gabs.Container {
+		ns := gabs.New()
+		ns.Set(name, "metadata", "name")
+		ns.Set(billing, "metadata", "annotations", "openshift.io/kontierung-element")
+		ns.Set(megaId, "metadata", "annotations", "openshift.io/MEGAID")
+		return ns
+	}
+	namespaces := []*gabs.Container{
+		namespace("b-project", "1111", "M1"),
+		namespace("a-project", "1111", "M2"),
+		namespace("other", "2222", "M1"),
+	}
+
+	var tests = []struct {
+		billing  string
+		megaId   string
+		expected []string
+	}{
+		{"1111", "", []string{"a-project", "b-project"}},
+		{"", "m1", []string{"b-project", "other"}},
+		{"3333", "", []string{}},
+	}
+	for _, test := range tests {
+		resources := getProjectsByBilling("cluster1", defaultMetadataSchema, namespaces, test.billing, test.megaId)
+		if len(resources) != len(test.expected) {
+			t.Errorf("billing %v, megaid %v: expected %v, got %v", test.billing, test.megaId, test.expected, resources)
+			continue
+		}
+		for i, r := range resources {
+			if r.Name != test.expected[i] || r.Location != "cluster1" || r.Type != common.BillingResourceProject {
+				t.Errorf("billing %v, megaid %v: unexpected resource %+v", test.billing, test.megaId, r)
+			}
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	blueprintAnnotation        = "openshift.io/blueprint"
+	blueprintAppliedAnnotation = "openshift.io/blueprint-applied"
+)
+
+// ProjectBlueprint is a set of objects, which are created in new projects.
+// Blueprints are defined in the config (openshift_project_blueprints).
+type ProjectBlueprint struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Default     bool                       `json:"default"`
+	Labels      map[string]string          `json:"-"`
+	Quota       map[string]string          `json:"-"`
+	LimitRange  *common.LimitRangeDefaults `json:"-"`
+	// Complete NetworkPolicy objects
+	NetworkPolicies []map[string]interface{} `json:"-"`
+	RoleBindings    []BlueprintRoleBinding   `json:"-"`
+	PullSecrets     []BlueprintPullSecret    `json:"-"`
+}
+
+type BlueprintRoleBinding struct {
+	Name string
+	// ClusterRole bound in the project
+	Role   string
+	Users  []string
+	Groups []string
+}
+
+type BlueprintPullSecret struct {
+	Name     string
+	Registry string
+	Username string
+	Password string
+	// Defaults to the default service account
+	ServiceAccounts []string
+}
+
+func getProjectBlueprintsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, getProjectBlueprints())
+}
+
+// applyProjectBlueprintHandler applies the blueprint to an existing project.
+// Without blueprint, the one recorded on the project is applied again.
+func applyProjectBlueprintHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ApplyBlueprintCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	namespace, err := getNamespace(data.ClusterId, data.Project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	current := getAnnotation(namespace, blueprintAnnotation)
+	name := data.Blueprint
+	if name == "" {
+		name = current
+	}
+
+	blueprint, err := getProjectBlueprint(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if blueprint == nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "A blueprint must be provided"})
+		return
+	}
+
+	operator, err := isOperator(data.ClusterId, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := validateBlueprintChange(current, *blueprint, operator); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if err := validateProjectBlueprint(*blueprint); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := applyProjectBlueprint(data.ClusterId, data.Project, username, *blueprint); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The blueprint %v has been applied to the project %v", blueprint.Name, data.Project),
+	})
+}
+
+func getProjectBlueprints() []ProjectBlueprint {
+	blueprints := []ProjectBlueprint{}
+	if err := config.Config().UnmarshalKey("openshift_project_blueprints", &blueprints); err != nil {
+		log.Printf("WARNING: Invalid openshift_project_blueprints configuration: %v", err)
+	}
+	return blueprints
+}
+
+// getProjectBlueprint returns the blueprint with the name or the default
+// blueprint if the name is empty. The result is nil if there is no default.
+func getProjectBlueprint(name string) (*ProjectBlueprint, error) {
+	for _, b := range getProjectBlueprints() {
+		b := b
+		if (name == "" && b.Default) || (name != "" && b.Name == name) {
+			return &b, nil
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("The blueprint %v doesn't exist", name)
+}
+
+// validateBlueprintChange checks if the user may apply the blueprint to a project
+// with the current blueprint. Blueprints can grant more resources, so only operators
+// can switch them. Projects created without blueprint can only get the default one.
+func validateBlueprintChange(current string, blueprint ProjectBlueprint, isOperator bool) error {
+	switch {
+	case isOperator || blueprint.Name == current:
+		return nil
+	case current == "":
+		if !blueprint.Default {
+			return fmt.Errorf("The project has no blueprint. Only operators can apply the blueprint %v", blueprint.Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("The project uses the blueprint %v. Only operators can change it", current)
+	}
+}
+
+// validateProjectBlueprint checks the quotas of the blueprint against the maxima,
+// which also apply when users change the quotas themselves
+func validateProjectBlueprint(blueprint ProjectBlueprint) error {
+	if err := validateQuotaValues(normalizeQuotaResources(blueprint.Quota), getQuotaMaxima()); err != nil {
+		return fmt.Errorf("The blueprint %v is invalid: %v", blueprint.Name, err.Error())
+	}
+	return nil
+}
+
+// applyProjectBlueprint creates or updates the objects of the blueprint.
+// It can be applied multiple times.
+func applyProjectBlueprint(clusterId, project, username string, blueprint ProjectBlueprint) error {
+	if len(blueprint.Quota) > 0 {
+		if err := updateProjectQuotas(clusterId, username, project, normalizeQuotaResources(blueprint.Quota)); err != nil {
+			return err
+		}
+	}
+	if blueprint.LimitRange != nil {
+		if err := updateLimitRangeDefaults(clusterId, username, project, *blueprint.LimitRange); err != nil {
+			return err
+		}
+	}
+
+	for _, np := range blueprint.NetworkPolicies {
+		policy := gabs.Wrap(normalizeConfigValue(np))
+		policy.Set("networking.k8s.io/v1", "apiVersion")
+		policy.Set("NetworkPolicy", "kind")
+		name, _ := policy.Path("metadata.name").Data().(string)
+		if err := applyObject(clusterId, fmt.Sprintf("apis/networking.k8s.io/v1/namespaces/%v/networkpolicies", project), name, policy); err != nil {
+			return err
+		}
+	}
+
+	for _, rb := range blueprint.RoleBindings {
+		if err := applyObject(clusterId, fmt.Sprintf("apis/rbac.authorization.k8s.io/v1/namespaces/%v/rolebindings", project), rb.Name, newBlueprintRoleBinding(rb)); err != nil {
+			return err
+		}
+	}
+
+	for _, ps := range blueprint.PullSecrets {
+		secret := newDockerConfigSecret(ps.Name, ps.Registry, ps.Username, ps.Password)
+		if err := applyObject(clusterId, fmt.Sprintf("api/v1/namespaces/%v/secrets", project), ps.Name, secret); err != nil {
+			return err
+		}
+		serviceAccounts := ps.ServiceAccounts
+		if len(serviceAccounts) == 0 {
+			serviceAccounts = []string{"default"}
+		}
+		for _, sa := range serviceAccounts {
+			// The default service accounts are created shortly after the project
+			if err := waitForServiceAccount(clusterId, project, sa); err != nil {
+				return err
+			}
+			linked, err := isPullSecretLinked(clusterId, project, sa, ps.Name)
+			if err != nil {
+				return err
+			}
+			if linked {
+				continue
+			}
+			if err := linkPullSecret(clusterId, project, sa, ps.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Labels and the annotation are set last, so that the blueprint is only
+	// recorded if everything else has been applied
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return err
+	}
+	for k, v := range blueprint.Labels {
+		namespace.Set(v, "metadata", "labels", k)
+	}
+	namespace.Set(blueprint.Name, "metadata", "annotations", blueprintAnnotation)
+	namespace.Set(time.Now().Format(time.RFC3339), "metadata", "annotations", blueprintAppliedAnnotation)
+	if err := updateNamespace(clusterId, project, namespace); err != nil {
+		return err
+	}
+
+	log.Printf("%v applied the blueprint %v to the project %v on cluster %v", username, blueprint.Name, project, clusterId)
+	return nil
+}
+
+func newBlueprintRoleBinding(rb BlueprintRoleBinding) *gabs.Container {
+	roleBinding := newObjectRequest("RoleBinding", rb.Name, "rbac.authorization.k8s.io/v1")
+	roleBinding.Set("rbac.authorization.k8s.io", "roleRef", "apiGroup")
+	roleBinding.Set("ClusterRole", "roleRef", "kind")
+	roleBinding.Set(rb.Role, "roleRef", "name")
+	roleBinding.Array("subjects")
+	for _, u := range rb.Users {
+		roleBinding.ArrayAppend(OpenshiftSubject{ApiGroup: "rbac.authorization.k8s.io", Kind: "User", Name: u}, "subjects")
+	}
+	for _, g := range rb.Groups {
+		roleBinding.ArrayAppend(OpenshiftSubject{ApiGroup: "rbac.authorization.k8s.io", Kind: "Group", Name: g}, "subjects")
+	}
+	return roleBinding
+}
+
+// applyObject creates the object or replaces it if it already exists
+func applyObject(clusterId, collectionURL, name string, object *gabs.Container) error {
+	resp, err := getOseHTTPClient("GET", clusterId, collectionURL+"/"+name, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return saveObject(clusterId, "POST", collectionURL, object)
+	}
+
+	existing, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return errors.New(genericAPIError)
+	}
+	// The resourceVersion is needed to replace the object
+	object.Set(existing.Path("metadata.resourceVersion").Data(), "metadata", "resourceVersion")
+	return saveObject(clusterId, "PUT", collectionURL+"/"+name, object)
+}
+
+// normalizeConfigValue converts the maps of the yaml config,
+// so that they can be marshalled to json
+func normalizeConfigValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range v {
+			m[fmt.Sprint(k)] = normalizeConfigValue(val)
+		}
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range v {
+			m[k] = normalizeConfigValue(val)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, val := range v {
+			l[i] = normalizeConfigValue(val)
+		}
+		return l
+	default:
+		return v
+	}
+}
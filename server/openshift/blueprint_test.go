@@ -0,0 +1,128 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestGetProjectBlueprint(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("openshift_project_blueprints", []map[string]interface{}{
+		{"name": "small"},
+		{"name": "standard", "default": true},
+	})
+	defer config.Config().Set("openshift_project_blueprints", nil)
+
+	var tests = []struct {
+		name     string
+		expected string
+		valid    bool
+	}{
+		{"", "standard", true},
+		{"small", "small", true},
+		{"unknown", "", false},
+	}
+	for _, test := range tests {
+		blueprint, err := getProjectBlueprint(test.name)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("Expected an error for the blueprint %v", test.name)
+			}
+			continue
+		}
+		if err != nil || blueprint == nil || blueprint.Name != test.expected {
+			t.Errorf("getProjectBlueprint(%v): expected %v, got %v (%v)", test.name, test.expected, blueprint, err)
+		}
+	}
+}
+
+func TestValidateBlueprintChange(t *testing.T) {
+	standard := ProjectBlueprint{Name: "standard", Default: true}
+	large := ProjectBlueprint{Name: "large"}
+	var tests = []struct {
+		current    string
+		blueprint  ProjectBlueprint
+		isOperator bool
+		valid      bool
+	}{
+		{"large", large, false, true},
+		{"standard", large, false, false},
+		{"standard", large, true, true},
+		{"", standard, false, true},
+		{"", large, false, false},
+		{"", large, true, true},
+	}
+	for _, test := range tests {
+		err := validateBlueprintChange(test.current, test.blueprint, test.isOperator)
+		if test.valid && err != nil {
+			t.Errorf("Expected %v -> %v (operator %v) to be valid, got %v", test.current, test.blueprint.Name, test.isOperator, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected %v -> %v (operator %v) to be invalid", test.current, test.blueprint.Name, test.isOperator)
+		}
+	}
+}
+
+func TestValidateProjectBlueprint(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("openshift_quota_limits", []map[string]interface{}{{"resource": "limits.cpu", "max": "8"}})
+	defer config.Config().Set("openshift_quota_limits", nil)
+
+	var tests = []struct {
+		quota map[string]string
+		valid bool
+	}{
+		{nil, true},
+		{map[string]string{"Limits.CPU": "8"}, true},
+		{map[string]string{"limits.cpu": "16"}, false},
+		{map[string]string{"pods": "20"}, false},
+	}
+	for _, test := range tests {
+		err := validateProjectBlueprint(ProjectBlueprint{Name: "large", Quota: test.quota})
+		if test.valid && err != nil {
+			t.Errorf("Expected the quota %v to be valid, got %v", test.quota, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected the quota %v to be invalid", test.quota)
+		}
+	}
+}
+
+func TestNewBlueprintRoleBinding(t *testing.T) {
+	rb, _ := gabs.ParseJSON(newBlueprintRoleBinding(BlueprintRoleBinding{
+		Name:   "view-support",
+		Role:   "view",
+		Users:  []string{"u1"},
+		Groups: []string{"support"},
+	}).Bytes())
+	if rb.Path("roleRef.name").Data() != "view" {
+		t.Errorf("Expected roleRef view, got %v", rb.Path("roleRef.name").Data())
+	}
+	subjects := rb.S("subjects").Children()
+	if len(subjects) != 2 {
+		t.Fatalf("Expected 2 subjects, got %v", len(subjects))
+	}
+	if subjects[1].S("kind").Data() != "Group" || subjects[1].S("name").Data() != "support" {
+		t.Errorf("Expected the group support, got %v", subjects[1])
+	}
+}
+
+func TestNormalizeConfigValue(t *testing.T) {
+	value := normalizeConfigValue(map[interface{}]interface{}{
+		"spec": map[interface{}]interface{}{
+			"podSelector": map[interface{}]interface{}{},
+			"ingress":     []interface{}{map[interface{}]interface{}{"from": 1}},
+		},
+	})
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected map[string]interface{}, got %T", value)
+	}
+	spec := m["spec"].(map[string]interface{})
+	if _, ok := spec["ingress"].([]interface{})[0].(map[string]interface{}); !ok {
+		t.Errorf("Expected nested maps to be converted, got %v", spec)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
 	"github.com/gin-gonic/gin"
 )
@@ -19,6 +20,12 @@ type OpenshiftCluster struct {
 	URL        string      `json:"url"`
 	GlusterApi *GlusterApi `json:"-"`
 	NfsApi     *NfsApi     `json:"-"`
+	// LDAP groups, which may create projects. Everybody if empty.
+	ProjectCreationGroups []string `json:"-"`
+	// Patterns of custom route hostnames, e.g. *.apps.example.com
+	AllowedRouteDomains []string `json:"allowedRouteDomains"`
+	// Storage classes which may be used for volumes of the technology "dynamic"
+	DynamicStorageClasses []string `json:"dynamicStorageClasses"`
 }
 
 type GlusterApi struct {
@@ -33,12 +40,30 @@ type NfsApi struct {
 	Secret       string `json:"-"`
 	Proxy        string `json:"-"`
 	StorageClass string `json:"-"`
+	// Workflow deleting nfs volumes. They can't be deleted if empty.
+	DeleteWorkflowUuid string `json:"-"`
 }
 
+// clustersHandler returns the clusters with their cached status
+// and whether the user may create projects
 func clustersHandler(c *gin.Context) {
-	//username := common.GetUserName(c)
+	username := common.GetUserName(c)
 	clusters := getOpenshiftClusters(c.Query("feature"))
-	c.JSON(http.StatusOK, clusters)
+
+	result := []ClusterInfo{}
+	for _, cluster := range clusters {
+		info := ClusterInfo{
+			OpenshiftCluster: cluster,
+			Status:           getCachedClusterStatus(cluster.ID),
+		}
+		if err := validateProjectCreationPermission(cluster, username); err != nil {
+			info.Eligibility = ClusterEligibility{Reason: err.Error()}
+		} else {
+			info.Eligibility = ClusterEligibility{CanCreateProjects: true}
+		}
+		result = append(result, info)
+	}
+	c.JSON(http.StatusOK, result)
 }
 
 func getOpenshiftClusters(feature string) []OpenshiftCluster {
@@ -0,0 +1,292 @@
+package openshift
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+const defaultClusterStatusRefreshMinutes = 5
+
+// ClusterInfo is a cluster of the config with its live status
+type ClusterInfo struct {
+	OpenshiftCluster
+	// nil until the status has been loaded the first time
+	Status      *ClusterStatus     `json:"status"`
+	Eligibility ClusterEligibility `json:"eligibility"`
+}
+
+type ClusterStatus struct {
+	Reachable bool   `json:"reachable"`
+	Version   string `json:"version"`
+	Error     string `json:"error,omitempty"`
+	// CPU in cores and memory in GiB of the worker nodes
+	AllocatableCPU    float64         `json:"allocatableCpu"`
+	RequestedCPU      float64         `json:"requestedCpu"`
+	AllocatableMemory float64         `json:"allocatableMemory"`
+	RequestedMemory   float64         `json:"requestedMemory"`
+	Storage           []StorageStatus `json:"storage"`
+	UpdatedAt         time.Time       `json:"updatedAt"`
+}
+
+type StorageStatus struct {
+	Technology string `json:"technology"`
+	Available  bool   `json:"available"`
+	// Only known for gluster
+	TotalGB *float64 `json:"totalGb,omitempty"`
+	FreeGB  *float64 `json:"freeGb,omitempty"`
+}
+
+type ClusterEligibility struct {
+	CanCreateProjects bool   `json:"canCreateProjects"`
+	Reason            string `json:"reason,omitempty"`
+}
+
+var clusterStatusCache = struct {
+	sync.RWMutex
+	status map[string]*ClusterStatus
+}{status: map[string]*ClusterStatus{}}
+
+func getCachedClusterStatus(clusterId string) *ClusterStatus {
+	clusterStatusCache.RLock()
+	defer clusterStatusCache.RUnlock()
+	return clusterStatusCache.status[clusterId]
+}
+
+// startClusterStatusRefresh loads the status of all clusters
+// every cluster_status_refresh_minutes in the background
+func startClusterStatusRefresh() {
+	interval := getConfigIntOrDefault("cluster_status_refresh_minutes", defaultClusterStatusRefreshMinutes)
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+		defer ticker.Stop()
+		for {
+			refreshClusterStatus()
+			<-ticker.C
+		}
+	}()
+}
+
+func refreshClusterStatus() {
+	timeout := time.Duration(getConfigIntOrDefault("openshift_cluster_timeout_seconds", defaultClusterTimeoutSeconds)) * time.Second
+
+	var wg sync.WaitGroup
+	for _, cluster := range getOpenshiftClusters("") {
+		wg.Add(1)
+		go func(cluster OpenshiftCluster) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			status := getClusterStatus(ctx, cluster)
+			clusterStatusCache.Lock()
+			clusterStatusCache.status[cluster.ID] = status
+			clusterStatusCache.Unlock()
+		}(cluster)
+	}
+	wg.Wait()
+}
+
+// getClusterStatus checks the storage APIs in parallel to the cluster API,
+// so a slow storage API doesn't use up the timeout of the cluster API
+func getClusterStatus(ctx context.Context, cluster OpenshiftCluster) *ClusterStatus {
+	storage := make(chan []StorageStatus, 1)
+	go func() {
+		storage <- getStorageStatus(ctx, cluster)
+	}()
+
+	status := &ClusterStatus{UpdatedAt: time.Now()}
+	setClusterAPIStatus(ctx, cluster.ID, status)
+	status.Storage = <-storage
+	return status
+}
+
+// setClusterAPIStatus sets the version and capacity of the cluster
+func setClusterAPIStatus(ctx context.Context, clusterId string, status *ClusterStatus) {
+	version, err := getClusterVersion(ctx, clusterId)
+	if err != nil {
+		log.Printf("Cluster %v is not reachable: %v", clusterId, err)
+		status.Error = err.Error()
+		return
+	}
+	status.Reachable = true
+	status.Version = version
+
+	nodes, err := getClusterObjects(ctx, clusterId, "api/v1/nodes")
+	if err != nil {
+		status.Error = err.Error()
+		return
+	}
+	pods, err := getClusterObjects(ctx, clusterId, "api/v1/pods?fieldSelector=status.phase=Running")
+	if err != nil {
+		status.Error = err.Error()
+		return
+	}
+	setClusterCapacity(status, nodes, pods)
+}
+
+func getClusterVersion(ctx context.Context, clusterId string) (string, error) {
+	resp, err := getOseHTTPClientWithContext(ctx, "GET", clusterId, "version", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("The API returned %v", resp.StatusCode)
+	}
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return "", errors.New(genericAPIError)
+	}
+	version, _ := json.S("gitVersion").Data().(string)
+	return version, nil
+}
+
+func getClusterObjects(ctx context.Context, clusterId, url string) ([]*gabs.Container, error) {
+	resp, err := getOseHTTPClientWithContext(ctx, "GET", clusterId, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+	return json.S("items").Children(), nil
+}
+
+// setClusterCapacity sums the allocatable resources of the worker nodes
+// and the requests of the pods running on them
+func setClusterCapacity(status *ClusterStatus, nodes, pods []*gabs.Container) {
+	workers := map[string]bool{}
+	for _, node := range nodes {
+		if !isWorkerNode(node) {
+			continue
+		}
+		name, _ := node.Path("metadata.name").Data().(string)
+		workers[name] = true
+		cpu, _ := parseQuantity(fmt.Sprint(node.Path("status.allocatable.cpu").Data()))
+		memory, _ := parseQuantity(fmt.Sprint(node.Path("status.allocatable.memory").Data()))
+		status.AllocatableCPU += cpu
+		status.AllocatableMemory += memory / gib
+	}
+	for _, pod := range pods {
+		node, _ := pod.Path("spec.nodeName").Data().(string)
+		if !workers[node] {
+			continue
+		}
+		for _, container := range pod.Path("spec.containers").Children() {
+			if cpu, ok := container.Path("resources.requests.cpu").Data().(string); ok {
+				value, _ := parseQuantity(cpu)
+				status.RequestedCPU += value
+			}
+			if memory, ok := container.Path("resources.requests.memory").Data().(string); ok {
+				value, _ := parseQuantity(memory)
+				status.RequestedMemory += value / gib
+			}
+		}
+	}
+}
+
+func getStorageStatus(ctx context.Context, cluster OpenshiftCluster) []StorageStatus {
+	storage := []StorageStatus{}
+	if cluster.GlusterApi != nil {
+		storage = append(storage, getGlusterStorageStatus(ctx, cluster))
+	}
+	if cluster.NfsApi != nil {
+		storage = append(storage, StorageStatus{Technology: "nfs", Available: isNfsApiAvailable(ctx, cluster.ID)})
+	}
+	// Dynamic volumes are provisioned by the cluster itself
+	if len(cluster.DynamicStorageClasses) > 0 {
+		storage = append(storage, StorageStatus{Technology: technologyDynamic, Available: true})
+	}
+	return storage
+}
+
+// isNfsApiAvailable checks if the create workflow can be read.
+// The NFS client has no timeout, so the context is checked separately.
+func isNfsApiAvailable(ctx context.Context, clusterId string) bool {
+	available := make(chan bool, 1)
+	go func() {
+		resp, err := getNfsHTTPClient("GET", clusterId, "workflows/"+apiCreateWorkflowUuid, nil)
+		if err != nil {
+			available <- false
+			return
+		}
+		resp.Body.Close()
+		available <- resp.StatusCode == http.StatusOK
+	}()
+	select {
+	case a := <-available:
+		return a
+	case <-ctx.Done():
+		log.Printf("The NFS API of cluster %v didn't answer in time", clusterId)
+		return false
+	}
+}
+
+// getGlusterStorageStatus reads the usage of the lvm pool from the glusterapi
+func getGlusterStorageStatus(ctx context.Context, cluster OpenshiftCluster) StorageStatus {
+	status := StorageStatus{Technology: "gluster"}
+
+	req, err := http.NewRequest("GET", cluster.GlusterApi.URL+"/pool", nil)
+	if err != nil {
+		log.Printf("Error creating glusterapi request for cluster %v: %v", cluster.ID, err)
+		return status
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		log.Printf("Error getting the gluster pool usage of cluster %v: %v", cluster.ID, err)
+		return status
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return status
+	}
+	var pool struct {
+		TotalKiloBytes int `json:"totalKiloBytes"`
+		UsedKiloBytes  int `json:"usedKiloBytes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pool); err != nil {
+		log.Printf(jsonDecodingError, err)
+		return status
+	}
+	total := float64(pool.TotalKiloBytes) / (1024 * 1024)
+	free := float64(pool.TotalKiloBytes-pool.UsedKiloBytes) / (1024 * 1024)
+	status.Available = true
+	status.TotalGB = &total
+	status.FreeGB = &free
+	return status
+}
+
+// validateProjectCreationPermission checks if the user is member of
+// one of the groups, which may create projects on the cluster
+func validateProjectCreationPermission(cluster OpenshiftCluster, username string) error {
+	if len(cluster.ProjectCreationGroups) == 0 {
+		return nil
+	}
+	groups, err := getLdapGroupsOfUser(username)
+	if err != nil {
+		log.Printf("Error getting LDAP groups of %v: %v", username, err)
+		return errors.New(genericAPIError)
+	}
+	for _, g := range cluster.ProjectCreationGroups {
+		if common.ContainsStringI(groups, g) {
+			return nil
+		}
+	}
+	return fmt.Errorf("You are not allowed to create projects on the cluster %v", cluster.Name)
+}
@@ -0,0 +1,123 @@
+package openshift
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestSetClusterCapacity(t *testing.T) {
+	json, _ := gabs.ParseJSON([]byte(`{
+		"nodes": [
+			{"metadata": {"name": "worker1"}, "status": {"allocatable": {"cpu": "4", "memory": "16Gi"}}},
+			{"metadata": {"name": "worker2"}, "status": {"allocatable": {"cpu": "3500m", "memory": "8Gi"}}},
+			{"metadata": {"name": "master1", "labels": {"node-role.kubernetes.io/master": "true"}}, "status": {"allocatable": {"cpu": "4", "memory": "16Gi"}}}
+		],
+		"pods": [
+			{"spec": {"nodeName": "worker1", "containers": [
+				{"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}},
+				{"resources": {}}
+			]}},
+			{"spec": {"nodeName": "worker2", "containers": [{"resources": {"requests": {"cpu": "1"}}}]}},
+			{"spec": {"nodeName": "master1", "containers": [{"resources": {"requests": {"cpu": "2", "memory": "4Gi"}}}]}}
+		]
+	}`))
+
+	status := &ClusterStatus{}
+	setClusterCapacity(status, json.S("nodes").Children(), json.S("pods").Children())
+
+	var tests = []struct {
+		name     string
+		value    float64
+		expected float64
+	}{
+		{"AllocatableCPU", status.AllocatableCPU, 7.5},
+		{"AllocatableMemory", status.AllocatableMemory, 24},
+		{"RequestedCPU", status.RequestedCPU, 1.5},
+		{"RequestedMemory", status.RequestedMemory, 1},
+	}
+	for _, test := range tests {
+		if math.Abs(test.value-test.expected) > 0.001 {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, test.value)
+		}
+	}
+}
+
+func TestGetGlusterStorageStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pool" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"totalKiloBytes": 104857600, "usedKiloBytes": 26214400}`))
+	}))
+	defer server.Close()
+
+	cluster := OpenshiftCluster{ID: "test", GlusterApi: &GlusterApi{URL: server.URL}}
+	status := getGlusterStorageStatus(context.Background(), cluster)
+	if !status.Available || status.TotalGB == nil || *status.TotalGB != 100 || *status.FreeGB != 75 {
+		t.Errorf("Expected 75 of 100 GB free, got %+v", status)
+	}
+
+	cluster.GlusterApi.URL = server.URL + "/unknown"
+	if status := getGlusterStorageStatus(context.Background(), cluster); status.Available {
+		t.Errorf("Expected the storage to be unavailable, got %+v", status)
+	}
+}
+
+func TestGetClusterStatusWithSlowStorage(t *testing.T) {
+	config.Init("bla")
+
+	// The glusterapi doesn't answer until the request is cancelled
+	glusterServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer glusterServer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/version":
+			w.Write([]byte(`{"gitVersion": "v1.20.0"}`))
+		default:
+			w.Write([]byte(`{"items": []}`))
+		}
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "url": server.URL, "token": "token"}})
+	defer config.Config().Set("openshift", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	cluster := OpenshiftCluster{ID: "c1", GlusterApi: &GlusterApi{URL: glusterServer.URL}}
+	status := getClusterStatus(ctx, cluster)
+	if !status.Reachable || status.Version != "v1.20.0" {
+		t.Errorf("Expected the cluster to be reachable, got %+v", status)
+	}
+	if len(status.Storage) != 1 || status.Storage[0].Available {
+		t.Errorf("Expected the gluster storage to be unavailable, got %+v", status.Storage)
+	}
+}
+
+func TestValidateProjectCreationPermission(t *testing.T) {
+	if err := validateProjectCreationPermission(OpenshiftCluster{ID: "test"}, "user"); err != nil {
+		t.Errorf("Expected everybody to be allowed without groups, got %v", err)
+	}
+
+	ldapGroupsCache.Set("member", []string{"DevOps"}, 0)
+	ldapGroupsCache.Set("other", []string{"Finance"}, 0)
+	defer ldapGroupsCache.Flush()
+
+	cluster := OpenshiftCluster{ID: "test", Name: "Test", ProjectCreationGroups: []string{"devops"}}
+	if err := validateProjectCreationPermission(cluster, "member"); err != nil {
+		t.Errorf("Expected member to be allowed, got %v", err)
+	}
+	if err := validateProjectCreationPermission(cluster, "other"); err == nil {
+		t.Errorf("Expected other not to be allowed")
+	}
+}
@@ -0,0 +1,206 @@
+package openshift
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	problemFailedScheduling = "failed-scheduling"
+	problemQuotaExceeded    = "quota-exceeded"
+	problemImagePull        = "image-pull"
+	problemOOMKilled        = "oom-killed"
+)
+
+// getProjectEventsHandler returns the events of the project, newest first.
+// They can be filtered by type (Normal, Warning), kind and name of the object.
+func getProjectEventsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	items, err := getProjectObjects(clusterId, "api/v1/namespaces/"+project+"/events")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	events := filterEvents(parseEvents(items), params.Get("type"), params.Get("kind"), params.Get("name"))
+	c.JSON(http.StatusOK, events)
+}
+
+// getProjectProblemsHandler summarizes the problems of all projects the user administers:
+// failed scheduling, exceeded quotas, image pull errors and OOMKilled containers
+func getProjectProblemsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	if clusterId == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Cluster must be provided"})
+		return
+	}
+
+	projects, err := getAdministeredProjects(clusterId, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	events, err := getProjectObjects(clusterId, "api/v1/events?fieldSelector=type=Warning")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	pods, err := getProjectObjects(clusterId, "api/v1/pods")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	problems := append(getEventProblems(events, projects), getOOMKilledProblems(pods, projects)...)
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].LastSeen.After(problems[j].LastSeen) })
+	c.JSON(http.StatusOK, problems)
+}
+
+func parseEvents(items []*gabs.Container) []common.ProjectEvent {
+	events := []common.ProjectEvent{}
+	for _, item := range items {
+		events = append(events, parseEvent(item))
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].LastSeen.After(events[j].LastSeen) })
+	return events
+}
+
+func parseEvent(item *gabs.Container) common.ProjectEvent {
+	event := common.ProjectEvent{Count: 1}
+	event.Type, _ = item.S("type").Data().(string)
+	event.Reason, _ = item.S("reason").Data().(string)
+	event.Message, _ = item.S("message").Data().(string)
+	event.Kind, _ = item.Path("involvedObject.kind").Data().(string)
+	event.Name, _ = item.Path("involvedObject.name").Data().(string)
+	if count, ok := item.S("count").Data().(float64); ok && count > 0 {
+		event.Count = int(count)
+	}
+	// Events created with the events.k8s.io API only have an eventTime
+	event.FirstSeen = getEventTime(item, "firstTimestamp", "eventTime", "metadata.creationTimestamp")
+	event.LastSeen = getEventTime(item, "lastTimestamp", "eventTime", "metadata.creationTimestamp")
+	return event
+}
+
+// getEventTime returns the first of the fields which is set
+func getEventTime(item *gabs.Container, paths ...string) time.Time {
+	for _, path := range paths {
+		value, ok := item.Path(path).Data().(string)
+		if !ok || value == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func filterEvents(events []common.ProjectEvent, eventType, kind, name string) []common.ProjectEvent {
+	filtered := []common.ProjectEvent{}
+	for _, e := range events {
+		if eventType != "" && !strings.EqualFold(e.Type, eventType) {
+			continue
+		}
+		if kind != "" && !strings.EqualFold(e.Kind, kind) {
+			continue
+		}
+		if name != "" && !strings.Contains(e.Name, name) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered
+}
+
+// getProblemCategory returns the category of a warning or "" if it's not a known problem
+func getProblemCategory(event common.ProjectEvent) string {
+	message := strings.ToLower(event.Message)
+	switch {
+	case event.Reason == "FailedScheduling":
+		return problemFailedScheduling
+	case strings.Contains(message, "exceeded quota"):
+		return problemQuotaExceeded
+	case event.Reason == "ErrImagePull" || event.Reason == "ImagePullBackOff" ||
+		strings.Contains(message, "errimagepull") || strings.Contains(message, "failed to pull image") ||
+		(event.Reason == "BackOff" && strings.Contains(message, "pulling image")):
+		return problemImagePull
+	case strings.Contains(message, "oomkilled"):
+		return problemOOMKilled
+	}
+	return ""
+}
+
+func getEventProblems(items []*gabs.Container, projects []string) []common.ProjectProblem {
+	problems := []common.ProjectProblem{}
+	for _, item := range items {
+		namespace, _ := item.Path("metadata.namespace").Data().(string)
+		if !common.ContainsStringI(projects, namespace) {
+			continue
+		}
+		event := parseEvent(item)
+		category := getProblemCategory(event)
+		if category == "" {
+			continue
+		}
+		problems = append(problems, common.ProjectProblem{
+			Project:  namespace,
+			Category: category,
+			Kind:     event.Kind,
+			Name:     event.Name,
+			Reason:   event.Reason,
+			Message:  event.Message,
+			Count:    event.Count,
+			LastSeen: event.LastSeen,
+		})
+	}
+	return problems
+}
+
+// getOOMKilledProblems returns the containers which were last terminated because
+// they ran out of memory. Kubernetes doesn't create an event for this.
+func getOOMKilledProblems(pods []*gabs.Container, projects []string) []common.ProjectProblem {
+	problems := []common.ProjectProblem{}
+	for _, pod := range pods {
+		namespace, _ := pod.Path("metadata.namespace").Data().(string)
+		if !common.ContainsStringI(projects, namespace) {
+			continue
+		}
+		name, _ := pod.Path("metadata.name").Data().(string)
+		for _, status := range pod.Path("status.containerStatuses").Children() {
+			terminated := status.Path("lastState.terminated")
+			if reason, _ := terminated.S("reason").Data().(string); reason != "OOMKilled" {
+				continue
+			}
+			container, _ := status.S("name").Data().(string)
+			restarts, _ := status.S("restartCount").Data().(float64)
+			problems = append(problems, common.ProjectProblem{
+				Project:  namespace,
+				Category: problemOOMKilled,
+				Kind:     "Pod",
+				Name:     name,
+				Reason:   "OOMKilled",
+				Message:  "The container " + container + " was terminated, because it exceeded its memory limit",
+				Count:    int(restarts),
+				LastSeen: getEventTime(terminated, "finishedAt"),
+			})
+		}
+	}
+	return problems
+}
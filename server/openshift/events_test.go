@@ -0,0 +1,99 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+func TestParseEvents(t *testing.T) {
+	items := []*gabs.Container{}
+	for _, j := range []string{
+		`{"type": "Normal", "reason": "Pulled", "message": "ok", "count": 3,
+		  "involvedObject": {"kind": "Pod", "name": "app-1"},
+		  "firstTimestamp": "2020-08-01T10:00:00Z", "lastTimestamp": "2020-08-01T11:00:00Z"}`,
+		`{"type": "Warning", "reason": "FailedScheduling", "message": "0/3 nodes are available",
+		  "involvedObject": {"kind": "Pod", "name": "app-2"},
+		  "lastTimestamp": null, "eventTime": "2020-08-01T12:00:00Z"}`,
+	} {
+		item, err := gabs.ParseJSON([]byte(j))
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, item)
+	}
+
+	events := parseEvents(items)
+	if len(events) != 2 || events[0].Name != "app-2" {
+		t.Fatalf("expected the newest event first, got %+v", events)
+	}
+	if events[0].Count != 1 || !events[0].LastSeen.Equal(time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected count 1 and eventTime as last seen, got %+v", events[0])
+	}
+	if events[1].Count != 3 || !events[1].FirstSeen.Equal(time.Date(2020, 8, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected event %+v", events[1])
+	}
+
+	var tests = []struct {
+		eventType string
+		kind      string
+		name      string
+		expected  int
+	}{
+		{"", "", "", 2},
+		{"warning", "", "", 1},
+		{"", "Pod", "", 2},
+		{"", "Deployment", "", 0},
+		{"", "", "app-1", 1},
+	}
+	for _, test := range tests {
+		if filtered := filterEvents(events, test.eventType, test.kind, test.name); len(filtered) != test.expected {
+			t.Errorf("filterEvents(%v, %v, %v): expected %v events, got %v", test.eventType, test.kind, test.name, test.expected, len(filtered))
+		}
+	}
+}
+
+func TestGetProblemCategory(t *testing.T) {
+	var tests = []struct {
+		reason   string
+		message  string
+		expected string
+	}{
+		{"FailedScheduling", "0/3 nodes are available: 3 Insufficient memory.", problemFailedScheduling},
+		{"FailedCreate", `pods "app-1" is forbidden: exceeded quota: compute-resources`, problemQuotaExceeded},
+		{"Failed", `Failed to pull image "app:latest": rpc error`, problemImagePull},
+		{"Failed", "Error: ErrImagePull", problemImagePull},
+		{"BackOff", `Back-off pulling image "app:latest"`, problemImagePull},
+		{"BackOff", "Back-off restarting failed container", ""},
+		{"Unhealthy", "Readiness probe failed", ""},
+	}
+	for _, test := range tests {
+		category := getProblemCategory(common.ProjectEvent{Reason: test.reason, Message: test.message})
+		if category != test.expected {
+			t.Errorf("getProblemCategory(%v, %v): expected %q, got %q", test.reason, test.message, test.expected, category)
+		}
+	}
+}
+
+func TestGetOOMKilledProblems(t *testing.T) {
+	pods, _ := gabs.ParseJSON([]byte(`[
+		{"metadata": {"name": "app-1", "namespace": "app"}, "status": {"containerStatuses": [
+			{"name": "web", "restartCount": 4, "lastState": {"terminated": {"reason": "OOMKilled", "finishedAt": "2020-08-01T10:00:00Z"}}},
+			{"name": "sidecar", "restartCount": 0, "lastState": {}}
+		]}},
+		{"metadata": {"name": "other-1", "namespace": "other"}, "status": {"containerStatuses": [
+			{"name": "web", "restartCount": 1, "lastState": {"terminated": {"reason": "OOMKilled"}}}
+		]}}
+	]`))
+
+	problems := getOOMKilledProblems(pods.Children(), []string{"app"})
+	if len(problems) != 1 {
+		t.Fatalf("expected 1 problem, got %+v", problems)
+	}
+	p := problems[0]
+	if p.Project != "app" || p.Name != "app-1" || p.Category != problemOOMKilled || p.Count != 4 {
+		t.Errorf("unexpected problem %+v", p)
+	}
+}
@@ -3,6 +3,7 @@ package openshift
 type Features struct {
 	Nfs     bool `json:"nfs"`
 	Gluster bool `json:"gluster"`
+	Dynamic bool `json:"dynamic"`
 }
 
 func GetFeatures(clusterId string) Features {
@@ -10,5 +11,6 @@ func GetFeatures(clusterId string) Features {
 	return Features{
 		Gluster: cluster.GlusterApi != nil,
 		Nfs:     cluster.NfsApi != nil,
+		Dynamic: len(cluster.DynamicStorageClasses) > 0,
 	}
 }
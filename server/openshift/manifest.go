@@ -0,0 +1,439 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	changeCreate = "create"
+	changeUpdate = "update"
+	changeDelete = "delete"
+)
+
+// These service accounts are created by OpenShift in every project
+// and are never pruned
+var systemServiceAccounts = []string{"builder", "default", "deployer"}
+
+// projectState is the live state of a project, as far as it can be
+// described by a manifest
+type projectState struct {
+	Exists          bool
+	Billing         string
+	MegaId          string
+	Metadata        map[string]string
+	Admins          []string
+	HasQuota        bool
+	CPU             int
+	Memory          int
+	ServiceAccounts []string
+	HasPullSecret   bool
+	PullSecret      string
+	// PVC name => requested size and bound PV
+	Volumes map[string]volumeState
+}
+
+type volumeState struct {
+	Size   string
+	PvName string
+}
+
+// manifestStep is a planned change and the function that applies it
+type manifestStep struct {
+	change common.ManifestChange
+	apply  func() error
+}
+
+func applyProjectManifestHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	planOnly := c.Query("plan") == "true"
+	prune := c.Query("prune") == "true"
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	// YAML is a superset of JSON, so both formats are accepted
+	var manifest common.ProjectManifest
+	if err := yaml.UnmarshalStrict(body, &manifest); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: fmt.Sprintf("Invalid manifest: %v", err)})
+		return
+	}
+	manifest.Project = strings.ToLower(manifest.Project)
+
+	if err := validateProjectManifest(manifest, prune); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	state, err := getProjectState(manifest.ClusterId, manifest.Project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if state.Exists {
+		if err := checkAdminPermissions(manifest.ClusterId, username, manifest.Project); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		metadata := withLegacyMetadata(manifest.Metadata, manifest.Billing, manifest.MegaId)
+		if err := validateMetadataUpdate(manifest.ClusterId, manifest.Project, username, metadata); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	} else {
+		// The user creating the project becomes admin
+		state.Admins = []string{strings.ToLower(username)}
+		// New projects get their quota from the project template
+		state.HasQuota = true
+	}
+
+	steps, err := planProjectManifest(manifest, *state, username, prune)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	respondWithManifestSteps(c, steps, planOnly, manifest.ClusterId, manifest.Project,
+		fmt.Sprintf("%v is applying a manifest with %v changes to project %v on cluster %v", username, len(steps), manifest.Project, manifest.ClusterId),
+		fmt.Sprintf("The manifest has been applied to project %v on cluster %v", manifest.Project, manifest.ClusterId))
+}
+
+// respondWithManifestSteps returns the planned changes (planOnly=true)
+// or applies them and returns the result of every change
+func respondWithManifestSteps(c *gin.Context, steps []manifestStep, planOnly bool, clusterId, project, logMessage, appliedMessage string) {
+	changes := []common.ManifestChange{}
+	for _, s := range steps {
+		changes = append(changes, s.change)
+	}
+	if planOnly || len(steps) == 0 {
+		c.JSON(http.StatusOK, common.ManifestApplyResponse{
+			Message: fmt.Sprintf("%v changes needed for project %v on cluster %v", len(steps), project, clusterId),
+			Changes: changes,
+		})
+		return
+	}
+
+	log.Println(logMessage)
+	changes, err := applyProjectManifest(steps)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ManifestApplyResponse{
+			Message: err.Error(),
+			Changes: changes,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, common.ManifestApplyResponse{
+		Message: appliedMessage,
+		Applied: true,
+		Changes: changes,
+	})
+}
+
+func validateProjectManifest(manifest common.ProjectManifest, prune bool) error {
+	if manifest.ClusterId == "" {
+		return errors.New("Cluster must be provided")
+	}
+	if err := validateNewProject(manifest.Project, withLegacyMetadata(manifest.Metadata, manifest.Billing, manifest.MegaId), false); err != nil {
+		return err
+	}
+	if prune && len(manifest.Admins) == 0 {
+		return errors.New("At least one admin must be provided when pruning")
+	}
+	if manifest.Quotas != nil {
+		if err := validateQuotaLimits(manifest.Quotas.CPU, manifest.Quotas.Memory); err != nil {
+			return err
+		}
+	}
+	for _, sa := range manifest.ServiceAccounts {
+		if sa.Name == "" {
+			return errors.New("Service account name must be provided")
+		}
+	}
+	if manifest.PullSecret != nil && (manifest.PullSecret.Username == "" || manifest.PullSecret.Password == "") {
+		return errors.New("Username and password of the pull secret must be provided")
+	}
+	for _, v := range manifest.Volumes {
+		if v.PvcName == "" || v.Size == "" || v.Mode == "" {
+			return errors.New("Name, size and mode of volumes must be provided")
+		}
+		if err := checkTechnology(v.Technology); err != nil {
+			return err
+		}
+		if err := validateSizeFormat(v.Size, v.Technology); err != nil {
+			return err
+		}
+		if err := validateSize(v.Size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getProjectState(clusterId, project string) (*projectState, error) {
+	state := projectState{
+		Volumes: map[string]volumeState{},
+	}
+	exists, err := projectExists(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return &state, nil
+	}
+	state.Exists = true
+
+	info, err := getProjectInformation(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	state.Billing = info.Kontierungsnummer
+	state.MegaId = info.MegaID
+	state.Metadata = info.Metadata
+
+	state.Admins, _, err = getProjectAdminsAndOperators(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+
+	quotas, err := getQuotas(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	if quotas.Data() != nil {
+		state.HasQuota = true
+		state.CPU, state.Memory = getQuotaValues(quotas)
+	}
+
+	state.ServiceAccounts, err = getServiceAccounts(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+
+	state.PullSecret, state.HasPullSecret, err = getPullSecretCredentials(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+
+	pvcs, err := getPvcs(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs.Children() {
+		name, _ := pvc.Path("metadata.name").Data().(string)
+		size, _ := pvc.Path("spec.resources.requests.storage").Data().(string)
+		pvName, _ := pvc.Path("spec.volumeName").Data().(string)
+		if pvName != "" {
+			pv, err := getOpenshiftPV(clusterId, pvName)
+			if err != nil {
+				return nil, err
+			}
+			capacity, _ := pv.Path("spec.capacity.storage").Data().(string)
+			size = getLargerVolumeSize(size, capacity)
+		}
+		state.Volumes[name] = volumeState{Size: size, PvName: pvName}
+	}
+	return &state, nil
+}
+
+// getLargerVolumeSize returns the larger of the pvc request and the pv capacity.
+// Gluster and nfs volumes only grow the pv, dynamic volumes the pvc first.
+func getLargerVolumeSize(request, capacity string) string {
+	r, errR := parseVolumeSize(request)
+	c, errC := parseVolumeSize(capacity)
+	if errC != nil || (errR == nil && r >= c) {
+		return request
+	}
+	return capacity
+}
+
+// planProjectManifest compares the manifest with the live state and returns
+// the changes needed, in the order they have to be applied
+func planProjectManifest(m common.ProjectManifest, state projectState, username string, prune bool) ([]manifestStep, error) {
+	steps := []manifestStep{}
+	add := func(action, kind, name, details string, apply func() error) {
+		steps = append(steps, manifestStep{
+			change: common.ManifestChange{Action: action, Kind: kind, Name: name, Details: details},
+			apply:  apply,
+		})
+	}
+
+	metadata := withLegacyMetadata(m.Metadata, m.Billing, m.MegaId)
+	if !state.Exists {
+		add(changeCreate, "project", m.Project, formatMetadata(metadata), func() error {
+			return createNewProject(m.ClusterId, m.Project, username, metadata, m.Blueprint, false)
+		})
+	} else if changed := getChangedMetadata(withLegacyMetadata(state.Metadata, state.Billing, state.MegaId), metadata); len(changed) > 0 {
+		add(changeUpdate, "metadata", m.Project, strings.Join(changed, ", "), func() error {
+			return updateProjectMetadata(m.ClusterId, m.Project, metadata, username)
+		})
+	}
+
+	for _, admin := range m.Admins {
+		admin := strings.ToLower(admin)
+		if !common.ContainsStringI(state.Admins, admin) {
+			add(changeCreate, "admin", admin, "", func() error {
+				return changeProjectPermission(m.ClusterId, m.Project, admin)
+			})
+		}
+	}
+	if prune {
+		for _, admin := range state.Admins {
+			admin := admin
+			if !common.ContainsStringI(m.Admins, admin) {
+				add(changeDelete, "admin", admin, "", func() error {
+					return removeProjectPermission(m.ClusterId, m.Project, admin)
+				})
+			}
+		}
+	}
+
+	if m.Quotas != nil && (m.Quotas.CPU != state.CPU || m.Quotas.Memory != state.Memory) {
+		if !state.HasQuota {
+			return nil, errors.New("The project has no ResourceQuota which could be updated")
+		}
+		add(changeUpdate, "quotas", m.Project,
+			fmt.Sprintf("CPU: %v => %v, Memory: %vGi => %vGi", state.CPU, m.Quotas.CPU, state.Memory, m.Quotas.Memory),
+			func() error {
+				return updateQuotas(m.ClusterId, username, m.Project, m.Quotas.CPU, m.Quotas.Memory)
+			})
+	}
+
+	wanted := []string{}
+	for _, sa := range m.ServiceAccounts {
+		sa := sa
+		wanted = append(wanted, sa.Name)
+		if contains(state.ServiceAccounts, sa.Name) {
+			continue
+		}
+		details := ""
+		if sa.OrganizationKey != "" {
+			details = "Jenkins organization: " + sa.OrganizationKey
+		}
+		add(changeCreate, "serviceaccount", sa.Name, details, func() error {
+			if err := createNewServiceAccount(m.ClusterId, username, m.Project, sa.Name); err != nil {
+				return err
+			}
+			if err := authorizeServiceAccount(m.ClusterId, m.Project, sa.Name); err != nil {
+				return err
+			}
+			if sa.OrganizationKey != "" {
+				return createJenkinsCredential(m.ClusterId, m.Project, sa.Name, sa.OrganizationKey)
+			}
+			return nil
+		})
+	}
+	if prune {
+		for _, sa := range state.ServiceAccounts {
+			sa := sa
+			if contains(wanted, sa) || contains(systemServiceAccounts, sa) {
+				continue
+			}
+			add(changeDelete, "serviceaccount", sa, "", func() error {
+				return deleteServiceAccount(m.ClusterId, username, m.Project, sa)
+			})
+		}
+	}
+
+	if m.PullSecret != nil {
+		credentials := fmt.Sprintf("%v:%v", m.PullSecret.Username, m.PullSecret.Password)
+		if !state.HasPullSecret {
+			add(changeCreate, "pullsecret", defaultPullSecretName, "Username: "+m.PullSecret.Username, func() error {
+				return createPullSecret(m.ClusterId, m.Project, m.PullSecret.Username, m.PullSecret.Password)
+			})
+		} else if credentials != state.PullSecret {
+			add(changeUpdate, "pullsecret", defaultPullSecretName, "Username: "+m.PullSecret.Username, func() error {
+				return updatePullSecret(m.ClusterId, m.Project, m.PullSecret.Username, m.PullSecret.Password)
+			})
+		}
+	} else if prune && state.HasPullSecret {
+		add(changeDelete, "pullsecret", defaultPullSecretName, "", func() error {
+			if err := removePullSecretFromServiceaccount(m.ClusterId, m.Project, "default", defaultPullSecretName); err != nil {
+				return err
+			}
+			return deleteSecret(m.ClusterId, m.Project, defaultPullSecretName)
+		})
+	}
+
+	// Volumes are never pruned, because this would delete data
+	for _, v := range m.Volumes {
+		v := v
+		live, ok := state.Volumes[v.PvcName]
+		if !ok {
+			add(changeCreate, "volume", v.PvcName, fmt.Sprintf("Size: %v, Mode: %v, Technology: %v", v.Size, v.Mode, v.Technology), func() error {
+				storageclass, err := getVolumeStorageClass(m.ClusterId, v.Technology, v.StorageClass)
+				if err != nil {
+					return err
+				}
+				_, err = createNewVolume(m.ClusterId, m.Project, v.Size, v.PvcName, v.Mode, v.Technology, username, storageclass)
+				return err
+			})
+			continue
+		}
+		wantedSize, err := parseVolumeSize(v.Size)
+		if err != nil {
+			return nil, err
+		}
+		liveSize, err := parseVolumeSize(live.Size)
+		if err != nil {
+			return nil, err
+		}
+		if wantedSize < liveSize {
+			return nil, fmt.Errorf("The volume %v can't be shrunk from %v to %v", v.PvcName, live.Size, v.Size)
+		}
+		if wantedSize > liveSize {
+			add(changeUpdate, "volume", v.PvcName, fmt.Sprintf("Size: %v => %v", live.Size, v.Size), func() error {
+				pv, err := getOpenshiftPV(m.ClusterId, live.PvName)
+				if err != nil {
+					return err
+				}
+				return growExistingVolume(m.ClusterId, pv, v.Size, username)
+			})
+		}
+	}
+
+	return steps, nil
+}
+
+// applyProjectManifest applies the steps in order and stops at the first
+// error. The remaining changes are marked as skipped.
+func applyProjectManifest(steps []manifestStep) ([]common.ManifestChange, error) {
+	changes := []common.ManifestChange{}
+	var failed error
+	for _, s := range steps {
+		change := s.change
+		if failed != nil {
+			change.Result = "skipped"
+		} else if err := s.apply(); err != nil {
+			failed = fmt.Errorf("Applying the manifest failed at %v %v %v: %v", change.Action, change.Kind, change.Name, err)
+			change.Result = err.Error()
+		} else {
+			change.Result = "ok"
+		}
+		changes = append(changes, change)
+	}
+	return changes, failed
+}
+
+// getChangedMetadata returns the descriptions of the changed values.
+// Empty values in the manifest leave the current value unchanged.
+func getChangedMetadata(current, desired map[string]string) []string {
+	changed := []string{}
+	for name, value := range desired {
+		if value != "" && value != current[name] {
+			changed = append(changed, fmt.Sprintf("%v: %v => %v", name, current[name], value))
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,148 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+func getChanges(steps []manifestStep) []common.ManifestChange {
+	changes := []common.ManifestChange{}
+	for _, s := range steps {
+		changes = append(changes, s.change)
+	}
+	return changes
+}
+
+func TestPlanProjectManifestNewProject(t *testing.T) {
+	manifest := common.ProjectManifest{
+		ClusterId:       "awsdev",
+		Project:         "my-project",
+		Billing:         "1234",
+		Admins:          []string{"u123456", "U654321"},
+		Quotas:          &common.ManifestQuotas{CPU: 4, Memory: 8},
+		ServiceAccounts: []common.ManifestServiceAccount{{Name: "deployer-jenkins"}},
+		Volumes:         []common.ManifestVolume{{PvcName: "data", Size: "1G", Mode: "ReadWriteOnce", Technology: "gluster"}},
+	}
+	state := projectState{
+		Admins:   []string{"u123456"},
+		HasQuota: true,
+		Volumes:  map[string]volumeState{},
+	}
+	steps, err := planProjectManifest(manifest, state, "u123456", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []common.ManifestChange{
+		{Action: changeCreate, Kind: "project", Name: "my-project"},
+		{Action: changeCreate, Kind: "admin", Name: "u654321"},
+		{Action: changeUpdate, Kind: "quotas", Name: "my-project"},
+		{Action: changeCreate, Kind: "serviceaccount", Name: "deployer-jenkins"},
+		{Action: changeCreate, Kind: "volume", Name: "data"},
+	}
+	changes := getChanges(steps)
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %v changes, got %v: %+v", len(expected), len(changes), changes)
+	}
+	for i, e := range expected {
+		if changes[i].Action != e.Action || changes[i].Kind != e.Kind || changes[i].Name != e.Name {
+			t.Errorf("Expected change %v to be %+v, got %+v", i, e, changes[i])
+		}
+	}
+}
+
+func TestPlanProjectManifestExistingProject(t *testing.T) {
+	manifest := common.ProjectManifest{
+		ClusterId:       "awsdev",
+		Project:         "my-project",
+		Billing:         "1234",
+		MegaId:          "5678",
+		Admins:          []string{"u123456"},
+		Quotas:          &common.ManifestQuotas{CPU: 4, Memory: 8},
+		ServiceAccounts: []common.ManifestServiceAccount{{Name: "deployer-jenkins"}},
+		Volumes:         []common.ManifestVolume{{PvcName: "data", Size: "2G", Mode: "ReadWriteOnce", Technology: "gluster"}},
+	}
+	state := projectState{
+		Exists:          true,
+		Billing:         "1234",
+		MegaId:          "5678",
+		Admins:          []string{"u123456", "u654321"},
+		HasQuota:        true,
+		CPU:             4,
+		Memory:          8,
+		ServiceAccounts: []string{"builder", "default", "deployer", "deployer-jenkins", "old"},
+		HasPullSecret:   true,
+		Volumes:         map[string]volumeState{"data": {Size: "1G", PvName: "gl-my-project-pv1"}},
+	}
+
+	t.Run("without prune", func(t *testing.T) {
+		steps, err := planProjectManifest(manifest, state, "u123456", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes := getChanges(steps)
+		if len(changes) != 1 || changes[0].Kind != "volume" || changes[0].Action != changeUpdate {
+			t.Errorf("Expected only the volume to be grown, got %+v", changes)
+		}
+	})
+
+	t.Run("with prune", func(t *testing.T) {
+		steps, err := planProjectManifest(manifest, state, "u123456", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deleted := map[string]string{}
+		for _, c := range getChanges(steps) {
+			if c.Action == changeDelete {
+				deleted[c.Kind] = c.Name
+			}
+		}
+		expected := map[string]string{"admin": "u654321", "serviceaccount": "old", "pullsecret": "external-registry"}
+		if len(deleted) != len(expected) {
+			t.Errorf("Expected deletions %v, got %v", expected, deleted)
+		}
+		for kind, name := range expected {
+			if deleted[kind] != name {
+				t.Errorf("Expected %v %v to be deleted, got %v", kind, name, deleted[kind])
+			}
+		}
+	})
+
+	t.Run("volume in binary units", func(t *testing.T) {
+		st := state
+		st.Volumes = map[string]volumeState{"data": {Size: "2Gi", PvName: "gl-my-project-pv1"}}
+		steps, err := planProjectManifest(manifest, st, "u123456", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changes := getChanges(steps); len(changes) != 0 {
+			t.Errorf("Expected 2G and 2Gi to be the same size, got %+v", changes)
+		}
+	})
+
+	t.Run("shrinking a volume", func(t *testing.T) {
+		m := manifest
+		m.Volumes = []common.ManifestVolume{{PvcName: "data", Size: "500M", Mode: "ReadWriteOnce", Technology: "gluster"}}
+		if _, err := planProjectManifest(m, state, "u123456", false); err == nil {
+			t.Error("Expected an error when shrinking a volume")
+		}
+	})
+}
+
+func TestGetLargerVolumeSize(t *testing.T) {
+	var tests = []struct {
+		request  string
+		capacity string
+		expected string
+	}{
+		{"1G", "2G", "2G"},
+		{"2Gi", "1G", "2Gi"},
+		{"1G", "1Gi", "1G"},
+		{"1G", "", "1G"},
+	}
+	for _, test := range tests {
+		if size := getLargerVolumeSize(test.request, test.capacity); size != test.expected {
+			t.Errorf("getLargerVolumeSize(%v, %v): expected %v, got %v", test.request, test.capacity, test.expected, size)
+		}
+	}
+}
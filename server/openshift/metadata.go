@@ -0,0 +1,250 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Metadata names of the accounting number and the MEGAID.
+	// They are also available as separate fields in the API.
+	metadataBilling = "billing"
+	metadataMegaId  = "megaid"
+
+	metadataEditableByAdmin    = "admin"
+	metadataEditableByOperator = "operator"
+	metadataEditableByNone     = "none"
+)
+
+// MetadataField describes a project metadata field, which is stored as
+// annotation on the namespace
+type MetadataField struct {
+	// Name of the field in the API
+	Name       string `json:"name"`
+	Annotation string `json:"annotation"`
+	Label      string `json:"label"`
+	// Query parameter to filter projects in /ose/projects
+	Filter   string `json:"filter"`
+	Required bool   `json:"required"`
+	Regex    string `json:"regex"`
+	// Who can change the value of an existing project: admin, operator or none
+	EditableBy string `json:"editableBy"`
+	// Value of test projects. Fields without it are left empty.
+	TestProjectDefault string `json:"testProjectDefault"`
+}
+
+var defaultMetadataSchema = []MetadataField{
+	{
+		Name:               metadataBilling,
+		Annotation:         "openshift.io/kontierung-element",
+		Label:              "Accounting number",
+		Filter:             "sbb_accounting_number",
+		Required:           true,
+		EditableBy:         metadataEditableByAdmin,
+		TestProjectDefault: "keine-verrechnung",
+	},
+	{
+		Name:       metadataMegaId,
+		Annotation: "openshift.io/MEGAID",
+		Label:      "MEGA ID",
+		Filter:     "sbb_mega_id",
+		EditableBy: metadataEditableByAdmin,
+	},
+}
+
+func getProjectMetadataSchemaHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, getProjectMetadataSchema())
+}
+
+// getProjectMetadataSchema returns the configured metadata fields
+// or the accounting number and MEGAID if nothing is configured
+func getProjectMetadataSchema() []MetadataField {
+	schema := []MetadataField{}
+	if err := config.Config().UnmarshalKey("openshift_project_metadata", &schema); err != nil {
+		log.Printf("WARNING: Invalid openshift_project_metadata configuration: %v", err)
+	}
+	if len(schema) == 0 {
+		return defaultMetadataSchema
+	}
+	for i := range schema {
+		if schema[i].EditableBy == "" {
+			schema[i].EditableBy = metadataEditableByAdmin
+		}
+	}
+	return schema
+}
+
+// withLegacyMetadata returns a copy of the metadata including the
+// accounting number and MEGAID from the separate API fields.
+// They are only added if the schema contains them.
+func withLegacyMetadata(metadata map[string]string, billing, megaid string) map[string]string {
+	schema := getProjectMetadataSchema()
+	result := map[string]string{}
+	for k, v := range metadata {
+		result[k] = v
+	}
+	if billing != "" && result[metadataBilling] == "" && hasMetadataField(schema, metadataBilling) {
+		result[metadataBilling] = billing
+	}
+	if megaid != "" && result[metadataMegaId] == "" && hasMetadataField(schema, metadataMegaId) {
+		result[metadataMegaId] = megaid
+	}
+	return result
+}
+
+func hasMetadataField(schema []MetadataField, name string) bool {
+	for _, f := range schema {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMetadata checks the values against the schema.
+// Required fields are not enforced for test projects.
+func validateMetadata(schema []MetadataField, metadata map[string]string, testProject bool) error {
+	fields := map[string]MetadataField{}
+	for _, f := range schema {
+		fields[f.Name] = f
+	}
+	for name := range metadata {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("Unknown project metadata: %v", name)
+		}
+	}
+	for _, f := range schema {
+		value := metadata[f.Name]
+		if value == "" {
+			if f.Required && !testProject {
+				return fmt.Errorf("%v must be provided", getMetadataLabel(f))
+			}
+			continue
+		}
+		if f.Regex == "" {
+			continue
+		}
+		re, err := regexp.Compile(f.Regex)
+		if err != nil {
+			log.Printf("WARNING: Invalid regex for project metadata %v: %v", f.Name, err)
+			return errors.New(genericAPIError)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("%v has an invalid format: %v", getMetadataLabel(f), value)
+		}
+	}
+	return nil
+}
+
+// getTestProjectMetadata returns the default values of test projects
+func getTestProjectMetadata(schema []MetadataField) map[string]string {
+	metadata := map[string]string{}
+	for _, f := range schema {
+		if f.TestProjectDefault != "" {
+			metadata[f.Name] = f.TestProjectDefault
+		}
+	}
+	return metadata
+}
+
+// validateMetadataUpdate checks if the user may change the metadata
+// of the existing project to the new values
+func validateMetadataUpdate(clusterId, project, username string, metadata map[string]string) error {
+	current, err := getProjectInformation(clusterId, project)
+	if err != nil {
+		return err
+	}
+	operator, err := isOperator(clusterId, username)
+	if err != nil {
+		return err
+	}
+	return validateMetadataChanges(getProjectMetadataSchema(), current.Metadata, metadata, operator)
+}
+
+// validateMetadataChanges checks if the user may change the fields
+func validateMetadataChanges(schema []MetadataField, current, metadata map[string]string, isOperator bool) error {
+	for _, f := range schema {
+		value := metadata[f.Name]
+		if value == "" || value == current[f.Name] {
+			continue
+		}
+		switch f.EditableBy {
+		case metadataEditableByAdmin:
+		case metadataEditableByOperator:
+			if !isOperator {
+				return fmt.Errorf("%v can only be changed by operators", getMetadataLabel(f))
+			}
+		default:
+			return fmt.Errorf("%v can't be changed", getMetadataLabel(f))
+		}
+	}
+	return nil
+}
+
+func getMetadataLabel(f MetadataField) string {
+	if f.Label != "" {
+		return f.Label
+	}
+	return f.Name
+}
+
+// getMetadataFromAnnotations reads the metadata fields of a namespace
+func getMetadataFromAnnotations(schema []MetadataField, namespace *gabs.Container) map[string]string {
+	metadata := map[string]string{}
+	for _, f := range schema {
+		metadata[f.Name] = getAnnotation(namespace, f.Annotation)
+	}
+	return metadata
+}
+
+// setMetadataAnnotations sets the non empty metadata fields as annotations
+func setMetadataAnnotations(schema []MetadataField, namespace *gabs.Container, metadata map[string]string) {
+	for _, f := range schema {
+		if value := metadata[f.Name]; value != "" {
+			namespace.Set(value, "metadata", "annotations", f.Annotation)
+		}
+	}
+}
+
+// getMetadataFilters returns the filter parameter names with their annotation
+func getMetadataFilters(schema []MetadataField) map[string]string {
+	filters := map[string]string{}
+	for _, f := range schema {
+		if f.Filter != "" {
+			filters[f.Filter] = f.Annotation
+		}
+	}
+	return filters
+}
+
+func isOperator(clusterId, username string) (bool, error) {
+	group, err := getOperatorGroup(clusterId)
+	if err != nil {
+		return false, err
+	}
+	for _, u := range group.Path("users").Children() {
+		if strings.ToLower(u.Data().(string)) == strings.ToLower(username) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// formatMetadata returns the metadata as sorted list for logs and mails
+func formatMetadata(metadata map[string]string) string {
+	var values []string
+	for k, v := range metadata {
+		values = append(values, fmt.Sprintf("%v: %v", k, v))
+	}
+	sort.Strings(values)
+	return strings.Join(values, ", ")
+}
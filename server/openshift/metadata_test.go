@@ -0,0 +1,158 @@
+package openshift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+var testMetadataSchema = []MetadataField{
+	{Name: "billing", Annotation: "openshift.io/kontierung-element", Required: true, EditableBy: metadataEditableByAdmin},
+	{Name: "costcenter", Annotation: "openshift.io/costcenter", Filter: "costcenter", Regex: "^[0-9]{4}$", EditableBy: metadataEditableByOperator},
+	{Name: "classification", Annotation: "openshift.io/classification", EditableBy: metadataEditableByNone},
+}
+
+func TestValidateMetadata(t *testing.T) {
+	var tests = []struct {
+		metadata    map[string]string
+		testProject bool
+		valid       bool
+	}{
+		{map[string]string{"billing": "1234"}, false, true},
+		{map[string]string{"billing": "1234", "costcenter": "5678"}, false, true},
+		{map[string]string{"billing": "1234", "costcenter": "56789"}, false, false},
+		{map[string]string{"costcenter": "5678"}, false, false},
+		{map[string]string{"costcenter": "5678"}, true, true},
+		{map[string]string{"billing": "1234", "unknown": "x"}, false, false},
+	}
+	for _, test := range tests {
+		err := validateMetadata(testMetadataSchema, test.metadata, test.testProject)
+		if test.valid && err != nil {
+			t.Errorf("Expected %v to be valid, got %v", test.metadata, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected %v to be invalid", test.metadata)
+		}
+	}
+}
+
+func TestValidateMetadataChanges(t *testing.T) {
+	current := map[string]string{"billing": "1234", "costcenter": "1111", "classification": "internal"}
+	var tests = []struct {
+		metadata   map[string]string
+		isOperator bool
+		valid      bool
+	}{
+		{map[string]string{"billing": "5678"}, false, true},
+		{map[string]string{"costcenter": "2222"}, false, false},
+		{map[string]string{"costcenter": "2222"}, true, true},
+		{map[string]string{"costcenter": "1111", "classification": "internal"}, false, true},
+		{map[string]string{"classification": "public"}, true, false},
+	}
+	for _, test := range tests {
+		err := validateMetadataChanges(testMetadataSchema, current, test.metadata, test.isOperator)
+		if test.valid && err != nil {
+			t.Errorf("Expected %v to be valid, got %v", test.metadata, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected %v to be invalid", test.metadata)
+		}
+	}
+}
+
+func TestValidateMetadataUpdate(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("openshift_project_metadata", []map[string]interface{}{
+		{"name": "billing", "annotation": "openshift.io/kontierung-element", "required": true},
+		{"name": "costcenter", "annotation": "openshift.io/costcenter", "editableBy": "operator"},
+	})
+	defer config.Init("bla")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/namespaces/app":
+			w.Write([]byte(`{"metadata": {"name": "app", "annotations": {"openshift.io/kontierung-element": "1234", "openshift.io/costcenter": "1111"}}}`))
+		case "/apis/user.openshift.io/v1/groups/operator":
+			w.Write([]byte(`{"users": ["operator1"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "url": server.URL, "token": "token"}})
+	defer config.Config().Set("openshift", nil)
+
+	var tests = []struct {
+		username string
+		metadata map[string]string
+		valid    bool
+	}{
+		{"u123456", map[string]string{"billing": "5678", "costcenter": "1111"}, true},
+		{"u123456", map[string]string{"billing": "1234", "costcenter": "2222"}, false},
+		{"operator1", map[string]string{"billing": "1234", "costcenter": "2222"}, true},
+	}
+	for _, test := range tests {
+		err := validateMetadataUpdate("c1", "app", test.username, test.metadata)
+		if test.valid && err != nil {
+			t.Errorf("Expected %v of %v to be valid, got %v", test.metadata, test.username, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected %v of %v to be invalid", test.metadata, test.username)
+		}
+	}
+}
+
+func TestWithLegacyMetadataConfiguredSchema(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("openshift_project_metadata", []map[string]interface{}{
+		{"name": "billing", "annotation": "openshift.io/kontierung-element"},
+		{"name": "costcenter", "annotation": "openshift.io/costcenter"},
+	})
+	defer config.Init("bla")
+
+	metadata := withLegacyMetadata(map[string]string{"costcenter": "1111"}, "1234", "M1")
+	if !reflect.DeepEqual(metadata, map[string]string{"costcenter": "1111", "billing": "1234"}) {
+		t.Errorf("Expected the MEGAID to be left out, got %v", metadata)
+	}
+	if err := validateMetadata(getProjectMetadataSchema(), metadata, false); err != nil {
+		t.Errorf("Expected the metadata to be valid, got %v", err)
+	}
+	if err := ValidateBilling("1234", "M1"); err != nil {
+		t.Errorf("Expected the billing to be valid, got %v", err)
+	}
+}
+
+func TestGetTestProjectMetadata(t *testing.T) {
+	if metadata := getTestProjectMetadata(defaultMetadataSchema); !reflect.DeepEqual(metadata, map[string]string{"billing": "keine-verrechnung"}) {
+		t.Errorf("Expected the default accounting number, got %v", metadata)
+	}
+	if metadata := getTestProjectMetadata(testMetadataSchema); len(metadata) != 0 {
+		t.Errorf("Expected no metadata without defaults, got %v", metadata)
+	}
+}
+
+func TestProjectFilterConfiguredSchema(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("openshift_project_metadata", []map[string]interface{}{
+		{"name": "costcenter", "annotation": "openshift.io/costcenter", "filter": "costcenter"},
+	})
+	defer config.Init("bla")
+
+	projects, _ := gabs.ParseJSON([]byte(`[
+		{"metadata": {"annotations": {"openshift.io/costcenter": "1234", "openshift.io/MEGAID": "1"}}},
+		{"metadata": {"annotations": {"openshift.io/costcenter": "5678", "openshift.io/MEGAID": "1"}}}
+	]`))
+	params := url.Values{}
+	params.Set("costcenter", "1234")
+	// not configured anymore, so it's ignored
+	params.Set("sbb_mega_id", "2")
+	if n := len(filterProjects(projects, params).Children()); n != 1 {
+		t.Errorf("Expected 1 project, got %v", n)
+	}
+}
@@ -0,0 +1,444 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const secretTypeDockerConfigJson = "kubernetes.io/dockerconfigjson"
+
+// These configmaps are created by OpenShift in every project
+var systemConfigMaps = []string{"kube-root-ca.crt", "openshift-service-ca.crt"}
+
+// migrateProjectHandler copies a project to another cluster.
+// With plan=true, only the changes are returned.
+func migrateProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	planOnly := c.Query("plan") == "true"
+
+	var data common.MigrateProjectCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if data.TargetProject == "" {
+		data.TargetProject = data.Project
+	}
+	data.TargetProject = strings.ToLower(data.TargetProject)
+
+	if err := validateMigration(data, username); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	state, err := getProjectState(data.TargetClusterId, data.TargetProject)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if state.Exists {
+		if err := checkAdminPermissions(data.TargetClusterId, username, data.TargetProject); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	} else {
+		// The user creating the project becomes admin
+		state.Admins = []string{strings.ToLower(username)}
+		// New projects get their quota from the project template
+		state.HasQuota = true
+	}
+
+	steps, err := planProjectMigration(data, *state, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	respondWithManifestSteps(c, steps, planOnly, data.TargetClusterId, data.TargetProject,
+		fmt.Sprintf("%v is migrating project %v from cluster %v to project %v on cluster %v with %v changes", username, data.Project, data.SourceClusterId, data.TargetProject, data.TargetClusterId, len(steps)),
+		fmt.Sprintf("The project %v has been migrated to project %v on cluster %v", data.Project, data.TargetProject, data.TargetClusterId))
+}
+
+func validateMigration(data common.MigrateProjectCommand, username string) error {
+	if data.Project == "" {
+		return errors.New("Project must be provided")
+	}
+	if data.SourceClusterId == "" || data.TargetClusterId == "" {
+		return errors.New("Source and target cluster must be provided")
+	}
+	if data.SourceClusterId == data.TargetClusterId {
+		return errors.New("Source and target cluster must be different")
+	}
+	if err := validateAdminAccess(data.SourceClusterId, username, data.Project); err != nil {
+		return err
+	}
+	cluster, err := getOpenshiftCluster(data.TargetClusterId)
+	if err != nil {
+		return err
+	}
+	return validateProjectCreationPermission(cluster, username)
+}
+
+// planProjectMigration reads the source project and returns the changes
+// needed to copy it to the target project
+func planProjectMigration(data common.MigrateProjectCommand, target projectState, username string) ([]manifestStep, error) {
+	source, err := getProjectState(data.SourceClusterId, data.Project)
+	if err != nil {
+		return nil, err
+	}
+	if !source.Exists {
+		return nil, fmt.Errorf("The project %v doesn't exist on cluster %v", data.Project, data.SourceClusterId)
+	}
+
+	namespace, err := getNamespace(data.SourceClusterId, data.Project)
+	if err != nil {
+		return nil, err
+	}
+	pvcs, err := getPvcs(data.SourceClusterId, data.Project)
+	if err != nil {
+		return nil, err
+	}
+	technologies := map[string]string{}
+	for _, pvc := range pvcs.Children() {
+		pvName, _ := pvc.Path("spec.volumeName").Data().(string)
+		if pvName == "" {
+			continue
+		}
+		pv, err := getOpenshiftPV(data.SourceClusterId, pvName)
+		if err != nil {
+			return nil, err
+		}
+		technology, err := getVolumeTechnology(data.SourceClusterId, pv)
+		if err != nil {
+			return nil, err
+		}
+		technologies[pvName] = technology
+	}
+
+	manifest, err := newMigrationManifest(data, *source, getAnnotation(namespace, blueprintAnnotation), pvcs.Children(), technologies)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateProjectManifest(manifest, false); err != nil {
+		return nil, err
+	}
+
+	steps, err := planProjectManifest(manifest, target, username, false)
+	if err != nil {
+		return nil, err
+	}
+	add := func(action, kind, name, details string, apply func() error) {
+		steps = append(steps, manifestStep{
+			change: common.ManifestChange{Action: action, Kind: kind, Name: name, Details: details},
+			apply:  apply,
+		})
+	}
+	to := data.TargetClusterId
+	project := data.TargetProject
+
+	// Admin groups
+	sourceAdminRoleBinding, err := getAdminRoleBinding(data.SourceClusterId, data.Project)
+	if err != nil {
+		return nil, err
+	}
+	targetGroups := []string{}
+	if target.Exists {
+		targetAdminRoleBinding, err := getAdminRoleBinding(to, project)
+		if err != nil {
+			return nil, err
+		}
+		targetGroups = getAdminGroups(targetAdminRoleBinding)
+	}
+	for _, group := range getAdminGroups(sourceAdminRoleBinding) {
+		group := group
+		if common.ContainsStringI(targetGroups, group) {
+			continue
+		}
+		add(changeCreate, "admingroup", group, "", func() error {
+			return changeProjectGroupPermission(to, project, group, true)
+		})
+	}
+
+	// Quotas and limit range defaults
+	quotas, err := getResourceQuotas(data.SourceClusterId, data.Project)
+	if err != nil {
+		return nil, err
+	}
+	if hard, _ := aggregateQuotas(quotas); len(hard) > 0 {
+		if err := validateQuotaValues(hard, getQuotaMaxima()); err != nil {
+			return nil, fmt.Errorf("The quotas of the project %v can't be migrated: %v", data.Project, err)
+		}
+		add(changeUpdate, "quotas", project, formatMetadata(hard), func() error {
+			return updateProjectQuotas(to, username, project, hard)
+		})
+	}
+	limitRanges, err := getLimitRanges(data.SourceClusterId, data.Project)
+	if err != nil {
+		return nil, err
+	}
+	if defaults := getLimitRangeDefaults(limitRanges); defaults != nil {
+		add(changeUpdate, "limitrange", project,
+			fmt.Sprintf("CPU: %v, Memory: %v", defaults.DefaultCPU, defaults.DefaultMemory),
+			func() error {
+				return updateLimitRangeDefaults(to, username, project, *defaults)
+			})
+	}
+
+	// Service accounts get new tokens on the target cluster
+	rolebindings, err := getRoleBindings(data.SourceClusterId, data.Project)
+	if err != nil {
+		return nil, err
+	}
+	roles := getServiceAccountRoles(rolebindings, data.Project)
+	for _, sa := range source.ServiceAccounts {
+		sa := sa
+		if contains(systemServiceAccounts, sa) || contains(target.ServiceAccounts, sa) {
+			continue
+		}
+		allowed, skipped := []string{}, []string{}
+		for _, role := range roles[sa] {
+			if isAllowedServiceAccountRole(role) {
+				allowed = append(allowed, role)
+			} else {
+				skipped = append(skipped, role)
+			}
+		}
+		details := "Roles: " + strings.Join(allowed, ", ")
+		if len(skipped) > 0 {
+			details += ". Not copied: " + strings.Join(skipped, ", ")
+		}
+		add(changeCreate, "serviceaccount", sa, details, func() error {
+			if err := createNewServiceAccount(to, username, project, sa); err != nil {
+				return err
+			}
+			for _, role := range allowed {
+				if err := addProjectRoleSubject(to, project, role, subjectKindServiceAccount, sa); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	// Pull secrets, configmaps and secrets
+	secrets, err := getProjectObjects(data.SourceClusterId, "api/v1/namespaces/"+data.Project+"/secrets")
+	if err != nil {
+		return nil, err
+	}
+	links, err := getLinkedPullSecrets(data.SourceClusterId, data.Project)
+	if err != nil {
+		return nil, err
+	}
+	secretTypes := []string{secretTypeDockerConfigJson}
+	if data.CopySecrets {
+		secretTypes = append(secretTypes, secretTypeOpaque, secretTypeTLS)
+	}
+	objects := getCopiedSecrets(secrets, secretTypes, links)
+
+	if data.CopyConfigMaps {
+		configmaps, err := getProjectObjects(data.SourceClusterId, "api/v1/namespaces/"+data.Project+"/configmaps")
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, getCopiedConfigMaps(configmaps)...)
+	}
+
+	for _, o := range objects {
+		o := o
+		action := changeCreate
+		if target.Exists {
+			exists, err := objectExists(to, o.collectionURL(project)+"/"+o.name)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				action = changeUpdate
+			}
+		}
+		details := ""
+		if len(o.serviceAccounts) > 0 {
+			details = "Linked to: " + strings.Join(o.serviceAccounts, ", ")
+		}
+		add(action, o.kind, o.name, details, func() error {
+			if err := applyObject(to, o.collectionURL(project), o.name, o.object); err != nil {
+				return err
+			}
+			for _, sa := range o.serviceAccounts {
+				// The service accounts of a new project are created asynchronously
+				if err := waitForServiceAccount(to, project, sa); err != nil {
+					return err
+				}
+				linked, err := isPullSecretLinked(to, project, sa, o.name)
+				if err != nil {
+					return err
+				}
+				if linked {
+					continue
+				}
+				if err := linkPullSecret(to, project, sa, o.name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	return steps, nil
+}
+
+// newMigrationManifest describes the source project as manifest for the target cluster.
+// Volumes are created with the size, access mode and technology of the source PVC.
+// The size is converted to the format of new volumes.
+func newMigrationManifest(data common.MigrateProjectCommand, source projectState, blueprint string, pvcs []*gabs.Container, technologies map[string]string) (common.ProjectManifest, error) {
+	manifest := common.ProjectManifest{
+		ClusterId: data.TargetClusterId,
+		Project:   data.TargetProject,
+		Billing:   source.Billing,
+		MegaId:    source.MegaId,
+		Metadata:  source.Metadata,
+		Blueprint: blueprint,
+		Admins:    source.Admins,
+		Volumes:   []common.ManifestVolume{},
+	}
+	for _, pvc := range pvcs {
+		name, _ := pvc.Path("metadata.name").Data().(string)
+		size, _ := pvc.Path("spec.resources.requests.storage").Data().(string)
+		mode, _ := pvc.Path("spec.accessModes").Index(0).Data().(string)
+		pvName, _ := pvc.Path("spec.volumeName").Data().(string)
+		technology, ok := technologies[pvName]
+		if !ok {
+			return manifest, fmt.Errorf("The volume %v is not bound and can't be migrated", name)
+		}
+		size, err := normalizeVolumeSize(size, technology)
+		if err != nil {
+			return manifest, fmt.Errorf("The volume %v can't be migrated: %v", name, err)
+		}
+		manifest.Volumes = append(manifest.Volumes, common.ManifestVolume{
+			PvcName:    name,
+			Size:       size,
+			Mode:       mode,
+			Technology: technology,
+		})
+	}
+	sort.Slice(manifest.Volumes, func(i, j int) bool { return manifest.Volumes[i].PvcName < manifest.Volumes[j].PvcName })
+	return manifest, nil
+}
+
+// getVolumeTechnology returns gluster, nfs or dynamic. Dynamic volumes get
+// the default storage class of the target cluster, the source one might not exist there.
+// Other volumes can't be migrated.
+func getVolumeTechnology(clusterId string, pv *gabs.Container) (string, error) {
+	if pv.Exists("spec", "glusterfs") {
+		return "gluster", nil
+	}
+	if pv.Exists("spec", "nfs") {
+		return "nfs", nil
+	}
+	if isDynamicVolume(clusterId, pv) {
+		return technologyDynamic, nil
+	}
+	name, _ := pv.Path("metadata.name").Data().(string)
+	return "", fmt.Errorf("The volume %v has an unsupported type and can't be migrated", name)
+}
+
+// copiedObject is a configmap or secret which is copied to the target project
+type copiedObject struct {
+	kind            string
+	name            string
+	object          *gabs.Container
+	serviceAccounts []string
+}
+
+func (o copiedObject) collectionURL(project string) string {
+	return fmt.Sprintf("api/v1/namespaces/%v/%vs", project, o.kind)
+}
+
+// getCopiedSecrets returns the secrets of the given types.
+// Pull secrets are linked to the same service accounts as on the source project.
+func getCopiedSecrets(secrets []*gabs.Container, types []string, links map[string][]string) []copiedObject {
+	objects := []copiedObject{}
+	for _, s := range secrets {
+		t, _ := s.S("type").Data().(string)
+		if !contains(types, t) {
+			continue
+		}
+		name, _ := s.Path("metadata.name").Data().(string)
+		o := copiedObject{kind: "secret", name: name, object: newCopiedObject(s, "Secret")}
+		if t == secretTypeDockerConfigJson {
+			o.serviceAccounts = links[name]
+		}
+		objects = append(objects, o)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].name < objects[j].name })
+	return objects
+}
+
+func getCopiedConfigMaps(configmaps []*gabs.Container) []copiedObject {
+	objects := []copiedObject{}
+	for _, cm := range configmaps {
+		name, _ := cm.Path("metadata.name").Data().(string)
+		if contains(systemConfigMaps, name) {
+			continue
+		}
+		objects = append(objects, copiedObject{kind: "configmap", name: name, object: newCopiedObject(cm, "ConfigMap")})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].name < objects[j].name })
+	return objects
+}
+
+// newCopiedObject returns the object without the fields set by the source cluster
+func newCopiedObject(source *gabs.Container, kind string) *gabs.Container {
+	name, _ := source.Path("metadata.name").Data().(string)
+	object := newObjectRequest(kind, name, "v1")
+	for _, field := range []string{"labels", "annotations"} {
+		if v := source.S("metadata", field).Data(); v != nil {
+			object.Set(v, "metadata", field)
+		}
+	}
+	object.Delete("metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration")
+	for _, field := range []string{"type", "data", "binaryData"} {
+		if v := source.S(field).Data(); v != nil {
+			object.Set(v, field)
+		}
+	}
+	return object
+}
+
+func getProjectObjects(clusterId, url string) ([]*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error getting %v on cluster %v: %v %v", url, clusterId, resp.StatusCode, string(errMsg))
+		return nil, errors.New(genericAPIError)
+	}
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+	return json.S("items").Children(), nil
+}
+
+func objectExists(clusterId, url string) (bool, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, url, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
@@ -0,0 +1,193 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestNewMigrationManifest(t *testing.T) {
+	pvc := func(name, size, mode, pvName string) *gabs.Container {
+		c := gabs.New()
+		c.Set(name, "metadata", "name")
+		c.Set(size, "spec", "resources", "requests", "storage")
+		c.Set([]interface{}{mode}, "spec", "accessModes")
+		if pvName != "" {
+			c.Set(pvName, "spec", "volumeName")
+		}
+		return c
+	}
+	data := common.MigrateProjectCommand{Project: "app", SourceClusterId: "a", TargetClusterId: "b", TargetProject: "app-new"}
+	source := projectState{Exists: true, Billing: "123", Admins: []string{"u1"}}
+
+	pvcs := []*gabs.Container{
+		pvc("logs", "5Gi", "ReadWriteMany", "pv-logs"),
+		pvc("data", "1G", "ReadWriteOnce", "pv-data"),
+		pvc("cache", "1500M", "ReadWriteOnce", "pv-cache"),
+	}
+	technologies := map[string]string{"pv-logs": "gluster", "pv-data": "nfs", "pv-cache": "dynamic"}
+
+	manifest, err := newMigrationManifest(data, source, "default", pvcs, technologies)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if manifest.ClusterId != "b" || manifest.Project != "app-new" || manifest.Blueprint != "default" || manifest.Billing != "123" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+	expected := []common.ManifestVolume{
+		{PvcName: "cache", Size: "2G", Mode: "ReadWriteOnce", Technology: "dynamic"},
+		{PvcName: "data", Size: "1G", Mode: "ReadWriteOnce", Technology: "nfs"},
+		{PvcName: "logs", Size: "5G", Mode: "ReadWriteMany", Technology: "gluster"},
+	}
+	if len(manifest.Volumes) != len(expected) {
+		t.Fatalf("expected %v volumes, got %v", len(expected), len(manifest.Volumes))
+	}
+	for i := range expected {
+		if manifest.Volumes[i] != expected[i] {
+			t.Errorf("volume %v: expected %+v, got %+v", i, expected[i], manifest.Volumes[i])
+		}
+	}
+
+	invalid := append(pvcs, pvc("invalid", "lots", "ReadWriteOnce", "pv-invalid"))
+	technologies["pv-invalid"] = "gluster"
+	if _, err := newMigrationManifest(data, source, "", invalid, technologies); err == nil {
+		t.Error("expected an error for an invalid volume size")
+	}
+
+	pvcs = append(pvcs, pvc("pending", "1G", "ReadWriteOnce", ""))
+	if _, err := newMigrationManifest(data, source, "", pvcs, technologies); err == nil {
+		t.Error("expected an error for an unbound volume")
+	}
+}
+
+func TestGetVolumeTechnology(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "dynamicstorageclasses": []string{"standard"}}})
+	defer config.Config().Set("openshift", nil)
+
+	var tests = []struct {
+		pv         string
+		technology string
+		valid      bool
+	}{
+		{`{"spec": {"glusterfs": {"path": "vol1"}}}`, "gluster", true},
+		{`{"spec": {"nfs": {"path": "/vol1"}}}`, "nfs", true},
+		{`{"spec": {"storageClassName": "standard", "csi": {}}}`, "dynamic", true},
+		{`{"metadata": {"name": "pv1"}, "spec": {"storageClassName": "local", "local": {}}}`, "", false},
+	}
+	for _, test := range tests {
+		pv, _ := gabs.ParseJSON([]byte(test.pv))
+		technology, err := getVolumeTechnology("c1", pv)
+		if test.valid && (err != nil || technology != test.technology) {
+			t.Errorf("%v: expected %v, got %v, %v", test.pv, test.technology, technology, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%v: expected an error, got %v", test.pv, technology)
+		}
+	}
+}
+
+func TestNewCopiedObject(t *testing.T) {
+	source, _ := gabs.ParseJSON([]byte(`{
+		"metadata": {
+			"name": "app-config",
+			"namespace": "app",
+			"uid": "1234",
+			"resourceVersion": "99",
+			"creationTimestamp": "2020-01-01T00:00:00Z",
+			"labels": {"app": "web"},
+			"annotations": {
+				"owner": "team",
+				"kubectl.kubernetes.io/last-applied-configuration": "{}"
+			}
+		},
+		"type": "Opaque",
+		"data": {"key": "dmFsdWU="}
+	}`))
+
+	object := newCopiedObject(source, "Secret")
+
+	if object.S("kind").Data() != "Secret" || object.S("apiVersion").Data() != "v1" {
+		t.Errorf("unexpected kind or apiVersion: %v", object.String())
+	}
+	for _, field := range []string{"namespace", "uid", "resourceVersion", "creationTimestamp"} {
+		if object.Exists("metadata", field) {
+			t.Errorf("expected metadata.%v to be removed", field)
+		}
+	}
+	if object.S("metadata", "labels", "app").Data() != "web" || object.S("metadata", "annotations", "owner").Data() != "team" {
+		t.Errorf("expected labels and annotations to be copied: %v", object.String())
+	}
+	if object.Exists("metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration") {
+		t.Error("expected the last-applied-configuration annotation to be removed")
+	}
+	if object.S("type").Data() != "Opaque" || object.S("data", "key").Data() != "dmFsdWU=" {
+		t.Errorf("expected type and data to be copied: %v", object.String())
+	}
+}
+
+func TestGetCopiedSecrets(t *testing.T) {
+	secret := func(name, secretType string) *gabs.Container {
+		c := gabs.New()
+		c.Set(name, "metadata", "name")
+		c.Set(secretType, "type")
+		return c
+	}
+	secrets := []*gabs.Container{
+		secret("registry", secretTypeDockerConfigJson),
+		secret("builder-token-abc", "kubernetes.io/service-account-token"),
+		secret("builder-dockercfg-abc", "kubernetes.io/dockercfg"),
+		secret("db", secretTypeOpaque),
+		secret("cert", secretTypeTLS),
+	}
+	links := map[string][]string{"registry": {"default", "deployer"}}
+
+	var tests = []struct {
+		types    []string
+		expected []string
+	}{
+		{[]string{secretTypeDockerConfigJson}, []string{"registry"}},
+		{[]string{secretTypeDockerConfigJson, secretTypeOpaque, secretTypeTLS}, []string{"cert", "db", "registry"}},
+	}
+	for _, test := range tests {
+		objects := getCopiedSecrets(secrets, test.types, links)
+		names := []string{}
+		for _, o := range objects {
+			names = append(names, o.name)
+			if o.name == "registry" && len(o.serviceAccounts) != 2 {
+				t.Errorf("expected the pull secret to be linked to 2 service accounts, got %v", o.serviceAccounts)
+			}
+			if o.name != "registry" && len(o.serviceAccounts) != 0 {
+				t.Errorf("expected secret %v not to be linked, got %v", o.name, o.serviceAccounts)
+			}
+		}
+		if len(names) != len(test.expected) {
+			t.Errorf("types %v: expected %v, got %v", test.types, test.expected, names)
+			continue
+		}
+		for i := range names {
+			if names[i] != test.expected[i] {
+				t.Errorf("types %v: expected %v, got %v", test.types, test.expected, names)
+				break
+			}
+		}
+	}
+}
+
+func TestGetCopiedConfigMaps(t *testing.T) {
+	configmaps := []*gabs.Container{}
+	for _, name := range []string{"kube-root-ca.crt", "app", "openshift-service-ca.crt"} {
+		c := gabs.New()
+		c.Set(name, "metadata", "name")
+		configmaps = append(configmaps, c)
+	}
+	objects := getCopiedConfigMaps(configmaps)
+	if len(objects) != 1 || objects[0].name != "app" {
+		t.Errorf("expected only the configmap app, got %v", objects)
+	}
+	if url := objects[0].collectionURL("target"); url != "api/v1/namespaces/target/configmaps" {
+		t.Errorf("unexpected collection url %v", url)
+	}
+}
@@ -0,0 +1,308 @@
+package openshift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	networkPolicyPresetAnnotation = "openshift.io/networkpolicy-preset"
+	networkPolicySourceAnnotation = "openshift.io/networkpolicy-source-project"
+
+	presetDenyAll            = "deny-all"
+	presetAllowSameNamespace = "allow-same-namespace"
+	presetAllowFromIngress   = "allow-from-ingress"
+	presetAllowFromProject   = "allow-from-project"
+
+	// Label of the namespace used to select other projects
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+
+	defaultIngressNamespaceLabel = "network.openshift.io/policy-group=ingress"
+)
+
+type NetworkPolicyPreset struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+var networkPolicyPresets = []NetworkPolicyPreset{
+	{presetDenyAll, "Denies all incoming traffic to the pods of the project"},
+	{presetAllowSameNamespace, "Allows traffic between the pods of the project"},
+	{presetAllowFromIngress, "Allows traffic from the router (routes and ingresses)"},
+	{presetAllowFromProject, "Allows traffic from another project you administer"},
+}
+
+func getNetworkPolicyPresetsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, networkPolicyPresets)
+}
+
+func getNetworkPoliciesHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	policies, err := getNetworkPolicies(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policies)
+}
+
+func newNetworkPolicyHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.NetworkPolicyCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if data.Preset == presetAllowFromProject {
+		if data.SourceProject == data.Project {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Use the preset allow-same-namespace for traffic within the project"})
+			return
+		}
+		// Users can only open their project to projects they administer as well
+		if err := validateAdminAccess(data.ClusterId, username, data.SourceProject); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	policy, err := renderNetworkPolicy(data.Preset, data.SourceProject)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if data.Preset == presetAllowFromProject {
+		if err := ensureNamespaceNameLabel(data.ClusterId, data.SourceProject); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	if err := createNetworkPolicy(data.ClusterId, data.Project, policy); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	name := policy.Path("metadata.name").Data()
+	log.Printf("%v created the network policy %v (%v) in project %v on cluster %v", username, name, data.Preset, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The network policy %v has been created in the project %v", name, data.Project),
+	})
+}
+
+func deleteNetworkPolicyHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+	name := params.Get("name")
+
+	if name == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Name must be provided"})
+		return
+	}
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := deleteNetworkPolicy(clusterId, project, name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v deleted the network policy %v in project %v on cluster %v", username, name, project, clusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The network policy %v has been deleted", name),
+	})
+}
+
+// renderNetworkPolicy creates the NetworkPolicy of the preset.
+// Users can't provide their own policies.
+func renderNetworkPolicy(preset, sourceProject string) (*gabs.Container, error) {
+	var name string
+	var from interface{}
+	switch preset {
+	case presetDenyAll:
+		name = presetDenyAll
+	case presetAllowSameNamespace:
+		name = presetAllowSameNamespace
+		from = map[string]interface{}{"podSelector": map[string]interface{}{}}
+	case presetAllowFromIngress:
+		key, value, err := getIngressNamespaceLabel()
+		if err != nil {
+			return nil, err
+		}
+		name = presetAllowFromIngress
+		from = map[string]interface{}{
+			"namespaceSelector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{key: value},
+			},
+		}
+	case presetAllowFromProject:
+		if sourceProject == "" {
+			return nil, errors.New("Source project must be provided")
+		}
+		name = "allow-from-" + sourceProject
+		from = map[string]interface{}{
+			"namespaceSelector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{namespaceNameLabel: sourceProject},
+			},
+		}
+	default:
+		return nil, fmt.Errorf("Unknown network policy preset: %v", preset)
+	}
+
+	policy := newObjectRequest("NetworkPolicy", name, "networking.k8s.io/v1")
+	policy.Set(preset, "metadata", "annotations", networkPolicyPresetAnnotation)
+	if sourceProject != "" && preset == presetAllowFromProject {
+		policy.Set(sourceProject, "metadata", "annotations", networkPolicySourceAnnotation)
+	}
+	policy.Set(map[string]interface{}{}, "spec", "podSelector")
+	policy.Set([]interface{}{"Ingress"}, "spec", "policyTypes")
+	if from == nil {
+		// No ingress rule denies all traffic
+		policy.Set([]interface{}{}, "spec", "ingress")
+	} else {
+		policy.Set([]interface{}{
+			map[string]interface{}{"from": []interface{}{from}},
+		}, "spec", "ingress")
+	}
+	return policy, nil
+}
+
+// getIngressNamespaceLabel returns the label (key=value) of the router namespaces
+func getIngressNamespaceLabel() (string, string, error) {
+	label := config.Config().GetString("openshift_ingress_namespace_label")
+	if label == "" {
+		label = defaultIngressNamespaceLabel
+	}
+	parts := strings.SplitN(label, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		log.Printf("WARNING: Invalid openshift_ingress_namespace_label: %v", label)
+		return "", "", errors.New(common.ConfigNotSetError)
+	}
+	return parts[0], parts[1], nil
+}
+
+// ensureNamespaceNameLabel sets the name label on namespaces of older
+// clusters, which don't set it automatically
+func ensureNamespaceNameLabel(clusterId, project string) error {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return err
+	}
+	if namespace.Exists("metadata", "labels", namespaceNameLabel) {
+		return nil
+	}
+	namespace.Set(project, "metadata", "labels", namespaceNameLabel)
+	return updateNamespace(clusterId, project, namespace)
+}
+
+func getNetworkPolicies(clusterId, project string) ([]common.NetworkPolicy, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "apis/networking.k8s.io/v1/namespaces/"+project+"/networkpolicies", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	policies := []common.NetworkPolicy{}
+	for _, np := range json.S("items").Children() {
+		name, _ := np.Path("metadata.name").Data().(string)
+		policies = append(policies, common.NetworkPolicy{
+			Name:          name,
+			Preset:        getAnnotation(np, networkPolicyPresetAnnotation),
+			SourceProject: getAnnotation(np, networkPolicySourceAnnotation),
+		})
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	return policies, nil
+}
+
+func createNetworkPolicy(clusterId, project string, policy *gabs.Container) error {
+	resp, err := getOseHTTPClient("POST", clusterId, "apis/networking.k8s.io/v1/namespaces/"+project+"/networkpolicies", bytes.NewReader(policy.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("The network policy %v already exists", policy.Path("metadata.name").Data())
+	}
+	if resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error creating network policy:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// deleteNetworkPolicy deletes the policy if it was created from a preset.
+// Policies of the operators can't be deleted.
+func deleteNetworkPolicy(clusterId, project, name string) error {
+	url := "apis/networking.k8s.io/v1/namespaces/" + project + "/networkpolicies/" + name
+	resp, err := getOseHTTPClient("GET", clusterId, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("The network policy %v doesn't exist", name)
+	}
+	policy, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return errors.New(genericAPIError)
+	}
+	if getAnnotation(policy, networkPolicyPresetAnnotation) == "" {
+		return fmt.Errorf("The network policy %v was not created by the SSP and can't be deleted", name)
+	}
+
+	resp, err = getOseHTTPClient("DELETE", clusterId, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error deleting network policy:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestRenderNetworkPolicy(t *testing.T) {
+	config.Init("bla")
+
+	var tests = []struct {
+		preset        string
+		sourceProject string
+		name          string
+		// Label of the namespace selector
+		selector string
+		valid    bool
+	}{
+		{presetDenyAll, "", "deny-all", "", true},
+		{presetAllowSameNamespace, "", "allow-same-namespace", "", true},
+		{presetAllowFromIngress, "", "allow-from-ingress", "network.openshift.io/policy-group", true},
+		{presetAllowFromProject, "other", "allow-from-other", "kubernetes.io/metadata.name", true},
+		{presetAllowFromProject, "", "", "", false},
+		{"allow-all", "", "", "", false},
+	}
+	for _, test := range tests {
+		policy, err := renderNetworkPolicy(test.preset, test.sourceProject)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("Expected an error for the preset %v", test.preset)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error for the preset %v: %v", test.preset, err)
+		}
+		// Parse the json to check the rendered object
+		json, _ := gabs.ParseJSON(policy.Bytes())
+		if json.Path("metadata.name").Data() != test.name {
+			t.Errorf("Expected the name %v, got %v", test.name, json.Path("metadata.name").Data())
+		}
+		if getAnnotation(json, networkPolicyPresetAnnotation) != test.preset {
+			t.Errorf("Expected the preset annotation %v, got %v", test.preset, getAnnotation(json, networkPolicyPresetAnnotation))
+		}
+		ingress := json.Path("spec.ingress").Children()
+		if test.preset == presetDenyAll {
+			if len(ingress) != 0 {
+				t.Errorf("Expected no ingress rules for deny-all, got %v", ingress)
+			}
+			continue
+		}
+		if len(ingress) != 1 {
+			t.Fatalf("Expected one ingress rule for %v, got %v", test.preset, ingress)
+		}
+		if test.selector != "" {
+			from := ingress[0].S("from").Children()[0]
+			if !from.Exists("namespaceSelector", "matchLabels", test.selector) {
+				t.Errorf("Expected %v in %v", test.selector, from)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package openshift
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io/ioutil"
 	"log"
@@ -26,15 +27,16 @@ func newProjectHandler(c *gin.Context) {
 
 	var data common.NewProjectCommand
 	if c.BindJSON(&data) == nil {
-		if err := validateNewProject(data.Project, data.Billing, false); err != nil {
+		metadata := withLegacyMetadata(data.Metadata, data.Billing, data.MegaId)
+		if err := validateNewProject(data.Project, metadata, false); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 			return
 		}
 
-		if err := createNewProject(data.ClusterId, data.Project, username, data.Billing, data.MegaId, false); err != nil {
+		if err := createNewProject(data.ClusterId, data.Project, username, metadata, data.Blueprint, false); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		} else {
-			err := sendNewProjectMail(data.ClusterId, data.Project, username, data.MegaId)
+			err := sendNewProjectMail(data.ClusterId, data.Project, username, metadata)
 			if err != nil {
 				log.Printf("Can't send e-mail about new project (%v) on cluster %v.", err, data.ClusterId)
 			}
@@ -54,15 +56,15 @@ func newTestProjectHandler(c *gin.Context) {
 	var data common.NewTestProjectCommand
 	if c.BindJSON(&data) == nil {
 		// Special values for a test project
-		billing := "keine-verrechnung"
+		metadata := getTestProjectMetadata(getProjectMetadataSchema())
 		data.Project = username + "-" + data.Project
 
-		if err := validateNewProject(data.Project, billing, true); err != nil {
+		if err := validateNewProject(data.Project, metadata, true); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 			return
 		}
 
-		if err := createNewProject(data.ClusterId, data.Project, username, billing, "", true); err != nil {
+		if err := createNewProject(data.ClusterId, data.Project, username, metadata, "", true); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		} else {
 			c.JSON(http.StatusOK, common.ApiResponse{
@@ -96,10 +98,8 @@ func getProjectsHandler(c *gin.Context) {
 // this is used by ESTA
 func filterProjects(projects *gabs.Container, params url.Values) *gabs.Container {
 	filtered, _ := gabs.New().Array()
-	// possible filters:
-	var filterMap = map[string]string{
-		"sbb_accounting_number": "openshift.io/kontierung-element",
-		"sbb_mega_id":           "openshift.io/MEGAID"}
+	// possible filters are defined by the project metadata schema
+	filterMap := getMetadataFilters(getProjectMetadataSchema())
 	// "filters" is a map containing only the parameters with valid
 	// filter names
 	filters := make(map[string]string)
@@ -156,7 +156,11 @@ func getProjectNames(projects *gabs.Container) []string {
 }
 
 func getProjects(clusterid, username string) (*gabs.Container, error) {
-	resp, err := getOseHTTPClient("GET", clusterid, "apis/project.openshift.io/v1/projects", nil)
+	return getProjectsWithContext(context.Background(), clusterid, username)
+}
+
+func getProjectsWithContext(ctx context.Context, clusterid, username string) (*gabs.Container, error) {
+	resp, err := getOseHTTPClientWithContext(ctx, "GET", clusterid, "apis/project.openshift.io/v1/projects", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -186,13 +190,20 @@ func getProjectAdminsHandler(c *gin.Context) {
 
 	log.Printf("%v has queried all the admins of project %v on cluster %v", username, project, clusterId)
 
-	if admins, _, err := getProjectAdminsAndOperators(clusterId, project); err != nil {
+	admins, _, err := getProjectAdminsAndOperators(clusterId, project)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
-	} else {
-		c.JSON(http.StatusOK, common.AdminList{
-			Admins: admins,
-		})
+		return
+	}
+	details, err := getProjectAdminDetails(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
 	}
+	c.JSON(http.StatusOK, common.AdminList{
+		Admins:  admins,
+		Details: details,
+	})
 }
 
 func getProjectInformationHandler(c *gin.Context) {
@@ -225,7 +236,8 @@ func updateProjectInformationHandler(c *gin.Context) {
 			return
 		}
 
-		if err := createOrUpdateMetadata(data.ClusterId, data.Project, data.Billing, data.MegaID, username, false); err != nil {
+		metadata := withLegacyMetadata(data.Metadata, data.Billing, data.MegaID)
+		if err := updateProjectMetadata(data.ClusterId, data.Project, metadata, username); err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		} else {
 			c.JSON(http.StatusOK, common.ApiResponse{
@@ -276,16 +288,12 @@ func addProjectAdminHandler(c *gin.Context) {
 	})
 }
 
-func validateNewProject(project string, billing string, testProject bool) error {
+func validateNewProject(project string, metadata map[string]string, testProject bool) error {
 	if len(project) == 0 {
 		return errors.New("Project name has to be provided")
 	}
 
-	if !testProject && len(billing) == 0 {
-		return errors.New("Accounting number must be provided")
-	}
-
-	return nil
+	return validateMetadata(getProjectMetadataSchema(), metadata, testProject)
 }
 
 func validateAdminAccess(clusterId, username, project string) error {
@@ -343,8 +351,10 @@ func validateProjectInformation(data common.UpdateProjectInformationCommand, use
 		return errors.New("Project name must be provided")
 	}
 
-	if data.Billing == "" {
-		return errors.New("Accounting number must be provided")
+	schema := getProjectMetadataSchema()
+	metadata := withLegacyMetadata(data.Metadata, data.Billing, data.MegaID)
+	if err := validateMetadata(schema, metadata, false); err != nil {
+		return err
 	}
 
 	// Validate permissions
@@ -352,33 +362,18 @@ func validateProjectInformation(data common.UpdateProjectInformationCommand, use
 		return err
 	}
 
-	return nil
+	return validateMetadataUpdate(data.ClusterId, data.Project, username, metadata)
 }
 
-func sendNewProjectMail(clusterId string, projectName string, userName string, megaID string) error {
-
-	mailServer, ok := os.LookupEnv("MAIL_SERVER")
-	if !ok {
-		return errors.New("Error looking up MAIL_SERVER from environment.")
-	}
-
-	fromMail, ok := os.LookupEnv("MAIL_ADMIN_SENDER")
-	if !ok {
-		return errors.New("Error looking up MAIL_ADMIN_SENDER from environment.")
-	}
+func sendNewProjectMail(clusterId string, projectName string, userName string, metadata map[string]string) error {
 
 	newProjectMail, ok := os.LookupEnv("MAIL_NEW_PROJECT_RECIPIENT")
 	if !ok {
 		return errors.New("Error looking up MAIL_NEW_PROJECT_RECIPIENT from environment.")
 	}
 
-	m := gomail.NewMessage()
-	m.SetHeader("From", fromMail)
-
-	m.SetHeader("To", newProjectMail)
-	m.SetHeader("Subject", fmt.Sprintf("New Project '%v' on OpenShift", projectName))
-
-	m.SetBody("text/html", fmt.Sprintf(`
+	subject := fmt.Sprintf("New Project '%v' on OpenShift", projectName)
+	body := fmt.Sprintf(`
 	Dear Ladys and Gentleman,
 	<br><br>
 	The following project has been created on:
@@ -386,26 +381,60 @@ func sendNewProjectMail(clusterId string, projectName string, userName string, m
 	Cluster: %v<br>
 	Project name:	%v<br>
 	Creator:		%v<br>
-	Mega ID:		%v
+	Mega ID:		%v<br>
+	Metadata:		%v
 	<br><br>
 	Kind regards<br>
 	Your Cloud Team<br>
 	IT-OM-SDL-CLP
-	`, clusterId, projectName, userName, megaID))
+	`, clusterId, projectName, userName, metadata[metadataMegaId], formatMetadata(metadata))
+
+	return sendMail(newProjectMail, subject, body)
+}
+
+// sendMail sends a html mail from the admin sender address
+func sendMail(to, subject, body string) error {
+	mailServer, ok := os.LookupEnv("MAIL_SERVER")
+	if !ok {
+		return errors.New("Error looking up MAIL_SERVER from environment.")
+	}
+
+	fromMail, ok := os.LookupEnv("MAIL_ADMIN_SENDER")
+	if !ok {
+		return errors.New("Error looking up MAIL_ADMIN_SENDER from environment.")
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", fromMail)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body)
 
 	d := gomail.Dialer{Host: mailServer, Port: 25}
 	d.TLSConfig = &tls.Config{InsecureSkipVerify: true}
-	err := d.DialAndSend(m)
+	return d.DialAndSend(m)
+}
 
+func createNewProject(clusterId string, project string, username string, metadata map[string]string, blueprintName string, testProject bool) error {
+	project = strings.ToLower(project)
+	cluster, err := getOpenshiftCluster(clusterId)
 	if err != nil {
 		return err
 	}
+	if err := validateProjectCreationPermission(cluster, username); err != nil {
+		return err
+	}
+	// Without name, the default blueprint is used (if there is one)
+	blueprint, err := getProjectBlueprint(blueprintName)
+	if err != nil {
+		return err
+	}
+	if blueprint != nil {
+		if err := validateProjectBlueprint(*blueprint); err != nil {
+			return err
+		}
+	}
 
-	return nil
-}
-
-func createNewProject(clusterId string, project string, username string, billing string, megaid string, testProject bool) error {
-	project = strings.ToLower(project)
 	p := newObjectRequest("ProjectRequest", project, "project.openshift.io/v1")
 
 	resp, err := getOseHTTPClient("POST", clusterId, "apis/project.openshift.io/v1/projectrequests", bytes.NewReader(p.Bytes()))
@@ -421,9 +450,15 @@ func createNewProject(clusterId string, project string, username string, billing
 			return err
 		}
 
-		if err := createOrUpdateMetadata(clusterId, project, billing, megaid, username, testProject); err != nil {
+		if err := createProjectMetadata(clusterId, project, metadata, username, testProject); err != nil {
 			return err
 		}
+
+		if blueprint != nil {
+			if err := applyProjectBlueprint(clusterId, project, username, *blueprint); err != nil {
+				return fmt.Errorf("The project %v has been created, but the blueprint %v could not be applied: %v", project, blueprint.Name, err.Error())
+			}
+		}
 		return nil
 	}
 	if resp.StatusCode == http.StatusConflict {
@@ -477,9 +512,72 @@ func changeProjectPermission(clusterId string, project string, username string)
 	return errors.New(genericAPIError)
 }
 
+// removeProjectPermission removes the user in both lower- and uppercase
+// from all rolebindings granting the admin role
+func removeProjectPermission(clusterId string, project string, username string) error {
+	if err := removeProjectRoleSubject(clusterId, project, "admin", subjectKindUser, username); err != nil {
+		return err
+	}
+	log.Print(username + " is no longer admin of " + project)
+	return nil
+}
+
+func projectExists(clusterId, project string) (bool, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error getting project:", resp.StatusCode, string(errMsg))
+		return false, errors.New(genericAPIError)
+	}
+	return true, nil
+}
+
+func getNamespace(clusterId, project string) (*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.New("Das Projekt existiert nicht")
+	}
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, errors.New(genericAPIError)
+	}
+	return json, nil
+}
+
+func updateNamespace(clusterId, project string, namespace *gabs.Container) error {
+	resp, err := getOseHTTPClient("PUT", clusterId, "api/v1/namespaces/"+project, bytes.NewReader(namespace.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error updating namespace:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
 type ProjectInformation struct {
-	Kontierungsnummer string `json:"kontierungsnummer"`
-	MegaID            string `json:"megaid"`
+	Kontierungsnummer string            `json:"kontierungsnummer"`
+	MegaID            string            `json:"megaid"`
+	Metadata          map[string]string `json:"metadata"`
 }
 
 func getProjectInformation(clusterId, project string) (*ProjectInformation, error) {
@@ -496,21 +594,31 @@ func getProjectInformation(clusterId, project string) (*ProjectInformation, erro
 		return nil, errors.New(genericAPIError)
 	}
 
-	billing := json.Path("metadata.annotations").S("openshift.io/kontierung-element").Data()
-	if billing == nil {
-		billing = ""
-	}
-	megaid := json.Path("metadata.annotations").S("openshift.io/MEGAID").Data()
-	if megaid == nil {
-		megaid = ""
-	}
+	metadata := getMetadataFromAnnotations(getProjectMetadataSchema(), json)
 	return &ProjectInformation{
-		Kontierungsnummer: billing.(string),
-		MegaID:            megaid.(string),
+		Kontierungsnummer: metadata[metadataBilling],
+		MegaID:            metadata[metadataMegaId],
+		Metadata:          metadata,
 	}, nil
 }
 
-func createOrUpdateMetadata(clusterId, project string, billing string, megaid string, username string, testProject bool) error {
+// createProjectMetadata sets the metadata and the requester of a new project
+func createProjectMetadata(clusterId, project string, metadata map[string]string, username string, testProject bool) error {
+	annotations := map[string]string{requesterAnnotation: username}
+	if testProject {
+		annotations["openshift.io/testproject-daystodeletion"] = testProjectDeletionDays
+		annotations["openshift.io/description"] = fmt.Sprintf("Dieses Testprojekt wird in %v Tagen automatisch gelöscht!", testProjectDeletionDays)
+	}
+	return saveProjectMetadata(clusterId, project, metadata, annotations, username)
+}
+
+// updateProjectMetadata changes the metadata of an existing project.
+// The requester stays unchanged, it's only changed by an ownership transfer.
+func updateProjectMetadata(clusterId, project string, metadata map[string]string, username string) error {
+	return saveProjectMetadata(clusterId, project, metadata, nil, username)
+}
+
+func saveProjectMetadata(clusterId, project string, metadata map[string]string, annotations map[string]string, username string) error {
 	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project, nil)
 	if err != nil {
 		return err
@@ -524,17 +632,9 @@ func createOrUpdateMetadata(clusterId, project string, billing string, megaid st
 		return errors.New(genericAPIError)
 	}
 
-	annotations := json.Path("metadata.annotations")
-	annotations.Set(billing, "openshift.io/kontierung-element")
-	annotations.Set(username, "openshift.io/requester")
-
-	if testProject {
-		annotations.Set(testProjectDeletionDays, "openshift.io/testproject-daystodeletion")
-		annotations.Set(fmt.Sprintf("Dieses Testprojekt wird in %v Tagen automatisch gelöscht!", testProjectDeletionDays), "openshift.io/description")
-	}
-
-	if len(megaid) > 0 {
-		annotations.Set(megaid, "openshift.io/MEGAID")
+	setMetadataAnnotations(getProjectMetadataSchema(), json, metadata)
+	for k, v := range annotations {
+		json.Set(v, "metadata", "annotations", k)
 	}
 
 	resp, err = getOseHTTPClient("PUT", clusterId, "api/v1/namespaces/"+project, bytes.NewReader(json.Bytes()))
@@ -544,7 +644,7 @@ func createOrUpdateMetadata(clusterId, project string, billing string, megaid st
 
 	if resp.StatusCode == http.StatusOK {
 		resp.Body.Close()
-		log.Println("User "+username+" changed config of project "+project+" on cluster "+clusterId+". Kontierungsnummer: "+billing, ", MegaID: "+megaid)
+		log.Println("User " + username + " changed config of project " + project + " on cluster " + clusterId + ". " + formatMetadata(metadata))
 		return nil
 	}
 
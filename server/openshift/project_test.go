@@ -2,6 +2,9 @@ package openshift
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
@@ -10,6 +13,8 @@ import (
 )
 
 func TestProjectFilter(t *testing.T) {
+	// the filters are defined by the project metadata schema in the config
+	config.Init("bla")
 	projects, err := gabs.ParseJSON([]byte(`[
 		{
 			"metadata": {
@@ -127,3 +132,39 @@ func TestValidateProjectPermissions(t *testing.T) {
 		t.Error("ERROR! function \"validateProjectPermissions\" not checking the functional account")
 	}
 }
+
+func TestProjectMetadataRequester(t *testing.T) {
+	config.Init("bla")
+
+	var saved *gabs.Container
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			body, _ := ioutil.ReadAll(r.Body)
+			saved, _ = gabs.ParseJSON(body)
+			w.Write(body)
+			return
+		}
+		w.Write([]byte(`{"metadata": {"name": "app", "annotations": {"openshift.io/requester": "owner"}}}`))
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "url": server.URL, "token": "token"}})
+	defer config.Config().Set("openshift", nil)
+
+	if err := updateProjectMetadata("c1", "app", map[string]string{metadataBilling: "1234"}, "u123456"); err != nil {
+		t.Fatal(err)
+	}
+	if requester := getAnnotation(saved, requesterAnnotation); requester != "owner" {
+		t.Errorf("Expected the requester to stay owner, got %v", requester)
+	}
+	if billing := getAnnotation(saved, "openshift.io/kontierung-element"); billing != "1234" {
+		t.Errorf("Expected the accounting number 1234, got %v", billing)
+	}
+
+	if err := createProjectMetadata("c1", "app", map[string]string{metadataBilling: "1234"}, "u123456", false); err != nil {
+		t.Fatal(err)
+	}
+	if requester := getAnnotation(saved, requesterAnnotation); requester != "u123456" {
+		t.Errorf("Expected the creator to be the requester, got %v", requester)
+	}
+}
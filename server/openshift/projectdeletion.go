@@ -0,0 +1,455 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	deletionScheduledLabel      = "openshift.io/deletion-scheduled"
+	deletionDateAnnotation      = "openshift.io/deletion-date"
+	deletionRequesterAnnotation = "openshift.io/deletion-requester"
+	replicasAnnotation          = "openshift.io/replicas-before-deletion"
+	// Set on the pvs of a deleted project. They are deleted as soon as the project is gone.
+	deletedProjectLabel      = "openshift.io/deleted-project"
+	defaultDeletionGraceDays = 7
+)
+
+// Confirmation tokens for project deletions. A token is only valid for the
+// user, project and mode (soft/hard) it was issued for.
+var deletionTokens = cache.New(10*time.Minute, 10*time.Minute)
+
+type deletionRequest struct {
+	ClusterId  string
+	Project    string
+	Username   string
+	SoftDelete bool
+}
+
+// Workloads that are scaled down during the grace period of a soft delete
+var scalableWorkloads = []string{
+	"apis/apps.openshift.io/v1/namespaces/%v/deploymentconfigs",
+	"apis/apps/v1/namespaces/%v/deployments",
+	"apis/apps/v1/namespaces/%v/statefulsets",
+}
+
+// deleteProjectHandler deletes a project in two steps: the first call returns
+// what will be deleted and a confirmation token. The second call with the
+// token deletes the project or schedules the deletion (softdelete=true).
+func deleteProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+	token := params.Get("token")
+	softDelete := params.Get("softdelete") == "true"
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	request := deletionRequest{
+		ClusterId:  clusterId,
+		Project:    project,
+		Username:   username,
+		SoftDelete: softDelete,
+	}
+
+	if token == "" {
+		volumes, err := getProjectVolumes(clusterId, project)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		token = common.RandomString(16)
+		deletionTokens.Set(token, request, cache.DefaultExpiration)
+
+		message := fmt.Sprintf("The project %v on cluster %v and all its volumes will be deleted. Please confirm with the token.", project, clusterId)
+		if softDelete {
+			message = fmt.Sprintf("The project %v on cluster %v will be scaled down and deleted with all its volumes in %v days. Please confirm with the token.", project, clusterId, getDeletionGraceDays())
+		}
+		c.JSON(http.StatusOK, common.ProjectDeletionResponse{
+			Message:    message,
+			Token:      token,
+			SoftDelete: softDelete,
+			Volumes:    volumes,
+		})
+		return
+	}
+
+	confirmed, ok := deletionTokens.Get(token)
+	if !ok || confirmed.(deletionRequest) != request {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "The confirmation token is invalid or has expired"})
+		return
+	}
+	deletionTokens.Delete(token)
+
+	if softDelete {
+		deletionDate, err := scheduleProjectDeletion(clusterId, project, username)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, common.ProjectDeletionResponse{
+			Message:    fmt.Sprintf("The project %v on cluster %v has been scaled down and will be deleted on %v", project, clusterId, deletionDate.Format("02.01.2006")),
+			SoftDelete: true,
+		})
+		return
+	}
+
+	if err := deleteProjectAndVolumes(clusterId, project, username); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, common.ProjectDeletionResponse{
+		Message: fmt.Sprintf("The project %v on cluster %v has been deleted. Its volumes are deleted as soon as the project is gone.", project, clusterId),
+	})
+}
+
+// restoreProjectHandler cancels a scheduled deletion and scales
+// the workloads back up
+func restoreProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.OpenshiftBase
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := cancelProjectDeletion(data.ClusterId, data.Project, username); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The deletion of project %v on cluster %v has been cancelled", data.Project, data.ClusterId),
+	})
+}
+
+func getDeletionGraceDays() int {
+	return getConfigIntOrDefault("project_deletion_grace_days", defaultDeletionGraceDays)
+}
+
+func scheduleProjectDeletion(clusterId, project, username string) (time.Time, error) {
+	deletionDate := time.Now().AddDate(0, 0, getDeletionGraceDays())
+
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return deletionDate, err
+	}
+	namespace.Set("true", "metadata", "labels", deletionScheduledLabel)
+	namespace.Set(deletionDate.Format(time.RFC3339), "metadata", "annotations", deletionDateAnnotation)
+	namespace.Set(username, "metadata", "annotations", deletionRequesterAnnotation)
+	if err := updateNamespace(clusterId, project, namespace); err != nil {
+		return deletionDate, err
+	}
+
+	if err := scaleProjectWorkloads(clusterId, project, true); err != nil {
+		return deletionDate, err
+	}
+
+	log.Printf("%v scheduled the deletion of project %v on cluster %v for %v", username, project, clusterId, deletionDate)
+	return deletionDate, nil
+}
+
+func cancelProjectDeletion(clusterId, project, username string) error {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return err
+	}
+	if !namespace.Exists("metadata", "labels", deletionScheduledLabel) {
+		return fmt.Errorf("The project %v isn't scheduled for deletion", project)
+	}
+	namespace.Delete("metadata", "labels", deletionScheduledLabel)
+	namespace.Delete("metadata", "annotations", deletionDateAnnotation)
+	namespace.Delete("metadata", "annotations", deletionRequesterAnnotation)
+	if err := updateNamespace(clusterId, project, namespace); err != nil {
+		return err
+	}
+
+	if err := scaleProjectWorkloads(clusterId, project, false); err != nil {
+		return err
+	}
+
+	log.Printf("%v cancelled the deletion of project %v on cluster %v", username, project, clusterId)
+	return nil
+}
+
+// scaleProjectWorkloads scales all workloads of the project down to zero.
+// The previous replicas are stored in an annotation and restored when
+// scaling back up (down=false).
+func scaleProjectWorkloads(clusterId, project string, down bool) error {
+	for _, workload := range scalableWorkloads {
+		url := fmt.Sprintf(workload, project)
+		resp, err := getOseHTTPClient("GET", clusterId, url, nil)
+		if err != nil {
+			return err
+		}
+		json, err := gabs.ParseJSONBuffer(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			log.Println("error decoding json:", err, resp.StatusCode)
+			return errors.New(genericAPIError)
+		}
+
+		for _, item := range json.S("items").Children() {
+			name, _ := item.Path("metadata.name").Data().(string)
+			var patch []common.JsonPatch
+			if down {
+				patch = getScaleDownPatch(item)
+			} else {
+				patch = getScaleUpPatch(item)
+			}
+			if len(patch) == 0 {
+				continue
+			}
+			if err := patchObject(clusterId, url+"/"+name, patch); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func getScaleDownPatch(item *gabs.Container) []common.JsonPatch {
+	replicas, _ := item.Path("spec.replicas").Data().(float64)
+	if replicas == 0 {
+		return nil
+	}
+	patch := []common.JsonPatch{}
+	if !item.Exists("metadata", "annotations") {
+		patch = append(patch, common.JsonPatch{
+			Operation: "add",
+			Path:      "/metadata/annotations",
+			Value:     map[string]string{},
+		})
+	}
+	return append(patch,
+		common.JsonPatch{
+			Operation: "add",
+			Path:      "/metadata/annotations/" + escapeJsonPointer(replicasAnnotation),
+			Value:     strconv.Itoa(int(replicas)),
+		},
+		common.JsonPatch{
+			Operation: "replace",
+			Path:      "/spec/replicas",
+			Value:     0,
+		})
+}
+
+func getScaleUpPatch(item *gabs.Container) []common.JsonPatch {
+	previous, ok := item.Search("metadata", "annotations", replicasAnnotation).Data().(string)
+	if !ok {
+		return nil
+	}
+	replicas, err := strconv.Atoi(previous)
+	if err != nil {
+		return nil
+	}
+	return []common.JsonPatch{
+		{
+			Operation: "replace",
+			Path:      "/spec/replicas",
+			Value:     replicas,
+		},
+		{
+			Operation: "remove",
+			Path:      "/metadata/annotations/" + escapeJsonPointer(replicasAnnotation),
+		},
+	}
+}
+
+// escapeJsonPointer escapes a key to be used in a JSON patch path (RFC 6901)
+func escapeJsonPointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+func patchObject(clusterId, url string, patch []common.JsonPatch) error {
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		log.Printf("Error marshalling patch: %v", err)
+		return errors.New(genericAPIError)
+	}
+
+	resp, err := getOseHTTPClient("PATCH", clusterId, url, bytes.NewBuffer(patchBytes))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error patching %v on cluster %v: %v %v", url, clusterId, resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// deleteProjectAndVolumes deletes the project. Its pvs are marked and deleted
+// with the gluster and nfs volumes behind them by cleanupDeletedProjectVolumes
+// as soon as the project is gone, because pods may still use them until then.
+func deleteProjectAndVolumes(clusterId, project, username string) error {
+	// The volumes must be looked up before the pvcs are gone
+	volumes, err := getProjectVolumes(clusterId, project)
+	if err != nil {
+		return err
+	}
+	for _, v := range volumes {
+		if v.PvName == "" {
+			continue
+		}
+		if err := markVolumeOfDeletedProject(clusterId, project, v.PvName); err != nil {
+			return err
+		}
+	}
+
+	return deleteProject(clusterId, project, username)
+}
+
+func markVolumeOfDeletedProject(clusterId, project, pvName string) error {
+	pv, err := getOpenshiftPV(clusterId, pvName)
+	if err != nil {
+		return err
+	}
+	pv.Set(project, "metadata", "labels", deletedProjectLabel)
+	return updateOpenshiftPV(clusterId, pv)
+}
+
+// cleanupDeletedProjectVolumes deletes the pvs of deleted projects and the gluster
+// and nfs volumes behind them. NFS volumes must be deleted manually if the cluster
+// has no nfs delete workflow.
+func cleanupDeletedProjectVolumes(clusterId string) error {
+	pvs, err := getProjectObjects(clusterId, "api/v1/persistentvolumes?labelSelector="+deletedProjectLabel)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, pv := range pvs {
+		pvName, _ := pv.Path("metadata.name").Data().(string)
+		project, _ := pv.Search("metadata", "labels", deletedProjectLabel).Data().(string)
+		exists, err := projectExists(clusterId, project)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("PV %v: %v", pvName, err.Error()))
+			continue
+		}
+		if exists {
+			// The project is still being deleted
+			continue
+		}
+
+		v := common.ProjectVolume{PvName: pvName}
+		v.Technology, v.Backend = getVolumeBackend(pv)
+		if err := deleteOpenshiftPV(clusterId, pvName, schedulerUsername); err != nil {
+			errs = append(errs, fmt.Sprintf("PV %v: %v", pvName, err.Error()))
+			continue
+		}
+		if v.Technology == "nfs" {
+			if _, err := getNfsDeleteWorkflow(clusterId); err != nil {
+				log.Printf("WARNING: The nfs volume %v of the deleted project %v on cluster %v must be deleted manually", v.Backend, project, clusterId)
+				continue
+			}
+		}
+		if _, err := deleteVolumeBackend(clusterId, v, schedulerUsername); err != nil {
+			errs = append(errs, fmt.Sprintf("Volume %v of project %v: %v", v.Backend, project, err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("The following volumes of deleted projects could not be cleaned up: %v", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func deleteProject(clusterId, project, username string) error {
+	resp, err := getOseHTTPClient("DELETE", clusterId, "apis/project.openshift.io/v1/projects/"+project, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.New("Das Projekt existiert nicht")
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Println("Error deleting project:", resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+
+	log.Printf("%v deleted the project %v on cluster %v", username, project, clusterId)
+	return nil
+}
+
+// getProjectsScheduledForDeletion returns the projects with a soft delete
+// whose grace period is over
+func getProjectsScheduledForDeletion(clusterId string, now time.Time) ([]string, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces?labelSelector="+deletionScheduledLabel+"%3Dtrue", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, errors.New(genericAPIError)
+	}
+
+	projects := []string{}
+	for _, namespace := range json.S("items").Children() {
+		name, _ := namespace.Path("metadata.name").Data().(string)
+		date, _ := namespace.Search("metadata", "annotations", deletionDateAnnotation).Data().(string)
+		deletionDate, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			log.Printf("WARNING: Invalid deletion date '%v' on project %v on cluster %v", date, name, clusterId)
+			continue
+		}
+		if now.After(deletionDate) {
+			projects = append(projects, name)
+		}
+	}
+	return projects, nil
+}
+
+func runScheduledProjectDeletions() {
+	for _, cluster := range getOpenshiftClusters("") {
+		projects, err := getProjectsScheduledForDeletion(cluster.ID, time.Now())
+		if err != nil {
+			log.Printf("Error getting projects scheduled for deletion on cluster %v: %v", cluster.ID, err)
+			continue
+		}
+		for _, project := range projects {
+			if err := deleteProjectAndVolumes(cluster.ID, project, schedulerUsername); err != nil {
+				log.Printf("Error deleting project %v on cluster %v: %v", project, cluster.ID, err)
+			}
+		}
+	}
+}
+
+func runDeletedProjectVolumeCleanup() {
+	for _, cluster := range getOpenshiftClusters("") {
+		if err := cleanupDeletedProjectVolumes(cluster.ID); err != nil {
+			log.Printf("Error cleaning up volumes on cluster %v: %v", cluster.ID, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package openshift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestEscapeJsonPointer(t *testing.T) {
+	var tests = []struct {
+		key      string
+		expected string
+	}{
+		{"replicas", "replicas"},
+		{"openshift.io/replicas-before-deletion", "openshift.io~1replicas-before-deletion"},
+		{"a~b", "a~0b"},
+		{"a~/b", "a~0~1b"},
+	}
+	for _, test := range tests {
+		if escaped := escapeJsonPointer(test.key); escaped != test.expected {
+			t.Errorf("escapeJsonPointer(%v): expected %v, got %v", test.key, test.expected, escaped)
+		}
+	}
+}
+
+func TestGetScaleDownPatch(t *testing.T) {
+	stopped, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "app"}, "spec": {"replicas": 0}}`))
+	if patch := getScaleDownPatch(stopped); len(patch) != 0 {
+		t.Errorf("expected no patch for a stopped workload, got %+v", patch)
+	}
+
+	withoutAnnotations, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "app"}, "spec": {"replicas": 3}}`))
+	patch := getScaleDownPatch(withoutAnnotations)
+	if len(patch) != 3 || patch[0].Path != "/metadata/annotations" {
+		t.Fatalf("expected the annotations to be created first, got %+v", patch)
+	}
+	if patch[1].Path != "/metadata/annotations/openshift.io~1replicas-before-deletion" || patch[1].Value != "3" {
+		t.Errorf("expected the replicas to be stored, got %+v", patch[1])
+	}
+	if patch[2].Path != "/spec/replicas" || patch[2].Value != 0 {
+		t.Errorf("expected the replicas to be set to 0, got %+v", patch[2])
+	}
+
+	withAnnotations, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "app", "annotations": {"a": "b"}}, "spec": {"replicas": 2}}`))
+	if patch := getScaleDownPatch(withAnnotations); len(patch) != 2 || patch[0].Value != "2" {
+		t.Errorf("expected the existing annotations to be kept, got %+v", patch)
+	}
+}
+
+func TestGetScaleUpPatch(t *testing.T) {
+	var tests = []struct {
+		item     string
+		replicas interface{}
+	}{
+		{`{"metadata": {"annotations": {"openshift.io/replicas-before-deletion": "3"}}, "spec": {"replicas": 0}}`, 3},
+		{`{"metadata": {"annotations": {"openshift.io/replicas-before-deletion": "x"}}, "spec": {"replicas": 0}}`, nil},
+		{`{"metadata": {}, "spec": {"replicas": 0}}`, nil},
+	}
+	for _, test := range tests {
+		item, _ := gabs.ParseJSON([]byte(test.item))
+		patch := getScaleUpPatch(item)
+		if test.replicas == nil {
+			if len(patch) != 0 {
+				t.Errorf("getScaleUpPatch(%v): expected no patch, got %+v", test.item, patch)
+			}
+			continue
+		}
+		if len(patch) != 2 || patch[0].Value != test.replicas || patch[1].Operation != "remove" {
+			t.Errorf("getScaleUpPatch(%v): expected %v replicas, got %+v", test.item, test.replicas, patch)
+		}
+	}
+}
+
+func TestGetProjectsScheduledForDeletion(t *testing.T) {
+	config.Init("bla")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("labelSelector") != deletionScheduledLabel+"=true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"items": [
+			{"metadata": {"name": "expired", "annotations": {"openshift.io/deletion-date": "2020-08-01T00:00:00Z"}}},
+			{"metadata": {"name": "grace", "annotations": {"openshift.io/deletion-date": "2020-08-10T00:00:00Z"}}},
+			{"metadata": {"name": "invalid", "annotations": {"openshift.io/deletion-date": "tomorrow"}}},
+			{"metadata": {"name": "missing"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "url": server.URL, "token": "token"}})
+	defer config.Config().Set("openshift", nil)
+
+	projects, err := getProjectsScheduledForDeletion("c1", time.Date(2020, 8, 5, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(projects, []string{"expired"}) {
+		t.Errorf("expected only the expired project, got %v", projects)
+	}
+}
+
+func TestCleanupDeletedProjectVolumes(t *testing.T) {
+	config.Init("bla")
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/api/v1/persistentvolumes":
+			w.Write([]byte(`{"items": [
+				{"metadata": {"name": "gl-gone-pv1", "labels": {"openshift.io/deleted-project": "gone"}},
+				 "spec": {"glusterfs": {"path": "vol_gone_pv1"}}},
+				{"metadata": {"name": "gl-terminating-pv1", "labels": {"openshift.io/deleted-project": "terminating"}},
+				 "spec": {"glusterfs": {"path": "vol_terminating_pv1"}}}
+			]}`))
+		case "/api/v1/namespaces/gone":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{
+		{"id": "c1", "name": "C1", "url": server.URL, "token": "token",
+			"glusterapi": map[string]interface{}{"url": server.URL, "secret": "secret"}},
+	})
+	defer config.Config().Set("openshift", nil)
+
+	if err := cleanupDeletedProjectVolumes("c1"); err != nil {
+		t.Fatal(err)
+	}
+	all := strings.Join(requests, ",")
+	if !strings.Contains(all, "DELETE /api/v1/persistentvolumes/gl-gone-pv1") || !strings.Contains(all, "POST /sec/volume/delete") {
+		t.Errorf("expected the volume of the deleted project to be deleted, got %v", requests)
+	}
+	if strings.Contains(all, "gl-terminating-pv1") {
+		t.Errorf("expected the volume of the terminating project to be kept, got %v", requests)
+	}
+}
@@ -0,0 +1,459 @@
+package openshift
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultQuotaName      = "default-quota"
+	defaultLimitRangeName = "default-limits"
+)
+
+// The quota resources which can be managed by the users
+var quotaResources = []string{
+	"cpu",
+	"memory",
+	"requests.cpu",
+	"requests.memory",
+	"limits.cpu",
+	"limits.memory",
+	"pods",
+	"services",
+	"persistentvolumeclaims",
+	"requests.storage",
+	"requests.ephemeral-storage",
+	"limits.ephemeral-storage",
+}
+
+type quotaLimit struct {
+	Resource string
+	Max      string
+}
+
+func getProjectQuotasHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	quotas, err := getResourceQuotas(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	limitRanges, err := getLimitRanges(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	hard, used := aggregateQuotas(quotas)
+	c.JSON(http.StatusOK, common.ProjectQuotas{
+		Hard:     hard,
+		Used:     used,
+		Maximum:  getQuotaMaxima(),
+		Defaults: getLimitRangeDefaults(limitRanges),
+	})
+}
+
+func editProjectQuotasHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ProjectQuotasCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	data.Hard = normalizeQuotaResources(data.Hard)
+	if err := validateQuotaValues(data.Hard, getQuotaMaxima()); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if data.Defaults != nil {
+		if err := validateLimitRangeDefaults(*data.Defaults); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	if len(data.Hard) > 0 {
+		if err := updateProjectQuotas(data.ClusterId, username, data.Project, data.Hard); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+	if data.Defaults != nil {
+		if err := updateLimitRangeDefaults(data.ClusterId, username, data.Project, *data.Defaults); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The new quotas have been saved: Cluster %v, Project %v", data.ClusterId, data.Project),
+	})
+}
+
+// getQuotaMaxima returns the configured maximum per resource.
+// CPU and memory default to max_quota_cpu and max_quota_memory.
+func getQuotaMaxima() map[string]string {
+	cfg := config.Config()
+	maxima := map[string]string{}
+	if maxCPU := cfg.GetInt("max_quota_cpu"); maxCPU > 0 {
+		for _, r := range []string{"cpu", "requests.cpu", "limits.cpu"} {
+			maxima[r] = fmt.Sprint(maxCPU)
+		}
+	}
+	if maxMemory := cfg.GetInt("max_quota_memory"); maxMemory > 0 {
+		for _, r := range []string{"memory", "requests.memory", "limits.memory"} {
+			maxima[r] = fmt.Sprintf("%vGi", maxMemory)
+		}
+	}
+
+	limits := []quotaLimit{}
+	if err := cfg.UnmarshalKey("openshift_quota_limits", &limits); err != nil {
+		log.Printf("WARNING: Invalid openshift_quota_limits configuration: %v", err)
+	}
+	for _, l := range limits {
+		maxima[strings.ToLower(l.Resource)] = l.Max
+	}
+	return maxima
+}
+
+// normalizeQuotaResources returns a copy of the quotas with the
+// resource names in lower case, as they are listed in quotaResources
+func normalizeQuotaResources(hard map[string]string) map[string]string {
+	result := map[string]string{}
+	for resource, value := range hard {
+		result[strings.ToLower(resource)] = value
+	}
+	return result
+}
+
+// validateQuotaValues checks that only supported resources are set
+// and that the values don't exceed the maxima. Resources without
+// a configured maximum can't be changed.
+func validateQuotaValues(hard map[string]string, maxima map[string]string) error {
+	for resource, value := range hard {
+		resource = strings.ToLower(resource)
+		if !common.ContainsStringI(quotaResources, resource) {
+			return fmt.Errorf("The quota %v can't be changed. Supported are: %v", resource, strings.Join(quotaResources, ", "))
+		}
+		quantity, err := parseQuantity(value)
+		if err != nil {
+			return err
+		}
+		if quantity < 0 {
+			return fmt.Errorf("The quota %v must not be negative", resource)
+		}
+		max, ok := maxima[resource]
+		if !ok {
+			return fmt.Errorf("The quota %v can't be changed, because no maximum is configured", resource)
+		}
+		maxQuantity, err := parseQuantity(max)
+		if err != nil {
+			log.Printf("WARNING: Invalid maximum for quota %v: %v", resource, max)
+			return errors.New(common.ConfigNotSetError)
+		}
+		if quantity > maxQuantity {
+			return fmt.Errorf("The maximal value for %v: %v", resource, max)
+		}
+	}
+	return nil
+}
+
+// getResourceQuotas returns the ResourceQuotas of the project, which apply
+// to all pods. Quotas with scopes (e.g. BestEffort) are ignored.
+func getResourceQuotas(clusterId, project string) ([]*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project+"/resourcequotas", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	return getUnscopedQuotas(json.S("items").Children()), nil
+}
+
+func getUnscopedQuotas(items []*gabs.Container) []*gabs.Container {
+	quotas := []*gabs.Container{}
+	for _, q := range items {
+		if q.Exists("spec", "scopes") || q.Exists("spec", "scopeSelector") {
+			continue
+		}
+		quotas = append(quotas, q)
+	}
+	return quotas
+}
+
+// aggregateQuotas returns the effective hard quotas and the usage.
+// If multiple quotas define the same resource, the lowest value applies.
+// Every quota counts the usage of the whole project, so the highest usage is returned.
+func aggregateQuotas(quotas []*gabs.Container) (map[string]string, map[string]string) {
+	hard := map[string]string{}
+	used := map[string]string{}
+	for _, q := range quotas {
+		for resource, value := range q.S("spec", "hard").ChildrenMap() {
+			v := fmt.Sprint(value.Data())
+			if current, ok := hard[resource]; ok {
+				a, errA := parseQuantity(current)
+				b, errB := parseQuantity(v)
+				if errA != nil || errB != nil || a <= b {
+					continue
+				}
+			}
+			hard[resource] = v
+		}
+		for resource, value := range q.S("status", "used").ChildrenMap() {
+			v := fmt.Sprint(value.Data())
+			if current, ok := used[resource]; ok {
+				a, errA := parseQuantity(current)
+				b, errB := parseQuantity(v)
+				if errA != nil || errB != nil || a >= b {
+					continue
+				}
+			}
+			used[resource] = v
+		}
+	}
+	return hard, used
+}
+
+// planQuotaUpdates sets the values on the quotas. A resource is changed on
+// every quota defining it, otherwise it's added to the first quota.
+// If the project has no quota, a new one is returned.
+func planQuotaUpdates(quotas []*gabs.Container, hard map[string]string, project string) ([]*gabs.Container, *gabs.Container) {
+	var created *gabs.Container
+	if len(quotas) == 0 {
+		created = newObjectRequest("ResourceQuota", defaultQuotaName, "v1")
+		created.Set(project, "metadata", "namespace")
+		quotas = []*gabs.Container{created}
+	}
+
+	changed := map[int]bool{}
+	resources := make([]string, 0, len(hard))
+	for r := range hard {
+		resources = append(resources, r)
+	}
+	sort.Strings(resources)
+
+	for _, resource := range resources {
+		found := false
+		for i, q := range quotas {
+			if q.Exists("spec", "hard", resource) {
+				q.Set(hard[resource], "spec", "hard", resource)
+				changed[i] = true
+				found = true
+			}
+		}
+		if !found {
+			quotas[0].Set(hard[resource], "spec", "hard", resource)
+			changed[0] = true
+		}
+	}
+
+	if created != nil {
+		return nil, created
+	}
+	updated := []*gabs.Container{}
+	for i, q := range quotas {
+		if changed[i] {
+			updated = append(updated, q)
+		}
+	}
+	return updated, nil
+}
+
+func updateProjectQuotas(clusterId, username, project string, hard map[string]string) error {
+	quotas, err := getResourceQuotas(clusterId, project)
+	if err != nil {
+		return err
+	}
+
+	updated, created := planQuotaUpdates(quotas, hard, project)
+	if created != nil {
+		if err := saveObject(clusterId, "POST", "api/v1/namespaces/"+project+"/resourcequotas", created); err != nil {
+			return err
+		}
+	}
+	for _, q := range updated {
+		name, _ := q.Path("metadata.name").Data().(string)
+		if err := saveObject(clusterId, "PUT", "api/v1/namespaces/"+project+"/resourcequotas/"+name, q); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("User %v changed quotas for the project %v on cluster %v: %v", username, project, clusterId, hard)
+	return nil
+}
+
+func getLimitRanges(clusterId, project string) ([]*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+project+"/limitranges", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+	return json.S("items").Children(), nil
+}
+
+// getContainerLimit returns the index of the Container limit of the LimitRange or -1
+func getContainerLimit(limitRange *gabs.Container) int {
+	for i, l := range limitRange.S("spec", "limits").Children() {
+		if l.S("type").Data() == "Container" {
+			return i
+		}
+	}
+	return -1
+}
+
+func getLimitRangeDefaults(limitRanges []*gabs.Container) *common.LimitRangeDefaults {
+	for _, lr := range limitRanges {
+		i := getContainerLimit(lr)
+		if i < 0 {
+			continue
+		}
+		limit := lr.S("spec", "limits").Index(i)
+		value := func(path ...string) string {
+			if v := limit.Search(path...).Data(); v != nil {
+				return fmt.Sprint(v)
+			}
+			return ""
+		}
+		return &common.LimitRangeDefaults{
+			DefaultCPU:           value("default", "cpu"),
+			DefaultMemory:        value("default", "memory"),
+			DefaultRequestCPU:    value("defaultRequest", "cpu"),
+			DefaultRequestMemory: value("defaultRequest", "memory"),
+		}
+	}
+	return nil
+}
+
+func validateLimitRangeDefaults(defaults common.LimitRangeDefaults) error {
+	check := func(name, request, limit string) error {
+		var r, l float64
+		var err error
+		if request != "" {
+			if r, err = parseQuantity(request); err != nil {
+				return err
+			}
+		}
+		if limit != "" {
+			if l, err = parseQuantity(limit); err != nil {
+				return err
+			}
+		}
+		if request != "" && limit != "" && r > l {
+			return fmt.Errorf("The default request for %v must not be greater than the default limit", name)
+		}
+		return nil
+	}
+	if err := check("cpu", defaults.DefaultRequestCPU, defaults.DefaultCPU); err != nil {
+		return err
+	}
+	return check("memory", defaults.DefaultRequestMemory, defaults.DefaultMemory)
+}
+
+// setLimitRangeDefaults sets the defaults on the Container limit of the
+// LimitRange. Empty values are left unchanged.
+func setLimitRangeDefaults(limitRange *gabs.Container, defaults common.LimitRangeDefaults) {
+	i := getContainerLimit(limitRange)
+	if i < 0 {
+		limitRange.ArrayAppend(map[string]interface{}{"type": "Container"}, "spec", "limits")
+		i = len(limitRange.S("spec", "limits").Children()) - 1
+	}
+	limit := limitRange.S("spec", "limits").Index(i)
+	set := func(value string, path ...string) {
+		if value != "" {
+			limit.Set(value, path...)
+		}
+	}
+	set(defaults.DefaultCPU, "default", "cpu")
+	set(defaults.DefaultMemory, "default", "memory")
+	set(defaults.DefaultRequestCPU, "defaultRequest", "cpu")
+	set(defaults.DefaultRequestMemory, "defaultRequest", "memory")
+}
+
+func updateLimitRangeDefaults(clusterId, username, project string, defaults common.LimitRangeDefaults) error {
+	limitRanges, err := getLimitRanges(clusterId, project)
+	if err != nil {
+		return err
+	}
+
+	var limitRange *gabs.Container
+	for _, lr := range limitRanges {
+		if getContainerLimit(lr) >= 0 {
+			limitRange = lr
+			break
+		}
+	}
+
+	if limitRange == nil {
+		limitRange = newObjectRequest("LimitRange", defaultLimitRangeName, "v1")
+		limitRange.Set(project, "metadata", "namespace")
+		setLimitRangeDefaults(limitRange, defaults)
+		if err := saveObject(clusterId, "POST", "api/v1/namespaces/"+project+"/limitranges", limitRange); err != nil {
+			return err
+		}
+	} else {
+		setLimitRangeDefaults(limitRange, defaults)
+		name, _ := limitRange.Path("metadata.name").Data().(string)
+		if err := saveObject(clusterId, "PUT", "api/v1/namespaces/"+project+"/limitranges/"+name, limitRange); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("User %v changed the default limits for the project %v on cluster %v: %+v", username, project, clusterId, defaults)
+	return nil
+}
+
+// saveObject creates (POST) or replaces (PUT) an object
+func saveObject(clusterId, method, url string, object *gabs.Container) error {
+	resp, err := getOseHTTPClient(method, clusterId, url, bytes.NewReader(object.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error saving %v on cluster %v: %v %v", url, clusterId, resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	subjectKindUser           = "User"
+	subjectKindGroup          = "Group"
+	subjectKindServiceAccount = "ServiceAccount"
+)
+
+var defaultProjectRoles = []string{"view", "edit", "admin"}
+
+// getProjectRoleBindingsHandler returns all rolebindings of the project with their subjects
+func getProjectRoleBindingsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	rolebindings, err := getRoleBindings(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	result := []common.ProjectRoleBinding{}
+	for _, rb := range rolebindings {
+		result = append(result, parseProjectRoleBinding(rb))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	c.JSON(http.StatusOK, result)
+}
+
+func addProjectRoleHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ProjectRoleCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateProjectRoleCommand(data.Role, data.Kind, data.Name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if data.Kind == subjectKindGroup {
+		var err error
+		if data.Role == "admin" {
+			err = validateAdminGroupGrant(data.ClusterId, username, data.Name)
+		} else {
+			err = validateLdapGroup(data.Name)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	var err error
+	switch {
+	case data.Role == "admin" && data.Kind == subjectKindGroup:
+		err = changeProjectGroupPermission(data.ClusterId, data.Project, data.Name, true)
+	case data.Role == "admin":
+		err = changeProjectPermission(data.ClusterId, data.Project, data.Name)
+	default:
+		err = addProjectRoleSubject(data.ClusterId, data.Project, data.Role, data.Kind, data.Name)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v gave the role %v to %v %v in project %v on cluster %v", username, data.Role, data.Kind, data.Name, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The %v %v has now the role %v in the project %v", strings.ToLower(data.Kind), data.Name, data.Role, data.Project),
+	})
+}
+
+func removeProjectRoleHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+	role := params.Get("role")
+	kind := params.Get("kind")
+	name := params.Get("name")
+
+	if err := validateProjectRoleCommand(role, kind, name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	var err error
+	switch {
+	case role == "admin" && kind == subjectKindGroup:
+		err = changeProjectGroupPermission(clusterId, project, name, false)
+	case role == "admin":
+		// The last admin can't be removed
+		err = removeProjectAdmin(clusterId, project, name)
+	default:
+		err = removeProjectRoleSubject(clusterId, project, role, kind, name)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v removed the role %v from %v %v in project %v on cluster %v", username, role, kind, name, project, clusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The role %v has been removed from the %v %v in the project %v", role, strings.ToLower(kind), name, project),
+	})
+}
+
+// getAllowedProjectRoles returns view, edit, admin and the ClusterRoles from openshift_project_roles
+func getAllowedProjectRoles() []string {
+	roles := append([]string{}, defaultProjectRoles...)
+	return append(roles, config.Config().GetStringSlice("openshift_project_roles")...)
+}
+
+func validateProjectRoleCommand(role, kind, name string) error {
+	if name == "" {
+		return errors.New("Name must be provided")
+	}
+	if kind != subjectKindUser && kind != subjectKindGroup {
+		return fmt.Errorf("Invalid kind %v. Allowed kinds: %v, %v", kind, subjectKindUser, subjectKindGroup)
+	}
+	if !contains(getAllowedProjectRoles(), role) {
+		return fmt.Errorf("The role %v is not allowed. Allowed roles: %v", role, strings.Join(getAllowedProjectRoles(), ", "))
+	}
+	return nil
+}
+
+func parseProjectRoleBinding(rb *gabs.Container) common.ProjectRoleBinding {
+	name, _ := rb.Path("metadata.name").Data().(string)
+	role, _ := rb.Path("roleRef.name").Data().(string)
+	roleKind, _ := rb.Path("roleRef.kind").Data().(string)
+	binding := common.ProjectRoleBinding{
+		Name:     name,
+		Role:     role,
+		RoleKind: roleKind,
+		Subjects: []common.RoleBindingSubject{},
+	}
+	for _, s := range rb.S("subjects").Children() {
+		kind, _ := s.S("kind").Data().(string)
+		subjectName, _ := s.S("name").Data().(string)
+		namespace, _ := s.S("namespace").Data().(string)
+		binding.Subjects = append(binding.Subjects, common.RoleBindingSubject{
+			Kind:      kind,
+			Name:      subjectName,
+			Namespace: namespace,
+		})
+	}
+	return binding
+}
+
+func getRoleBindingsURL(project string) string {
+	return "apis/rbac.authorization.k8s.io/v1/namespaces/" + project + "/rolebindings"
+}
+
+func getRoleBindings(clusterId, project string) ([]*gabs.Container, error) {
+	return getProjectObjects(clusterId, getRoleBindingsURL(project))
+}
+
+// saveRoleBinding updates the existing rolebinding
+func saveRoleBinding(clusterId, project string, rb *gabs.Container) error {
+	name, _ := rb.Path("metadata.name").Data().(string)
+	return saveObject(clusterId, "PUT", getRoleBindingsURL(project)+"/"+name, rb)
+}
+
+// bindsClusterRole checks if the rolebinding grants the ClusterRole.
+// Local roles with the same name grant other permissions.
+func bindsClusterRole(rb *gabs.Container, role string) bool {
+	return rb.Path("roleRef.kind").Data() == "ClusterRole" && rb.Path("roleRef.name").Data() == role
+}
+
+// newRoleSubjects returns the rbac subjects. Like the admins,
+// users are added in lower- and uppercase.
+func newRoleSubjects(project, kind, name string) []OpenshiftSubject {
+	switch kind {
+	case subjectKindGroup:
+		return []OpenshiftSubject{{ApiGroup: "rbac.authorization.k8s.io", Kind: kind, Name: name}}
+	case subjectKindServiceAccount:
+		return []OpenshiftSubject{{Kind: kind, Name: name, Namespace: project}}
+	}
+	return []OpenshiftSubject{
+		{ApiGroup: "rbac.authorization.k8s.io", Kind: kind, Name: strings.ToLower(name)},
+		{ApiGroup: "rbac.authorization.k8s.io", Kind: kind, Name: strings.ToUpper(name)},
+	}
+}
+
+// isRoleSubject compares the names case insensitive.
+// Service accounts must belong to the project.
+func isRoleSubject(subject *gabs.Container, project, kind, name string) bool {
+	k, _ := subject.S("kind").Data().(string)
+	n, _ := subject.S("name").Data().(string)
+	if k != kind || strings.ToLower(n) != strings.ToLower(name) {
+		return false
+	}
+	if kind == subjectKindServiceAccount {
+		namespace, _ := subject.S("namespace").Data().(string)
+		return namespace == "" || namespace == project
+	}
+	return true
+}
+
+func hasRoleSubject(rb *gabs.Container, project, kind, name string) bool {
+	for _, s := range rb.S("subjects").Children() {
+		if isRoleSubject(s, project, kind, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutRoleSubject returns the subjects of the rolebinding without the user, group or service account
+func withoutRoleSubject(rb *gabs.Container, project, kind, name string) *gabs.Container {
+	subjects, _ := gabs.New().Array()
+	for _, s := range rb.S("subjects").Children() {
+		if isRoleSubject(s, project, kind, name) {
+			continue
+		}
+		subjects.ArrayAppend(s.Data())
+	}
+	return subjects
+}
+
+// getServiceAccountRoles returns the roles of the service accounts of the project
+func getServiceAccountRoles(rolebindings []*gabs.Container, project string) map[string][]string {
+	roles := map[string][]string{}
+	for _, rb := range rolebindings {
+		role, _ := rb.Path("roleRef.name").Data().(string)
+		for _, s := range rb.S("subjects").Children() {
+			name, _ := s.S("name").Data().(string)
+			if isRoleSubject(s, project, subjectKindServiceAccount, name) && !common.ContainsStringI(roles[name], role) {
+				roles[name] = append(roles[name], role)
+			}
+		}
+	}
+	return roles
+}
+
+// addProjectRoleSubject adds the user, group or service account to the rolebinding,
+// which has the name of the role. It is created if it doesn't exist.
+func addProjectRoleSubject(clusterId, project, role, kind, name string) error {
+	rolebindings, err := getRoleBindings(clusterId, project)
+	if err != nil {
+		return err
+	}
+
+	var existing *gabs.Container
+	for _, rb := range rolebindings {
+		rbName, _ := rb.Path("metadata.name").Data().(string)
+		if !bindsClusterRole(rb, role) {
+			if rbName == role {
+				return fmt.Errorf("The rolebinding %v doesn't bind the ClusterRole %v", rbName, role)
+			}
+			continue
+		}
+		if hasRoleSubject(rb, project, kind, name) {
+			return fmt.Errorf("The %v %v has already the role %v", strings.ToLower(kind), name, role)
+		}
+		if rbName == role {
+			existing = rb
+		}
+	}
+
+	if existing == nil {
+		rolebinding := newObjectRequest("RoleBinding", role, "rbac.authorization.k8s.io/v1")
+		rolebinding.Set("rbac.authorization.k8s.io", "roleRef", "apiGroup")
+		rolebinding.Set("ClusterRole", "roleRef", "kind")
+		rolebinding.Set(role, "roleRef", "name")
+		rolebinding.Array("subjects")
+		for _, s := range newRoleSubjects(project, kind, name) {
+			rolebinding.ArrayAppend(s, "subjects")
+		}
+		return saveObject(clusterId, "POST", getRoleBindingsURL(project), rolebinding)
+	}
+
+	if !existing.Exists("subjects") {
+		existing.Array("subjects")
+	}
+	for _, s := range newRoleSubjects(project, kind, name) {
+		existing.ArrayAppend(s, "subjects")
+	}
+	return saveRoleBinding(clusterId, project, existing)
+}
+
+// removeProjectRoleSubject removes the user or group from all rolebindings of the role
+func removeProjectRoleSubject(clusterId, project, role, kind, name string) error {
+	rolebindings, err := getRoleBindings(clusterId, project)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, rb := range rolebindings {
+		if !bindsClusterRole(rb, role) || !hasRoleSubject(rb, project, kind, name) {
+			continue
+		}
+		found = true
+		rb.Set(withoutRoleSubject(rb, project, kind, name).Data(), "subjects")
+		if err := saveRoleBinding(clusterId, project, rb); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("The %v %v doesn't have the role %v", strings.ToLower(kind), name, role)
+	}
+	return nil
+}
+
+// removeServiceAccountFromRoleBindings removes the service account from the rolebindings of all roles
+func removeServiceAccountFromRoleBindings(clusterId, project, serviceaccount string) error {
+	rolebindings, err := getRoleBindings(clusterId, project)
+	if err != nil {
+		return err
+	}
+	for _, rb := range rolebindings {
+		if !hasRoleSubject(rb, project, subjectKindServiceAccount, serviceaccount) {
+			continue
+		}
+		rb.Set(withoutRoleSubject(rb, project, subjectKindServiceAccount, serviceaccount).Data(), "subjects")
+		if err := saveRoleBinding(clusterId, project, rb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package openshift
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestValidateProjectRoleCommand(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("openshift_project_roles", []string{"monitoring-edit"})
+	defer config.Config().Set("openshift_project_roles", nil)
+
+	var tests = []struct {
+		role  string
+		kind  string
+		name  string
+		valid bool
+	}{
+		{"view", "User", "u1", true},
+		{"edit", "Group", "team", true},
+		{"admin", "User", "u1", true},
+		{"monitoring-edit", "Group", "team", true},
+		{"cluster-admin", "User", "u1", false},
+		{"view", "ServiceAccount", "builder", false},
+		{"view", "user", "u1", false},
+		{"view", "User", "", false},
+	}
+	for _, test := range tests {
+		err := validateProjectRoleCommand(test.role, test.kind, test.name)
+		if (err == nil) != test.valid {
+			t.Errorf("validateProjectRoleCommand(%v, %v, %v): expected valid=%v, got %v", test.role, test.kind, test.name, test.valid, err)
+		}
+	}
+}
+
+func TestParseProjectRoleBinding(t *testing.T) {
+	rb, _ := gabs.ParseJSON([]byte(`{
+		"metadata": {"name": "edit"},
+		"roleRef": {"apiGroup": "rbac.authorization.k8s.io", "kind": "ClusterRole", "name": "edit"},
+		"subjects": [
+			{"kind": "User", "name": "u1"},
+			{"kind": "ServiceAccount", "name": "jenkins", "namespace": "app"}
+		]
+	}`))
+
+	binding := parseProjectRoleBinding(rb)
+	if binding.Name != "edit" || binding.Role != "edit" || binding.RoleKind != "ClusterRole" {
+		t.Errorf("unexpected rolebinding: %+v", binding)
+	}
+	if len(binding.Subjects) != 2 || binding.Subjects[1].Kind != "ServiceAccount" || binding.Subjects[1].Namespace != "app" {
+		t.Errorf("unexpected subjects: %+v", binding.Subjects)
+	}
+
+	empty, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "view"}, "roleRef": {"name": "view"}}`))
+	if subjects := parseProjectRoleBinding(empty).Subjects; subjects == nil || len(subjects) != 0 {
+		t.Errorf("expected empty subjects, got %v", subjects)
+	}
+}
+
+func TestWithoutRoleSubject(t *testing.T) {
+	rb, _ := gabs.ParseJSON([]byte(`{
+		"subjects": [
+			{"kind": "User", "name": "u1"},
+			{"kind": "User", "name": "U1"},
+			{"kind": "Group", "name": "u1"},
+			{"kind": "User", "name": "u2"},
+			{"kind": "ServiceAccount", "name": "deployer", "namespace": "other"}
+		]
+	}`))
+
+	if !hasRoleSubject(rb, "app", "User", "U1") || hasRoleSubject(rb, "app", "Group", "u2") {
+		t.Error("unexpected result of hasRoleSubject")
+	}
+	if hasRoleSubject(rb, "app", "ServiceAccount", "deployer") || !hasRoleSubject(rb, "other", "ServiceAccount", "deployer") {
+		t.Error("expected service accounts to be compared with their namespace")
+	}
+
+	subjects := withoutRoleSubject(rb, "app", "User", "u1").Children()
+	if len(subjects) != 3 {
+		t.Fatalf("expected 3 remaining subjects, got %v", len(subjects))
+	}
+	if subjects[0].S("kind").Data() != "Group" || subjects[1].S("name").Data() != "u2" {
+		t.Errorf("unexpected remaining subjects: %v", subjects)
+	}
+}
+
+func TestNewRoleSubjects(t *testing.T) {
+	users := newRoleSubjects("app", "User", "User1")
+	if len(users) != 2 || users[0].Name != "user1" || users[1].Name != "USER1" {
+		t.Errorf("expected the user in lower- and uppercase, got %v", users)
+	}
+	groups := newRoleSubjects("app", "Group", "Team-A")
+	if len(groups) != 1 || groups[0].Name != "Team-A" || groups[0].ApiGroup != "rbac.authorization.k8s.io" {
+		t.Errorf("unexpected group subject %v", groups)
+	}
+	serviceaccounts := newRoleSubjects("app", "ServiceAccount", "deployer")
+	if len(serviceaccounts) != 1 || serviceaccounts[0].Name != "deployer" || serviceaccounts[0].Namespace != "app" {
+		t.Errorf("unexpected service account subject %v", serviceaccounts)
+	}
+}
+
+func TestGetServiceAccountRoles(t *testing.T) {
+	json, _ := gabs.ParseJSON([]byte(`{"items": [
+		{"roleRef": {"name": "edit"}, "subjects": [
+			{"kind": "ServiceAccount", "name": "deployer", "namespace": "project"},
+			{"kind": "ServiceAccount", "name": "other", "namespace": "other-project"},
+			{"kind": "User", "name": "u1"}]},
+		{"roleRef": {"name": "view"}, "subjects": [
+			{"kind": "ServiceAccount", "name": "deployer", "namespace": "project"},
+			{"kind": "ServiceAccount", "name": "monitoring"}]},
+		{"roleRef": {"name": "admin"}}
+	]}`))
+
+	roles := getServiceAccountRoles(json.S("items").Children(), "project")
+	expected := map[string][]string{
+		"deployer":   {"edit", "view"},
+		"monitoring": {"view"},
+	}
+	if !reflect.DeepEqual(roles, expected) {
+		t.Errorf("Expected %v, got %v", expected, roles)
+	}
+}
+
+func TestAddProjectRoleSubject(t *testing.T) {
+	config.Init("bla")
+
+	saved := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" || r.Method == "POST" {
+			body, _ := ioutil.ReadAll(r.Body)
+			saved[r.Method+" "+r.URL.Path] = string(body)
+			w.Write(body)
+			return
+		}
+		w.Write([]byte(`{"items": [
+			{"metadata": {"name": "view"}, "roleRef": {"kind": "ClusterRole", "name": "view"}, "subjects": [
+				{"kind": "ServiceAccount", "name": "deployer", "namespace": "app"}]},
+			{"metadata": {"name": "edit"}, "roleRef": {"kind": "Role", "name": "edit"}, "subjects": [
+				{"kind": "User", "name": "u1"}]},
+			{"metadata": {"name": "local-admin"}, "roleRef": {"kind": "Role", "name": "admin"}, "subjects": [
+				{"kind": "User", "name": "u1"}]}
+		]}`))
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "url": server.URL, "token": "token"}})
+	defer config.Config().Set("openshift", nil)
+
+	url := "/apis/rbac.authorization.k8s.io/v1/namespaces/app/rolebindings"
+	if err := addProjectRoleSubject("c1", "app", "view", subjectKindServiceAccount, "deployer"); err == nil {
+		t.Error("expected an error for an existing subject")
+	}
+	if err := addProjectRoleSubject("c1", "app", "view", subjectKindServiceAccount, "builder"); err != nil {
+		t.Fatal(err)
+	}
+	if view := saved["PUT "+url+"/view"]; !strings.Contains(view, "builder") || !strings.Contains(view, "deployer") {
+		t.Errorf("expected the service account to be added to the rolebinding view, got %v", saved)
+	}
+	if err := addProjectRoleSubject("c1", "app", "edit", subjectKindUser, "u2"); err == nil {
+		t.Error("expected an error if the rolebinding binds a local role")
+	}
+	if err := removeProjectRoleSubject("c1", "app", "admin", subjectKindUser, "u1"); err == nil {
+		t.Error("expected the rolebinding of a local role to be ignored")
+	}
+
+	saved = map[string]string{}
+	if err := addProjectRoleSubject("c1", "app", "admin", subjectKindUser, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if admin := saved["POST "+url]; !strings.Contains(admin, `"ClusterRole"`) || !strings.Contains(admin, "U1") {
+		t.Errorf("expected a new rolebinding for the ClusterRole admin, got %v", saved)
+	}
+}
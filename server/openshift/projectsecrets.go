@@ -0,0 +1,374 @@
+package openshift
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	secretTypeOpaque = "Opaque"
+	secretTypeTLS    = "kubernetes.io/tls"
+)
+
+var secretKeyRegex = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+func getProjectSecretsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	secrets, err := getProjectSecrets(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, secrets)
+}
+
+func newProjectSecretHandler(c *gin.Context) {
+	saveProjectSecretHandler(c, false)
+}
+
+func updateProjectSecretHandler(c *gin.Context) {
+	saveProjectSecretHandler(c, true)
+}
+
+// saveProjectSecretHandler creates (update=false) or replaces the secret
+func saveProjectSecretHandler(c *gin.Context, update bool) {
+	username := common.GetUserName(c)
+
+	var data common.ProjectSecretCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	secret, err := newProjectSecret(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if update {
+		if err := validateSecretUpdate(data.ClusterId, data.Project, data.Name, data.Type); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		err = replaceSecret(data.ClusterId, data.Project, data.Name, secret)
+	} else {
+		err = createSecret(data.ClusterId, data.Project, secret)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v saved the secret %v (%v) in project %v on cluster %v", username, data.Name, data.Type, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The secret %v has been saved", data.Name),
+	})
+}
+
+func deleteProjectSecretHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+	name := params.Get("name")
+
+	if name == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Name must be provided"})
+		return
+	}
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	// Service account tokens and pull secrets can't be deleted here
+	if _, err := getProjectSecret(clusterId, project, name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := deleteSecret(clusterId, project, name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v deleted the secret %v in project %v on cluster %v", username, name, project, clusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The secret %v has been deleted", name),
+	})
+}
+
+// getCertificateExpiryHandler returns the TLS secrets of all projects
+// the user administers, ordered by expiry
+func getCertificateExpiryHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	if clusterId == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Cluster must be provided"})
+		return
+	}
+
+	projects, err := getAdministeredProjects(clusterId, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/secrets?fieldSelector=type="+secretTypeTLS, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: genericAPIError})
+		return
+	}
+
+	c.JSON(http.StatusOK, getCertificateExpiry(json.S("items").Children(), projects, time.Now()))
+}
+
+func getCertificateExpiry(secrets []*gabs.Container, projects []string, now time.Time) []common.CertificateExpiry {
+	result := []common.CertificateExpiry{}
+	for _, s := range secrets {
+		namespace, _ := s.Path("metadata.namespace").Data().(string)
+		if !common.ContainsStringI(projects, namespace) {
+			continue
+		}
+		secret := parseProjectSecret(s)
+		if secret.Certificate == nil {
+			continue
+		}
+		result = append(result, common.CertificateExpiry{
+			Project:         namespace,
+			Secret:          secret.Name,
+			DaysLeft:        int(math.Floor(secret.Certificate.NotAfter.Sub(now).Hours() / 24)),
+			CertificateInfo: *secret.Certificate,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NotAfter.Before(result[j].NotAfter) })
+	return result
+}
+
+// newProjectSecret validates the command and creates the secret object
+func newProjectSecret(data common.ProjectSecretCommand) (*gabs.Container, error) {
+	if !secretNameRegex.MatchString(data.Name) || len(data.Name) > 253 {
+		return nil, fmt.Errorf("Invalid secret name: %v", data.Name)
+	}
+
+	values := map[string][]byte{}
+	switch data.Type {
+	case secretTypeOpaque:
+		if len(data.Data) == 0 {
+			return nil, errors.New("Data must be provided")
+		}
+		for k, v := range data.Data {
+			if !secretKeyRegex.MatchString(k) {
+				return nil, fmt.Errorf("Invalid key: %v", k)
+			}
+			values[k] = []byte(v)
+		}
+	case secretTypeTLS:
+		if _, err := validateCertificate(data.Certificate, data.Key, time.Now()); err != nil {
+			return nil, err
+		}
+		values["tls.crt"] = []byte(data.Certificate)
+		values["tls.key"] = []byte(data.Key)
+	default:
+		return nil, fmt.Errorf("Unsupported secret type: %v. Allowed types: %v, %v", data.Type, secretTypeOpaque, secretTypeTLS)
+	}
+
+	secret := newObjectRequest("Secret", data.Name, "v1")
+	secret.Set(data.Type, "type")
+	// byte arrays are marshalled to base64
+	secret.Set(values, "data")
+	return secret, nil
+}
+
+// validateSecretUpdate checks that the secret is managed here.
+// Only secrets managed here can be replaced.
+func validateSecretUpdate(clusterId, project, name, secretType string) error {
+	existing, err := getProjectSecret(clusterId, project, name)
+	if err != nil {
+		return err
+	}
+	return validateSecretType(*existing, secretType)
+}
+
+// validateSecretType checks that the type of an existing secret isn't changed.
+// Kubernetes doesn't allow it, the secret must be deleted and created again.
+func validateSecretType(existing common.ProjectSecret, secretType string) error {
+	if existing.Type != secretType {
+		return fmt.Errorf("The type of the secret %v can't be changed from %v to %v. Delete the secret and create it again.", existing.Name, existing.Type, secretType)
+	}
+	return nil
+}
+
+// validateCertificate checks that the key matches the certificate, the chain
+// is ordered from the leaf to the issuers and the certificates are valid
+func validateCertificate(certificate, key string, now time.Time) (*common.CertificateInfo, error) {
+	if certificate == "" || key == "" {
+		return nil, errors.New("Certificate and key must be provided")
+	}
+
+	chain, err := parseCertificateChain([]byte(certificate))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tls.X509KeyPair([]byte(certificate), []byte(key)); err != nil {
+		return nil, fmt.Errorf("The key doesn't match the certificate: %v", err)
+	}
+
+	for i := 1; i < len(chain); i++ {
+		if err := chain[i-1].CheckSignatureFrom(chain[i]); err != nil {
+			return nil, fmt.Errorf("Certificate %v of the chain is not signed by the next certificate. The chain must start with the server certificate followed by its issuers", i)
+		}
+	}
+
+	for _, cert := range chain {
+		if now.After(cert.NotAfter) {
+			return nil, fmt.Errorf("The certificate %v expired on %v", cert.Subject.CommonName, cert.NotAfter.Format("02.01.2006"))
+		}
+		if now.Before(cert.NotBefore) {
+			return nil, fmt.Errorf("The certificate %v is not valid before %v", cert.Subject.CommonName, cert.NotBefore.Format("02.01.2006"))
+		}
+	}
+
+	return getCertificateInfo(chain[0]), nil
+}
+
+func parseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid certificate: %v", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("No PEM encoded certificate found")
+	}
+	return chain, nil
+}
+
+func getCertificateInfo(cert *x509.Certificate) *common.CertificateInfo {
+	dnsNames := cert.DNSNames
+	if dnsNames == nil {
+		dnsNames = []string{}
+	}
+	return &common.CertificateInfo{
+		Subject:   cert.Subject.CommonName,
+		Issuer:    cert.Issuer.CommonName,
+		DNSNames:  dnsNames,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}
+}
+
+// getProjectSecrets returns the Opaque and TLS secrets of the project without their values
+func getProjectSecrets(clusterId, namespace string) ([]common.ProjectSecret, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces/"+namespace+"/secrets", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	secrets := []common.ProjectSecret{}
+	for _, s := range json.S("items").Children() {
+		t, _ := s.S("type").Data().(string)
+		if t != secretTypeOpaque && t != secretTypeTLS {
+			continue
+		}
+		secrets = append(secrets, parseProjectSecret(s))
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	return secrets, nil
+}
+
+func getProjectSecret(clusterId, namespace, name string) (*common.ProjectSecret, error) {
+	secrets, err := getProjectSecrets(clusterId, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range secrets {
+		if s.Name == name {
+			return &s, nil
+		}
+	}
+	return nil, fmt.Errorf("The secret %v doesn't exist or is not an Opaque or TLS secret", name)
+}
+
+func parseProjectSecret(s *gabs.Container) common.ProjectSecret {
+	name, _ := s.Path("metadata.name").Data().(string)
+	t, _ := s.S("type").Data().(string)
+	secret := common.ProjectSecret{Name: name, Type: t, Keys: []string{}}
+	for k := range s.S("data").ChildrenMap() {
+		secret.Keys = append(secret.Keys, k)
+	}
+	sort.Strings(secret.Keys)
+
+	if t == secretTypeTLS {
+		encoded, _ := s.Path("data").S("tls.crt").Data().(string)
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			log.Printf("Error decoding certificate of secret %v: %v", name, err)
+			return secret
+		}
+		chain, err := parseCertificateChain(decoded)
+		if err != nil {
+			log.Printf("Error parsing certificate of secret %v: %v", name, err)
+			return secret
+		}
+		secret.Certificate = getCertificateInfo(chain[0])
+	}
+	return secret
+}
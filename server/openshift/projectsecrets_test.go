@@ -0,0 +1,138 @@
+package openshift
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  string
+}
+
+func newTestCertificate(t *testing.T, name string, notAfter time.Time, parent *testCertificate) testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+	}
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		template.DNSNames = []string{name, "www." + name}
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return testCertificate{cert, key, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+func keyPEM(t *testing.T, key *ecdsa.PrivateKey) string {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+func TestValidateCertificate(t *testing.T) {
+	now := time.Now()
+	ca := newTestCertificate(t, "Test CA", now.AddDate(1, 0, 0), nil)
+	leaf := newTestCertificate(t, "app.example.com", now.AddDate(0, 3, 0), &ca)
+	other := newTestCertificate(t, "other.example.com", now.AddDate(0, 3, 0), &ca)
+
+	var tests = []struct {
+		description string
+		certificate string
+		key         string
+		now         time.Time
+		valid       bool
+	}{
+		{"leaf", leaf.pem, keyPEM(t, leaf.key), now, true},
+		{"chain", leaf.pem + ca.pem, keyPEM(t, leaf.key), now, true},
+		{"wrong chain order", ca.pem + leaf.pem, keyPEM(t, leaf.key), now, false},
+		{"wrong key", leaf.pem, keyPEM(t, other.key), now, false},
+		{"expired", leaf.pem, keyPEM(t, leaf.key), now.AddDate(0, 4, 0), false},
+		{"no certificate", "", keyPEM(t, leaf.key), now, false},
+	}
+	for _, test := range tests {
+		info, err := validateCertificate(test.certificate, test.key, test.now)
+		if test.valid && err != nil {
+			t.Errorf("%v: expected to be valid, got %v", test.description, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%v: expected to be invalid", test.description)
+		}
+		if test.valid && (info.Subject != "app.example.com" || len(info.DNSNames) != 2) {
+			t.Errorf("%v: unexpected certificate info %+v", test.description, info)
+		}
+	}
+}
+
+func TestGetCertificateExpiry(t *testing.T) {
+	now := time.Now()
+	ca := newTestCertificate(t, "Test CA", now.AddDate(1, 0, 0), nil)
+	soon := newTestCertificate(t, "soon.example.com", now.AddDate(0, 0, 10).Add(time.Hour), &ca)
+	later := newTestCertificate(t, "later.example.com", now.AddDate(0, 0, 60).Add(time.Hour), &ca)
+
+	newSecret := func(namespace, name string, cert testCertificate) *gabs.Container {
+		secret, _ := gabs.ParseJSON(newObjectRequest("Secret", name, "v1").Bytes())
+		secret.Set(namespace, "metadata", "namespace")
+		secret.Set(secretTypeTLS, "type")
+		secret.Set(base64.StdEncoding.EncodeToString([]byte(cert.pem)), "data", "tls.crt")
+		return secret
+	}
+	secrets := []*gabs.Container{
+		newSecret("project-a", "later", later),
+		newSecret("project-b", "soon", soon),
+		newSecret("foreign", "foreign", soon),
+	}
+
+	expiry := getCertificateExpiry(secrets, []string{"project-a", "project-b"}, now)
+	expected := []common.CertificateExpiry{
+		{Project: "project-b", Secret: "soon", DaysLeft: 10},
+		{Project: "project-a", Secret: "later", DaysLeft: 60},
+	}
+	if len(expiry) != len(expected) {
+		t.Fatalf("Expected %v certificates, got %v", len(expected), len(expiry))
+	}
+	for i, e := range expected {
+		if expiry[i].Project != e.Project || expiry[i].Secret != e.Secret || expiry[i].DaysLeft != e.DaysLeft {
+			t.Errorf("Expected %v/%v with %v days left, got %v/%v with %v", e.Project, e.Secret, e.DaysLeft, expiry[i].Project, expiry[i].Secret, expiry[i].DaysLeft)
+		}
+	}
+}
+
+func TestValidateSecretType(t *testing.T) {
+	existing := common.ProjectSecret{Name: "app-tls", Type: secretTypeTLS}
+	if err := validateSecretType(existing, secretTypeTLS); err != nil {
+		t.Errorf("expected the same type to be valid, got %v", err)
+	}
+	if err := validateSecretType(existing, secretTypeOpaque); err == nil || !strings.Contains(err.Error(), "can't be changed") {
+		t.Errorf("expected a type change to be refused, got %v", err)
+	}
+}
@@ -1,11 +1,12 @@
 package openshift
 
 import (
-	"bytes"
 	"errors"
-	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"fmt"
 
@@ -53,7 +54,11 @@ func getQuotas(clusterId, project string) (*gabs.Container, error) {
 		return nil, errors.New(genericAPIError)
 	}
 
-	return json.S("items").Index(0), nil
+	quotas := getUnscopedQuotas(json.S("items").Children())
+	if len(quotas) == 0 {
+		return gabs.New(), nil
+	}
+	return quotas[0], nil
 }
 
 func editQuotasHandler(c *gin.Context) {
@@ -80,15 +85,6 @@ func editQuotasHandler(c *gin.Context) {
 }
 
 func validateEditQuotas(clusterId, username, project string, cpu int, memory int) error {
-	cfg := config.Config()
-	maxCPU := cfg.GetInt("max_quota_cpu")
-	maxMemory := cfg.GetInt("max_quota_memory")
-
-	if maxCPU == 0 || maxMemory == 0 {
-		log.Println("WARNING: Env variables 'MAX_QUOTA_MEMORY' and 'MAX_QUOTA_CPU' must be specified and valid integers")
-		return errors.New(common.ConfigNotSetError)
-	}
-
 	// Validate user input
 	if clusterId == "" {
 		return errors.New("Cluster must be provided")
@@ -98,6 +94,25 @@ func validateEditQuotas(clusterId, username, project string, cpu int, memory int
 		return errors.New("Project must be provided")
 	}
 
+	if err := validateQuotaLimits(cpu, memory); err != nil {
+		return err
+	}
+
+	// Validate permissions
+	resp := checkAdminPermissions(clusterId, username, project)
+	return resp
+}
+
+func validateQuotaLimits(cpu int, memory int) error {
+	cfg := config.Config()
+	maxCPU := cfg.GetInt("max_quota_cpu")
+	maxMemory := cfg.GetInt("max_quota_memory")
+
+	if maxCPU == 0 || maxMemory == 0 {
+		log.Println("WARNING: Env variables 'MAX_QUOTA_MEMORY' and 'MAX_QUOTA_CPU' must be specified and valid integers")
+		return errors.New(common.ConfigNotSetError)
+	}
+
 	if cpu > maxCPU {
 		return fmt.Errorf("The maximal value for CPU cores: %v", maxCPU)
 	}
@@ -106,33 +121,55 @@ func validateEditQuotas(clusterId, username, project string, cpu int, memory int
 		return fmt.Errorf("The maximal value for memory: %v", maxMemory)
 	}
 
-	// Validate permissions
-	resp := checkAdminPermissions(clusterId, username, project)
-	return resp
+	return nil
 }
 
 func updateQuotas(clusterId, username, project string, cpu int, memory int) error {
-	quotas, err := getQuotas(clusterId, project)
-	if err != nil {
-		return err
-	}
-	quotas.SetP(cpu, "spec.hard.cpu")
-	quotas.SetP(fmt.Sprintf("%vGi", memory), "spec.hard.memory")
+	return updateProjectQuotas(clusterId, username, project, map[string]string{
+		"cpu":    fmt.Sprint(cpu),
+		"memory": fmt.Sprintf("%vGi", memory),
+	})
+}
+
+// getQuotaValues returns the cpu cores and the memory in Gi of a ResourceQuota
+func getQuotaValues(quotas *gabs.Container) (int, int) {
+	cpu, _ := parseQuantity(fmt.Sprint(quotas.Path("spec.hard.cpu").Data()))
+	memory, _ := parseQuantity(fmt.Sprint(quotas.Path("spec.hard.memory").Data()))
+	return int(cpu), int(memory / (1 << 30))
+}
+
+var quantitySuffixes = map[string]float64{
+	"n":  1e-9,
+	"u":  1e-6,
+	"m":  1e-3,
+	"k":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"P":  1e15,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+}
 
-	resp, err := getOseHTTPClient("PUT",
-		clusterId,
-		"api/v1/namespaces/"+project+"/resourcequotas/"+quotas.Path("metadata.name").Data().(string),
-		bytes.NewReader(quotas.Bytes()))
+// parseQuantity parses a Kubernetes quantity (e.g. 500m, 4, 512Mi or 8Gi)
+// and returns the value in the base unit (cores or bytes)
+func parseQuantity(quantity string) (float64, error) {
+	quantity = strings.TrimSpace(quantity)
+	number := strings.TrimRightFunc(quantity, unicode.IsLetter)
+	suffix := quantity[len(number):]
+	value, err := strconv.ParseFloat(number, 64)
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("Invalid quantity: %v", quantity)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		errMsg, _ := ioutil.ReadAll(resp.Body)
-		log.Println("Error updating resourceQuota:", resp.StatusCode, string(errMsg))
-		return errors.New(genericAPIError)
+	if suffix == "" {
+		return value, nil
 	}
-	log.Printf("User %v changed quotas for the project %v on cluster %v. CPU: %v Mem: %v", username, clusterId, project, cpu, memory)
-	return nil
+	multiplier, ok := quantitySuffixes[suffix]
+	if !ok {
+		return 0, fmt.Errorf("Invalid quantity: %v", quantity)
+	}
+	return value * multiplier, nil
 }
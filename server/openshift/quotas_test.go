@@ -0,0 +1,143 @@
+package openshift
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+func TestParseQuantity(t *testing.T) {
+	var quantities = []struct {
+		in       string
+		expected float64
+	}{
+		{"4", 4},
+		{"500m", 0.5},
+		{"8Gi", 8 << 30},
+		{"512Mi", 512 << 20},
+		{"10G", 10e9},
+		{"250000000n", 0.25},
+	}
+	for _, q := range quantities {
+		value, err := parseQuantity(q.in)
+		if err != nil {
+			t.Errorf("Unexpected error for %v: %v", q.in, err)
+		}
+		if value != q.expected {
+			t.Errorf("Expected %v for %v, got %v", q.expected, q.in, value)
+		}
+	}
+	if _, err := parseQuantity("8Xi"); err == nil {
+		t.Error("Expected an error for an invalid suffix")
+	}
+}
+
+func parseQuotas(t *testing.T, raw string) []*gabs.Container {
+	json, err := gabs.ParseJSON([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return getUnscopedQuotas(json.Children())
+}
+
+func TestAggregateQuotas(t *testing.T) {
+	quotas := parseQuotas(t, `[
+		{"spec": {"hard": {"limits.cpu": "8", "pods": "20"}}, "status": {"used": {"limits.cpu": "2", "pods": "3"}}},
+		{"spec": {"hard": {"limits.cpu": "4000m", "requests.storage": "10Gi"}}, "status": {"used": {"limits.cpu": "2500m"}}},
+		{"spec": {"hard": {"pods": "1"}, "scopes": ["BestEffort"]}}
+	]`)
+	hard, used := aggregateQuotas(quotas)
+	expected := map[string]string{"limits.cpu": "4000m", "pods": "20", "requests.storage": "10Gi"}
+	if !reflect.DeepEqual(hard, expected) {
+		t.Errorf("Expected hard quotas %v, got %v", expected, hard)
+	}
+	if used["pods"] != "3" {
+		t.Errorf("Expected 3 used pods, got %v", used["pods"])
+	}
+	if used["limits.cpu"] != "2500m" {
+		t.Errorf("Expected the highest cpu usage 2500m, got %v", used["limits.cpu"])
+	}
+}
+
+func TestPlanQuotaUpdates(t *testing.T) {
+	quotas := parseQuotas(t, `[
+		{"metadata": {"name": "first"}, "spec": {"hard": {"limits.cpu": "8"}}},
+		{"metadata": {"name": "second"}, "spec": {"hard": {"limits.cpu": "8", "pods": "20"}}},
+		{"metadata": {"name": "third"}, "spec": {"hard": {"services": "10"}}}
+	]`)
+	updated, created := planQuotaUpdates(quotas, map[string]string{"limits.cpu": "4", "requests.storage": "5Gi"}, "my-project")
+	if created != nil {
+		t.Fatal("Expected no new quota")
+	}
+	if len(updated) != 2 {
+		t.Fatalf("Expected 2 updated quotas, got %v", len(updated))
+	}
+	if v := updated[0].Search("spec", "hard", "requests.storage").Data(); v != "5Gi" {
+		t.Errorf("Expected requests.storage to be added to the first quota, got %v", v)
+	}
+	for _, q := range updated {
+		if v := q.Search("spec", "hard", "limits.cpu").Data(); v != "4" {
+			t.Errorf("Expected limits.cpu to be 4, got %v", v)
+		}
+	}
+
+	updated, created = planQuotaUpdates(nil, map[string]string{"pods": "10"}, "my-project")
+	if len(updated) != 0 || created == nil {
+		t.Fatal("Expected a new quota")
+	}
+	if created.Search("spec", "hard", "pods").Data() != "10" || created.Search("metadata", "namespace").Data() != "my-project" {
+		t.Errorf("Unexpected new quota: %v", created.String())
+	}
+}
+
+func TestValidateQuotaValues(t *testing.T) {
+	maxima := map[string]string{"limits.cpu": "8", "limits.memory": "16Gi", "pods": "100"}
+	var tests = []struct {
+		hard  map[string]string
+		valid bool
+	}{
+		{map[string]string{"limits.cpu": "8", "limits.memory": "16Gi", "pods": "100"}, true},
+		{map[string]string{"limits.cpu": "8500m"}, false},
+		{map[string]string{"limits.memory": "17Gi"}, false},
+		{map[string]string{"Limits.CPU": "8500m"}, false},
+		{map[string]string{"Limits.CPU": "4"}, true},
+		{map[string]string{"services": "10"}, false},
+		{map[string]string{"count/secrets": "10"}, false},
+		{map[string]string{"pods": "many"}, false},
+	}
+	for _, test := range tests {
+		err := validateQuotaValues(test.hard, maxima)
+		if test.valid && err != nil {
+			t.Errorf("Expected %v to be valid, got %v", test.hard, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected %v to be invalid", test.hard)
+		}
+	}
+}
+
+func TestNormalizeQuotaResources(t *testing.T) {
+	normalized := normalizeQuotaResources(map[string]string{"Limits.CPU": "4", "pods": "10"})
+	expected := map[string]string{"limits.cpu": "4", "pods": "10"}
+	if !reflect.DeepEqual(normalized, expected) {
+		t.Errorf("Expected %v, got %v", expected, normalized)
+	}
+}
+
+func TestSetLimitRangeDefaults(t *testing.T) {
+	limitRange, _ := gabs.ParseJSON([]byte(`{"spec": {"limits": [
+		{"type": "Pod", "max": {"cpu": "4"}},
+		{"type": "Container", "default": {"cpu": "500m", "memory": "512Mi"}}
+	]}}`))
+	setLimitRangeDefaults(limitRange, common.LimitRangeDefaults{DefaultCPU: "1", DefaultRequestMemory: "256Mi"})
+	defaults := getLimitRangeDefaults([]*gabs.Container{limitRange})
+	expected := &common.LimitRangeDefaults{DefaultCPU: "1", DefaultMemory: "512Mi", DefaultRequestMemory: "256Mi"}
+	if !reflect.DeepEqual(defaults, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, defaults)
+	}
+	if err := validateLimitRangeDefaults(common.LimitRangeDefaults{DefaultCPU: "1", DefaultRequestCPU: "2"}); err == nil {
+		t.Error("Expected an error for a request greater than the limit")
+	}
+}
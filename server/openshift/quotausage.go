@@ -0,0 +1,277 @@
+package openshift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const gib = 1 << 30
+
+// Nodes with these roles don't run user workloads
+var nonWorkerNodeRoles = []string{
+	"node-role.kubernetes.io/master",
+	"node-role.kubernetes.io/infra",
+}
+
+// getQuotaUsageHandler returns the quotas and the consumption of all projects
+// the user administers. Operators get a summary of the cluster as well.
+func getQuotaUsageHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	if clusterId == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Cluster must be provided"})
+		return
+	}
+
+	projects, err := getAdministeredProjects(clusterId, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	quotas, err := getAllResourceQuotas(clusterId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	// The metrics API is optional
+	usage, err := getPodMetrics(clusterId)
+	if err != nil {
+		log.Printf("WARNING: Could not get pod metrics on cluster %v: %v", clusterId, err)
+	}
+
+	response := common.QuotaUsageResponse{
+		ClusterId: clusterId,
+		Projects:  getProjectQuotaUsage(projects, quotas, usage),
+	}
+
+	operator, err := isOperator(clusterId, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if operator {
+		nodes, err := getNodes(clusterId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		response.Cluster = getClusterQuotaSummary(quotas, nodes, usage)
+	}
+
+	log.Printf("%v has queried the quota usage on cluster %v", username, clusterId)
+	c.JSON(http.StatusOK, response)
+}
+
+// getAdministeredProjects returns the projects, where the user is admin directly
+// or through an LDAP group. The operator group is ignored, because it's admin
+// of all projects.
+func getAdministeredProjects(clusterId, username string) ([]string, error) {
+	return getAdministeredProjectsWithContext(context.Background(), clusterId, username)
+}
+
+// getAdministeredProjectsWithContext cancels the request when the context is done
+func getAdministeredProjectsWithContext(ctx context.Context, clusterId, username string) ([]string, error) {
+	resp, err := getOseHTTPClientWithContext(ctx, "GET", clusterId, "apis/rbac.authorization.k8s.io/v1/rolebindings", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, errors.New(genericAPIError)
+	}
+
+	var groups []string
+	groupsLoaded := false
+	projects := []string{}
+	for _, rb := range json.S("items").Children() {
+		if rb.Path("roleRef.name").Data() != "admin" {
+			continue
+		}
+		namespace, _ := rb.Path("metadata.namespace").Data().(string)
+		for _, subject := range rb.S("subjects").Children() {
+			kind, _ := subject.S("kind").Data().(string)
+			name, _ := subject.S("name").Data().(string)
+			isAdmin := false
+			switch {
+			case kind == "User":
+				isAdmin = strings.ToLower(name) == strings.ToLower(username)
+			case kind == "Group" && strings.ToLower(name) != "operator":
+				if !groupsLoaded {
+					groupsLoaded = true
+					if groups, err = getLdapGroupsOfUser(username); err != nil {
+						log.Printf("Error getting LDAP groups of %v: %v", username, err)
+					}
+				}
+				isAdmin = common.ContainsStringI(groups, name)
+			}
+			if isAdmin {
+				projects = append(projects, namespace)
+				break
+			}
+		}
+	}
+	projects = common.RemoveDuplicates(projects)
+	sort.Strings(projects)
+	return projects, nil
+}
+
+// getAllResourceQuotas returns the unscoped quotas of all projects by namespace
+func getAllResourceQuotas(clusterId string) (map[string][]*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/resourcequotas", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	quotas := map[string][]*gabs.Container{}
+	for _, q := range getUnscopedQuotas(json.S("items").Children()) {
+		namespace, _ := q.Path("metadata.namespace").Data().(string)
+		quotas[namespace] = append(quotas[namespace], q)
+	}
+	return quotas, nil
+}
+
+type resourceUsage struct {
+	CPU    float64
+	Memory float64
+}
+
+// getPodMetrics returns the current cpu (cores) and memory (bytes)
+// consumption by namespace from the metrics API
+func getPodMetrics(clusterId string) (map[string]resourceUsage, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "apis/metrics.k8s.io/v1beta1/pods", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Metrics API returned %v", resp.StatusCode)
+	}
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+	return sumPodMetrics(json.S("items").Children()), nil
+}
+
+func sumPodMetrics(pods []*gabs.Container) map[string]resourceUsage {
+	usage := map[string]resourceUsage{}
+	for _, pod := range pods {
+		namespace, _ := pod.Path("metadata.namespace").Data().(string)
+		u := usage[namespace]
+		for _, container := range pod.S("containers").Children() {
+			cpu, _ := parseQuantity(fmt.Sprint(container.Path("usage.cpu").Data()))
+			memory, _ := parseQuantity(fmt.Sprint(container.Path("usage.memory").Data()))
+			u.CPU += cpu
+			u.Memory += memory
+		}
+		usage[namespace] = u
+	}
+	return usage
+}
+
+func getProjectQuotaUsage(projects []string, quotas map[string][]*gabs.Container, usage map[string]resourceUsage) []common.ProjectQuotaUsage {
+	result := []common.ProjectQuotaUsage{}
+	for _, p := range projects {
+		hard, used := aggregateQuotas(quotas[p])
+		u := usage[p]
+		result = append(result, common.ProjectQuotaUsage{
+			Project:          p,
+			Hard:             hard,
+			Used:             used,
+			MetricsAvailable: usage != nil,
+			UsageCPU:         u.CPU,
+			UsageMemory:      u.Memory / gib,
+		})
+	}
+	return result
+}
+
+func getNodes(clusterId string) ([]*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/nodes", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+	return json.S("items").Children(), nil
+}
+
+// isWorkerNode returns true for schedulable nodes without master or infra role
+func isWorkerNode(node *gabs.Container) bool {
+	if unschedulable, _ := node.Path("spec.unschedulable").Data().(bool); unschedulable {
+		return false
+	}
+	for _, role := range nonWorkerNodeRoles {
+		if node.Exists("metadata", "labels", role) {
+			return false
+		}
+	}
+	return true
+}
+
+// getQuotaValue returns the first of the resources set in the quota
+func getQuotaValue(hard map[string]string, resources ...string) float64 {
+	for _, r := range resources {
+		if v, ok := hard[r]; ok {
+			value, _ := parseQuantity(v)
+			return value
+		}
+	}
+	return 0
+}
+
+func getClusterQuotaSummary(quotas map[string][]*gabs.Container, nodes []*gabs.Container, usage map[string]resourceUsage) *common.ClusterQuotaSummary {
+	summary := common.ClusterQuotaSummary{}
+	for _, q := range quotas {
+		hard, _ := aggregateQuotas(q)
+		summary.Projects++
+		summary.QuotaRequestsCPU += getQuotaValue(hard, "requests.cpu", "cpu")
+		summary.QuotaLimitsCPU += getQuotaValue(hard, "limits.cpu")
+		summary.QuotaRequestsMemory += getQuotaValue(hard, "requests.memory", "memory") / gib
+		summary.QuotaLimitsMemory += getQuotaValue(hard, "limits.memory") / gib
+	}
+	for _, node := range nodes {
+		if !isWorkerNode(node) {
+			continue
+		}
+		summary.Nodes++
+		cpu, _ := parseQuantity(fmt.Sprint(node.Path("status.allocatable.cpu").Data()))
+		memory, _ := parseQuantity(fmt.Sprint(node.Path("status.allocatable.memory").Data()))
+		summary.AllocatableCPU += cpu
+		summary.AllocatableMemory += memory / gib
+	}
+	for _, u := range usage {
+		summary.UsageCPU += u.CPU
+		summary.UsageMemory += u.Memory / gib
+	}
+	return &summary
+}
@@ -0,0 +1,51 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+func TestClusterQuotaSummary(t *testing.T) {
+	quotaList := parseQuotas(t, `[
+		{"metadata": {"namespace": "a"}, "spec": {"hard": {"cpu": "2", "limits.cpu": "4", "memory": "4Gi", "limits.memory": "8Gi"}}},
+		{"metadata": {"namespace": "b"}, "spec": {"hard": {"requests.cpu": "1", "limits.cpu": "2", "requests.memory": "2Gi", "limits.memory": "4Gi"}}}
+	]`)
+	quotas := map[string][]*gabs.Container{}
+	for _, q := range quotaList {
+		namespace := q.Path("metadata.namespace").Data().(string)
+		quotas[namespace] = append(quotas[namespace], q)
+	}
+
+	nodes, _ := gabs.ParseJSON([]byte(`[
+		{"metadata": {"labels": {}}, "status": {"allocatable": {"cpu": "3500m", "memory": "16Gi"}}},
+		{"metadata": {"labels": {}}, "status": {"allocatable": {"cpu": "4", "memory": "16Gi"}}},
+		{"metadata": {"labels": {"node-role.kubernetes.io/master": "true"}}, "status": {"allocatable": {"cpu": "4", "memory": "16Gi"}}},
+		{"metadata": {"labels": {}}, "spec": {"unschedulable": true}, "status": {"allocatable": {"cpu": "4", "memory": "16Gi"}}}
+	]`))
+	pods, _ := gabs.ParseJSON([]byte(`[
+		{"metadata": {"namespace": "a"}, "containers": [{"usage": {"cpu": "250000000n", "memory": "1Gi"}}, {"usage": {"cpu": "250m", "memory": "512Mi"}}]},
+		{"metadata": {"namespace": "b"}, "containers": [{"usage": {"cpu": "1", "memory": "1Gi"}}]}
+	]`))
+	usage := sumPodMetrics(pods.Children())
+	if usage["a"].CPU != 0.5 || usage["a"].Memory != 1.5*gib {
+		t.Errorf("Unexpected usage of project a: %+v", usage["a"])
+	}
+
+	summary := getClusterQuotaSummary(quotas, nodes.Children(), usage)
+	if summary.Projects != 2 || summary.Nodes != 2 {
+		t.Errorf("Expected 2 projects and 2 nodes, got %v and %v", summary.Projects, summary.Nodes)
+	}
+	if summary.QuotaRequestsCPU != 3 || summary.QuotaLimitsCPU != 6 {
+		t.Errorf("Expected CPU quotas 3/6, got %v/%v", summary.QuotaRequestsCPU, summary.QuotaLimitsCPU)
+	}
+	if summary.QuotaRequestsMemory != 6 || summary.QuotaLimitsMemory != 12 {
+		t.Errorf("Expected memory quotas 6/12, got %v/%v", summary.QuotaRequestsMemory, summary.QuotaLimitsMemory)
+	}
+	if summary.AllocatableCPU != 7.5 || summary.AllocatableMemory != 32 {
+		t.Errorf("Expected allocatable 7.5/32, got %v/%v", summary.AllocatableCPU, summary.AllocatableMemory)
+	}
+	if summary.UsageCPU != 1.5 || summary.UsageMemory != 2.5 {
+		t.Errorf("Expected usage 1.5/2.5, got %v/%v", summary.UsageCPU, summary.UsageMemory)
+	}
+}
@@ -0,0 +1,334 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	routeTerminationEdge      = "edge"
+	routeTerminationReencrypt = "reencrypt"
+)
+
+var routeNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// The spec fields set by the route command. Other fields
+// of existing routes are kept on updates.
+var managedRouteFields = []string{"path", "to", "port", "tls"}
+
+func getProjectRoutesHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	routes, err := getRoutes(clusterId, "apis/route.openshift.io/v1/namespaces/"+project+"/routes")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, getRouteInfos(routes, nil))
+}
+
+// getRoutesHandler returns the routes of all projects the user administers
+// ordered by the expiry of their certificates
+func getRoutesHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	if clusterId == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Cluster must be provided"})
+		return
+	}
+
+	projects, err := getAdministeredProjects(clusterId, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	routes, err := getRoutes(clusterId, "apis/route.openshift.io/v1/routes")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	infos := getRouteInfos(routes, projects)
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].Certificate == nil || infos[j].Certificate == nil {
+			return infos[j].Certificate == nil && infos[i].Certificate != nil
+		}
+		return infos[i].Certificate.NotAfter.Before(infos[j].Certificate.NotAfter)
+	})
+	c.JSON(http.StatusOK, infos)
+}
+
+func newRouteHandler(c *gin.Context) {
+	saveRouteHandler(c, false)
+}
+
+func updateRouteHandler(c *gin.Context) {
+	saveRouteHandler(c, true)
+}
+
+// saveRouteHandler creates (update=false) or replaces the route
+func saveRouteHandler(c *gin.Context, update bool) {
+	username := common.GetUserName(c)
+
+	var data common.RouteCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := validateRoute(data, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if data.Host != "" {
+		cluster, err := getOpenshiftCluster(data.ClusterId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		if !isAllowedRouteHost(data.Host, cluster.AllowedRouteDomains) {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{
+				Message: fmt.Sprintf("The hostname %v is not allowed on this cluster. Allowed domains: %v", data.Host, strings.Join(cluster.AllowedRouteDomains, ", ")),
+			})
+			return
+		}
+		if err := validateRouteHostNotClaimed(data.ClusterId, data.Project, data.Host); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+
+	url := "apis/route.openshift.io/v1/namespaces/" + data.Project + "/routes"
+	route := newRoute(data)
+	var err error
+	if update {
+		err = replaceRoute(data.ClusterId, url+"/"+data.Name, route)
+	} else {
+		err = saveObject(data.ClusterId, "POST", url, route)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v saved the route %v (%v) in project %v on cluster %v", username, data.Name, data.Host, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The route %v has been saved", data.Name),
+	})
+}
+
+func validateRoute(data common.RouteCommand, now time.Time) error {
+	if !routeNameRegex.MatchString(data.Name) {
+		return fmt.Errorf("Invalid route name: %v", data.Name)
+	}
+	if data.Service == "" {
+		return errors.New("Service must be provided")
+	}
+	if data.Path != "" && !strings.HasPrefix(data.Path, "/") {
+		return errors.New("The path must start with /")
+	}
+
+	switch data.Termination {
+	case "":
+		if data.Certificate != "" || data.Key != "" {
+			return errors.New("A certificate can only be used with edge or reencrypt termination")
+		}
+		return nil
+	case routeTerminationEdge, routeTerminationReencrypt:
+	default:
+		return fmt.Errorf("Unsupported termination: %v. Allowed: %v, %v", data.Termination, routeTerminationEdge, routeTerminationReencrypt)
+	}
+
+	// The default certificate of the router is used
+	if data.Certificate == "" && data.Key == "" {
+		return nil
+	}
+	if _, err := validateCertificate(data.Certificate, data.Key, now); err != nil {
+		return err
+	}
+	if data.Host == "" {
+		return errors.New("A hostname must be provided to use a custom certificate")
+	}
+	chain, _ := parseCertificateChain([]byte(data.Certificate))
+	if err := chain[0].VerifyHostname(data.Host); err != nil {
+		return fmt.Errorf("The certificate is not valid for %v: %v", data.Host, err)
+	}
+	if data.CACertificate != "" {
+		if _, err := parseCertificateChain([]byte(data.CACertificate)); err != nil {
+			return fmt.Errorf("Invalid CA certificate: %v", err)
+		}
+	}
+	if data.DestinationCACertificate != "" {
+		if data.Termination != routeTerminationReencrypt {
+			return errors.New("A destination CA certificate can only be used with reencrypt termination")
+		}
+		if _, err := parseCertificateChain([]byte(data.DestinationCACertificate)); err != nil {
+			return fmt.Errorf("Invalid destination CA certificate: %v", err)
+		}
+	}
+	return nil
+}
+
+// isAllowedRouteHost checks the hostname against the patterns.
+// *.example.com matches all subdomains of example.com.
+func isAllowedRouteHost(host string, patterns []string) bool {
+	host = strings.ToLower(host)
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if strings.HasPrefix(p, "*.") {
+			if strings.HasSuffix(host, p[1:]) && len(host) > len(p)-1 {
+				return true
+			}
+		} else if host == p {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRouteHostNotClaimed checks that no other project uses the hostname
+func validateRouteHostNotClaimed(clusterId, project, host string) error {
+	routes, err := getRoutes(clusterId, "apis/route.openshift.io/v1/routes")
+	if err != nil {
+		return err
+	}
+	for _, r := range routes {
+		namespace, _ := r.Path("metadata.namespace").Data().(string)
+		h, _ := r.Path("spec.host").Data().(string)
+		if namespace != project && strings.ToLower(h) == strings.ToLower(host) {
+			return fmt.Errorf("The hostname %v is already used by another project", host)
+		}
+	}
+	return nil
+}
+
+func newRoute(data common.RouteCommand) *gabs.Container {
+	route := newObjectRequest("Route", data.Name, "route.openshift.io/v1")
+	if data.Host != "" {
+		route.Set(data.Host, "spec", "host")
+	}
+	if data.Path != "" {
+		route.Set(data.Path, "spec", "path")
+	}
+	route.Set("Service", "spec", "to", "kind")
+	route.Set(data.Service, "spec", "to", "name")
+	if data.TargetPort != "" {
+		route.Set(data.TargetPort, "spec", "port", "targetPort")
+	}
+	if data.Termination != "" {
+		route.Set(data.Termination, "spec", "tls", "termination")
+		route.Set("Redirect", "spec", "tls", "insecureEdgeTerminationPolicy")
+		tls := map[string]string{
+			"certificate":              data.Certificate,
+			"key":                      data.Key,
+			"caCertificate":            data.CACertificate,
+			"destinationCACertificate": data.DestinationCACertificate,
+		}
+		for k, v := range tls {
+			if v != "" {
+				route.Set(v, "spec", "tls", k)
+			}
+		}
+	}
+	return route
+}
+
+// replaceRoute sets the fields of the route on the existing route
+func replaceRoute(clusterId, url string, route *gabs.Container) error {
+	resp, err := getOseHTTPClient("GET", clusterId, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("The route %v doesn't exist", route.Path("metadata.name").Data())
+	}
+	existing, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return errors.New(genericAPIError)
+	}
+	return saveObject(clusterId, "PUT", url, mergeRoute(existing, route))
+}
+
+// mergeRoute sets the managed fields of the route on the existing route.
+// Its labels, annotations and other fields are kept. The host is
+// kept if none is provided, because OpenShift has generated it.
+func mergeRoute(existing, route *gabs.Container) *gabs.Container {
+	for _, field := range managedRouteFields {
+		existing.Delete("spec", field)
+		if v := route.S("spec", field).Data(); v != nil {
+			existing.Set(v, "spec", field)
+		}
+	}
+	if host := route.S("spec", "host").Data(); host != nil {
+		existing.Set(host, "spec", "host")
+	}
+	existing.Delete("status")
+	return existing
+}
+
+func getRoutes(clusterId, url string) ([]*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+	return json.S("items").Children(), nil
+}
+
+// getRouteInfos returns the routes of the projects (all if nil) without keys
+func getRouteInfos(routes []*gabs.Container, projects []string) []common.RouteInfo {
+	infos := []common.RouteInfo{}
+	for _, r := range routes {
+		namespace, _ := r.Path("metadata.namespace").Data().(string)
+		if projects != nil && !common.ContainsStringI(projects, namespace) {
+			continue
+		}
+		info := common.RouteInfo{Project: namespace}
+		info.Name, _ = r.Path("metadata.name").Data().(string)
+		info.Host, _ = r.Path("spec.host").Data().(string)
+		info.Path, _ = r.Path("spec.path").Data().(string)
+		info.Service, _ = r.Path("spec.to.name").Data().(string)
+		info.Termination, _ = r.Path("spec.tls.termination").Data().(string)
+		if certificate, ok := r.Path("spec.tls.certificate").Data().(string); ok && certificate != "" {
+			if chain, err := parseCertificateChain([]byte(certificate)); err == nil {
+				info.Certificate = getCertificateInfo(chain[0])
+			} else {
+				log.Printf("Error parsing certificate of route %v/%v: %v", namespace, info.Name, err)
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
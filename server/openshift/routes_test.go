@@ -0,0 +1,123 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+)
+
+func TestIsAllowedRouteHost(t *testing.T) {
+	patterns := []string{"*.apps.example.com", "www.example.ch"}
+
+	var tests = []struct {
+		host    string
+		allowed bool
+	}{
+		{"app.apps.example.com", true},
+		{"a.b.apps.example.com", true},
+		{"APP.Apps.Example.com", true},
+		{"www.example.ch", true},
+		{"apps.example.com", false},
+		{"app.example.ch", false},
+		{"evilapps.example.com", false},
+		{"app.apps.example.com.evil.com", false},
+	}
+	for _, test := range tests {
+		if isAllowedRouteHost(test.host, patterns) != test.allowed {
+			t.Errorf("isAllowedRouteHost(%v): expected %v", test.host, test.allowed)
+		}
+	}
+}
+
+func TestValidateRoute(t *testing.T) {
+	now := time.Now()
+	ca := newTestCertificate(t, "Test CA", now.AddDate(1, 0, 0), nil)
+	leaf := newTestCertificate(t, "app.example.com", now.AddDate(0, 3, 0), &ca)
+	key := keyPEM(t, leaf.key)
+
+	base := common.RouteCommand{Name: "app", Service: "app"}
+	withTLS := func(host, termination, certificate, key string) common.RouteCommand {
+		r := base
+		r.Host, r.Termination, r.Certificate, r.Key = host, termination, certificate, key
+		return r
+	}
+
+	var tests = []struct {
+		description string
+		route       common.RouteCommand
+		valid       bool
+	}{
+		{"plain", base, true},
+		{"edge with default certificate", withTLS("app.example.com", "edge", "", ""), true},
+		{"edge with certificate", withTLS("app.example.com", "edge", leaf.pem, key), true},
+		{"reencrypt with chain", withTLS("www.app.example.com", "reencrypt", leaf.pem+ca.pem, key), true},
+		{"certificate for other host", withTLS("other.example.com", "edge", leaf.pem, key), false},
+		{"certificate without host", withTLS("", "edge", leaf.pem, key), false},
+		{"certificate without termination", withTLS("app.example.com", "", leaf.pem, key), false},
+		{"passthrough", withTLS("app.example.com", "passthrough", "", ""), false},
+		{"invalid name", common.RouteCommand{Name: "App_1", Service: "app"}, false},
+		{"no service", common.RouteCommand{Name: "app"}, false},
+	}
+	for _, test := range tests {
+		err := validateRoute(test.route, now)
+		if test.valid && err != nil {
+			t.Errorf("%v: expected to be valid, got %v", test.description, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%v: expected to be invalid", test.description)
+		}
+	}
+}
+
+func TestGetRouteInfos(t *testing.T) {
+	now := time.Now()
+	ca := newTestCertificate(t, "Test CA", now.AddDate(1, 0, 0), nil)
+	leaf := newTestCertificate(t, "app.example.com", now.AddDate(0, 3, 0), &ca)
+
+	route, _ := gabs.ParseJSON(newRoute(common.RouteCommand{
+		Name: "app", Host: "app.example.com", Service: "app-svc", Termination: "edge",
+		Certificate: leaf.pem, Key: keyPEM(t, leaf.key),
+	}).Bytes())
+	route.Set("project-a", "metadata", "namespace")
+	other, _ := gabs.ParseJSON(newRoute(common.RouteCommand{Name: "other", Service: "other"}).Bytes())
+	other.Set("project-b", "metadata", "namespace")
+
+	infos := getRouteInfos([]*gabs.Container{route, other}, []string{"project-a"})
+	if len(infos) != 1 {
+		t.Fatalf("Expected 1 route, got %v", infos)
+	}
+	info := infos[0]
+	if info.Host != "app.example.com" || info.Service != "app-svc" || info.Termination != "edge" {
+		t.Errorf("Unexpected route %+v", info)
+	}
+	if info.Certificate == nil || !info.Certificate.NotAfter.Equal(leaf.cert.NotAfter) {
+		t.Errorf("Expected the certificate expiry %v, got %+v", leaf.cert.NotAfter, info.Certificate)
+	}
+}
+
+func TestMergeRoute(t *testing.T) {
+	existing, _ := gabs.ParseJSON([]byte(`{
+		"metadata": {"name": "web", "resourceVersion": "42", "labels": {"app": "web"}, "annotations": {"haproxy.router.openshift.io/timeout": "60s"}},
+		"spec": {"host": "web-app.apps.example.com", "path": "/old", "to": {"kind": "Service", "name": "old"},
+			"tls": {"termination": "edge"}, "wildcardPolicy": "None"},
+		"status": {"ingress": []}
+	}`))
+	route := newRoute(common.RouteCommand{Name: "web", Service: "web", TargetPort: "8080"})
+
+	merged := mergeRoute(existing, route)
+	if merged.Path("metadata.resourceVersion").Data() != "42" || merged.Path("metadata.labels.app").Data() != "web" ||
+		!merged.Exists("metadata", "annotations", "haproxy.router.openshift.io/timeout") {
+		t.Errorf("expected the metadata to be kept, got %v", merged)
+	}
+	if merged.Path("spec.host").Data() != "web-app.apps.example.com" || merged.Path("spec.wildcardPolicy").Data() != "None" {
+		t.Errorf("expected the generated host and the other fields to be kept, got %v", merged)
+	}
+	if merged.Exists("spec", "path") || merged.Exists("spec", "tls") || merged.Exists("status") {
+		t.Errorf("expected the path, tls and status to be removed, got %v", merged)
+	}
+	if merged.Path("spec.to.name").Data() != "web" || merged.Path("spec.port.targetPort").Data() != "8080" {
+		t.Errorf("expected the service and port to be updated, got %v", merged)
+	}
+}
@@ -0,0 +1,36 @@
+package openshift
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	// Used as username in the logs for changes made by scheduled jobs
+	schedulerUsername               = "ssp-scheduler"
+	defaultSchedulerIntervalMinutes = 60
+)
+
+// StartScheduledJobs runs the periodic OpenShift housekeeping jobs in the background
+func StartScheduledJobs() {
+	interval := getConfigIntOrDefault("scheduler_interval_minutes", defaultSchedulerIntervalMinutes)
+	log.Printf("Starting scheduled jobs with an interval of %v minutes", interval)
+
+	startClusterStatusRefresh()
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+		defer ticker.Stop()
+		for {
+			runScheduledJobs()
+			<-ticker.C
+		}
+	}()
+}
+
+func runScheduledJobs() {
+	runScheduledProjectDeletions()
+	runTestProjectReaper()
+	runVolumeUsageMonitor()
+	runDeletedProjectVolumeCleanup()
+}
@@ -6,9 +6,13 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"regexp"
+	"sort"
+	"strings"
 
 	"fmt"
 
+	"encoding/base64"
 	"encoding/json"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
@@ -24,46 +28,415 @@ type Auth struct {
 	Auth []byte `json:"auth"`
 }
 
+const defaultPullSecretName = "external-registry"
+
+// Secret names must be valid DNS subdomains
+var secretNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
 func newPullSecretHandler(c *gin.Context) {
 	username := common.GetUserName(c)
-	cfg := config.Config()
-	dockerRepository := cfg.GetString("docker_repository")
-	if dockerRepository == "" {
-		log.Println("Env variable 'docker_repository' must be specified")
-		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: common.ConfigNotSetError})
+
+	var data common.NewPullSecretCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	setPullSecretDefaults(&data)
+	if err := validatePullSecret(data); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 		return
 	}
+	if err := createNamedPullSecret(data.ClusterId, data.Project, data.Name, data.Registry, data.Username, data.Password, data.ServiceAccounts); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	log.Printf("%v created the pull secret %v for %v on project %v on cluster %v", username, data.Name, data.Registry, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: "Das Pull-Secret wurde angelegt"})
+}
+
+// updatePullSecretHandler replaces the credentials of an existing pull secret
+func updatePullSecretHandler(c *gin.Context) {
+	username := common.GetUserName(c)
 
 	var data common.NewPullSecretCommand
 	if c.BindJSON(&data) != nil {
 		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
 		return
 	}
-	secret := newObjectRequest("Secret", "external-registry", "v1")
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if data.Name == "" {
+		data.Name = defaultPullSecretName
+	}
+	existing, err := getPullSecret(data.ClusterId, data.Project, data.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	// Keep the registry of the secret if none is provided
+	if data.Registry == "" && len(existing.Registries) > 0 {
+		data.Registry = existing.Registries[0]
+	}
+	setPullSecretDefaults(&data)
+	if err := validatePullSecret(data); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	secret := newDockerConfigSecret(data.Name, data.Registry, data.Username, data.Password)
+	if err := replaceSecret(data.ClusterId, data.Project, data.Name, secret); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	log.Printf("%v updated the pull secret %v on project %v on cluster %v", username, data.Name, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: fmt.Sprintf("The pull secret %v has been updated", data.Name)})
+}
+
+func getPullSecretsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	secrets, err := getPullSecrets(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, secrets)
+}
+
+func linkPullSecretHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.LinkPullSecretCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if data.Name == "" || data.ServiceAccount == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Name and service account must be provided"})
+		return
+	}
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if _, err := getPullSecret(data.ClusterId, data.Project, data.Name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	linked, err := isPullSecretLinked(data.ClusterId, data.Project, data.ServiceAccount, data.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if linked {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: fmt.Sprintf("The pull secret %v is already linked to the service account %v", data.Name, data.ServiceAccount)})
+		return
+	}
+	if err := linkPullSecret(data.ClusterId, data.Project, data.ServiceAccount, data.Name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	log.Printf("%v linked the pull secret %v to the service account %v on project %v on cluster %v", username, data.Name, data.ServiceAccount, data.Project, data.ClusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The pull secret %v has been linked to the service account %v", data.Name, data.ServiceAccount),
+	})
+}
+
+// deletePullSecretHandler unlinks the pull secret from all service accounts and deletes it
+func deletePullSecretHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+	name := params.Get("name")
+
+	if name == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "Name must be provided"})
+		return
+	}
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	secret, err := getPullSecret(clusterId, project, name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	for _, sa := range secret.ServiceAccounts {
+		if err := removePullSecretFromServiceaccount(clusterId, project, sa, name); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+	}
+	if err := deleteSecret(clusterId, project, name); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	log.Printf("%v deleted the pull secret %v on project %v on cluster %v", username, name, project, clusterId)
+	c.JSON(http.StatusOK, common.ApiResponse{Message: fmt.Sprintf("The pull secret %v has been deleted", name)})
+}
+
+func setPullSecretDefaults(data *common.NewPullSecretCommand) {
+	if data.Name == "" {
+		data.Name = defaultPullSecretName
+	}
+	if data.Registry == "" {
+		data.Registry = config.Config().GetString("docker_repository")
+	}
+	if len(data.ServiceAccounts) == 0 {
+		data.ServiceAccounts = []string{"default"}
+	}
+}
+
+func validatePullSecret(data common.NewPullSecretCommand) error {
+	if !secretNameRegex.MatchString(data.Name) || len(data.Name) > 253 {
+		return fmt.Errorf("Invalid secret name: %v", data.Name)
+	}
+	if data.Username == "" || data.Password == "" {
+		return errors.New("Username and password must be provided")
+	}
+	registries := getAllowedRegistries()
+	if len(registries) == 0 {
+		log.Println("Env variable 'docker_repository' or 'openshift_pull_secret_registries' must be specified")
+		return errors.New(common.ConfigNotSetError)
+	}
+	if !common.ContainsStringI(registries, data.Registry) {
+		return fmt.Errorf("The registry %v is not allowed. Allowed registries: %v", data.Registry, strings.Join(registries, ", "))
+	}
+	return nil
+}
+
+// getAllowedRegistries returns the docker_repository and the
+// registries configured in openshift_pull_secret_registries
+func getAllowedRegistries() []string {
+	registries := []string{}
+	if r := config.Config().GetString("docker_repository"); r != "" {
+		registries = append(registries, r)
+	}
+	registries = append(registries, config.Config().GetStringSlice("openshift_pull_secret_registries")...)
+	return common.RemoveDuplicates(registries)
+}
+
+func newPullSecret(username, password string) (*gabs.Container, error) {
+	dockerRepository := config.Config().GetString("docker_repository")
+	if dockerRepository == "" {
+		log.Println("Env variable 'docker_repository' must be specified")
+		return nil, errors.New(common.ConfigNotSetError)
+	}
+
+	return newDockerConfigSecret(defaultPullSecretName, dockerRepository, username, password), nil
+}
+
+// newDockerConfigSecret returns a pull secret with the credentials for the registry
+func newDockerConfigSecret(name, registry, username, password string) *gabs.Container {
+	secret := newObjectRequest("Secret", name, "v1")
 	dockerConfig := DockerConfig{
 		Auths: make(map[string]*Auth),
 	}
 	auth := Auth{
-		Auth: []byte(fmt.Sprintf("%v:%v", data.Username, data.Password)),
+		Auth: []byte(fmt.Sprintf("%v:%v", username, password)),
 	}
-	dockerConfig.Auths[dockerRepository] = &auth
+	dockerConfig.Auths[registry] = &auth
 	secretData, _ := json.Marshal(dockerConfig)
 
 	secret.Set(secretData, "data", ".dockerconfigjson")
 	secret.Set("kubernetes.io/dockerconfigjson", "type")
-	if err := createSecret(data.ClusterId, data.Project, secret); err != nil {
-		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
-		return
+	return secret
+}
+
+func createPullSecret(clusterId, namespace, username, password string) error {
+	secret, err := newPullSecret(username, password)
+	if err != nil {
+		return err
 	}
-	if err := addPullSecretToServiceaccount(data.ClusterId, data.Project, "default"); err != nil {
-		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
-		return
+	if err := createSecret(clusterId, namespace, secret); err != nil {
+		return err
 	}
-	log.Printf("%v created a new pull secret to default serviceaccount on project %v on cluster %v", username, data.Project, data.ClusterId)
-	c.JSON(http.StatusOK, common.ApiResponse{Message: "Das Pull-Secret wurde angelegt"})
+	return addPullSecretToServiceaccount(clusterId, namespace, "default")
+}
+
+// createNamedPullSecret creates the pull secret and links it to the service accounts
+func createNamedPullSecret(clusterId, namespace, name, registry, username, password string, serviceaccounts []string) error {
+	secret := newDockerConfigSecret(name, registry, username, password)
+	if err := createSecret(clusterId, namespace, secret); err != nil {
+		return err
+	}
+	for _, sa := range serviceaccounts {
+		if err := linkPullSecret(clusterId, namespace, sa, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updatePullSecret replaces the credentials of the existing pull secret
+func updatePullSecret(clusterId, namespace, username, password string) error {
+	secret, err := newPullSecret(username, password)
+	if err != nil {
+		return err
+	}
+	return replaceSecret(clusterId, namespace, defaultPullSecretName, secret)
+}
+
+func replaceSecret(clusterId, namespace, name string, secret *gabs.Container) error {
+	url := fmt.Sprintf("api/v1/namespaces/%v/secrets/%v", namespace, name)
+	resp, err := getOseHTTPClient("PUT", clusterId, url, bytes.NewReader(secret.Bytes()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error updating pull secret on cluster %v: StatusCode: %v, Nachricht: %v", clusterId, resp.StatusCode, string(bodyBytes))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// getPullSecrets returns the pull secrets of the project without the passwords
+func getPullSecrets(clusterId, namespace string) ([]common.PullSecret, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, fmt.Sprintf("api/v1/namespaces/%v/secrets?fieldSelector=type=kubernetes.io/dockerconfigjson", namespace), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	linked, err := getLinkedPullSecrets(clusterId, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := []common.PullSecret{}
+	for _, s := range json.S("items").Children() {
+		secret := parsePullSecret(s)
+		secret.ServiceAccounts = linked[secret.Name]
+		if secret.ServiceAccounts == nil {
+			secret.ServiceAccounts = []string{}
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+func getPullSecret(clusterId, namespace, name string) (*common.PullSecret, error) {
+	secrets, err := getPullSecrets(clusterId, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range secrets {
+		if s.Name == name {
+			return &s, nil
+		}
+	}
+	return nil, fmt.Errorf("The pull secret %v doesn't exist", name)
+}
+
+// parsePullSecret reads the registries and the username of a dockerconfigjson secret
+func parsePullSecret(secret *gabs.Container) common.PullSecret {
+	name, _ := secret.Path("metadata.name").Data().(string)
+	result := common.PullSecret{Name: name, Registries: []string{}}
+
+	encoded, _ := secret.Path("data").S(".dockerconfigjson").Data().(string)
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		log.Printf("Error decoding pull secret %v: %v", name, err)
+		return result
+	}
+	var dockerConfig DockerConfig
+	if err := json.Unmarshal(decoded, &dockerConfig); err != nil {
+		log.Printf("Error unmarshalling pull secret %v: %v", name, err)
+		return result
+	}
+	for registry, auth := range dockerConfig.Auths {
+		result.Registries = append(result.Registries, registry)
+		if result.Username == "" && auth != nil {
+			result.Username = strings.SplitN(string(auth.Auth), ":", 2)[0]
+		}
+	}
+	sort.Strings(result.Registries)
+	return result
+}
+
+// getLinkedPullSecrets returns the service accounts by pull secret
+func getLinkedPullSecrets(clusterId, namespace string) (map[string][]string, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, fmt.Sprintf("api/v1/namespaces/%v/serviceaccounts", namespace), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+
+	linked := map[string][]string{}
+	for _, sa := range json.S("items").Children() {
+		name, _ := sa.Path("metadata.name").Data().(string)
+		for _, pullSecret := range sa.S("imagePullSecrets").Children() {
+			if secret, ok := pullSecret.S("name").Data().(string); ok {
+				linked[secret] = append(linked[secret], name)
+			}
+		}
+	}
+	return linked, nil
+}
+
+// getPullSecretCredentials returns the credentials stored in the pull secret
+// for the configured docker repository. ok is false if there is no pull secret.
+func getPullSecretCredentials(clusterId, namespace string) (credentials string, ok bool, err error) {
+	secret, err := getSecret(clusterId, namespace, defaultPullSecretName)
+	if err != nil {
+		return "", false, err
+	}
+	if secret.Path("kind").Data() != "Secret" {
+		return "", false, nil
+	}
+	encoded, _ := secret.Path("data").S(".dockerconfigjson").Data().(string)
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		log.Printf("Error decoding pull secret: %v", err)
+		return "", true, nil
+	}
+	var dockerConfig DockerConfig
+	if err := json.Unmarshal(decoded, &dockerConfig); err != nil {
+		log.Printf("Error unmarshalling pull secret: %v", err)
+		return "", true, nil
+	}
+	auth, found := dockerConfig.Auths[config.Config().GetString("docker_repository")]
+	if !found {
+		return "", true, nil
+	}
+	return string(auth.Auth), true, nil
 }
 
 func addPullSecretToServiceaccount(clusterId, namespace string, serviceaccount string) error {
+	return linkPullSecret(clusterId, namespace, serviceaccount, defaultPullSecretName)
+}
+
+// linkPullSecret adds the secret to the imagePullSecrets of the service account
+func linkPullSecret(clusterId, namespace, serviceaccount, secret string) error {
 	url := fmt.Sprintf("api/v1/namespaces/%v/serviceaccounts/%v", namespace, serviceaccount)
 	patch := []common.JsonPatch{
 		{
@@ -72,7 +445,7 @@ func addPullSecretToServiceaccount(clusterId, namespace string, serviceaccount s
 			Value: struct {
 				Name string `json:"name"`
 			}{
-				Name: "external-registry",
+				Name: secret,
 			},
 		},
 	}
@@ -120,3 +493,76 @@ func createSecret(clusterId, namespace string, secret *gabs.Container) error {
 
 	return nil
 }
+
+func removePullSecretFromServiceaccount(clusterId, namespace, serviceaccount, secret string) error {
+	sa, err := getServiceAccount(clusterId, namespace, serviceaccount)
+	if err != nil {
+		return err
+	}
+	pullSecrets, _ := sa.S("imagePullSecrets").Children()
+	for i, pullSecret := range pullSecrets {
+		if pullSecret.Path("name").Data() != secret {
+			continue
+		}
+		url := fmt.Sprintf("api/v1/namespaces/%v/serviceaccounts/%v", namespace, serviceaccount)
+		patch := []common.JsonPatch{
+			{
+				Operation: "remove",
+				Path:      fmt.Sprintf("/imagePullSecrets/%v", i),
+			},
+		}
+		patchBytes, err := json.Marshal(patch)
+		if err != nil {
+			log.Printf("Error marshalling patch: %v", err)
+			return errors.New(genericAPIError)
+		}
+		resp, err := getOseHTTPClient("PATCH", clusterId, url, bytes.NewBuffer(patchBytes))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := ioutil.ReadAll(resp.Body)
+			log.Printf("Error removing pull secret from service account on cluster %v: StatusCode: %v, Nachricht: %v", clusterId, resp.StatusCode, string(bodyBytes))
+			return errors.New(genericAPIError)
+		}
+		return nil
+	}
+	return nil
+}
+
+func deleteSecret(clusterId, namespace, secret string) error {
+	url := fmt.Sprintf("api/v1/namespaces/%v/secrets/%v", namespace, secret)
+
+	resp, err := getOseHTTPClient("DELETE", clusterId, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("The secret %v doesn't exist", secret)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error deleting secret on cluster %v: StatusCode: %v, Nachricht: %v", clusterId, resp.StatusCode, string(bodyBytes))
+		return errors.New(genericAPIError)
+	}
+	return nil
+}
+
+// isPullSecretLinked checks if the secret is in the imagePullSecrets of the service account
+func isPullSecretLinked(clusterId, namespace, serviceaccount, secret string) (bool, error) {
+	sa, err := getServiceAccount(clusterId, namespace, serviceaccount)
+	if err != nil {
+		return false, err
+	}
+	pullSecrets, _ := sa.S("imagePullSecrets").Children()
+	for _, pullSecret := range pullSecrets {
+		if pullSecret.Path("name").Data() == secret {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,53 @@
+package openshift
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestParsePullSecret(t *testing.T) {
+	secret, _ := gabs.ParseJSON(newDockerConfigSecret("registry", "registry.example.com", "user", "secret:password").Bytes())
+	pullSecret := parsePullSecret(secret)
+
+	expected := common.PullSecret{
+		Name:       "registry",
+		Registries: []string{"registry.example.com"},
+		Username:   "user",
+	}
+	if !reflect.DeepEqual(pullSecret, expected) {
+		t.Errorf("Expected %v, got %v", expected, pullSecret)
+	}
+}
+
+func TestValidatePullSecret(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("docker_repository", "registry.example.com")
+	config.Config().Set("openshift_pull_secret_registries", []string{"docker.io"})
+	defer config.Config().Set("docker_repository", "")
+	defer config.Config().Set("openshift_pull_secret_registries", nil)
+
+	var tests = []struct {
+		data  common.NewPullSecretCommand
+		valid bool
+	}{
+		{common.NewPullSecretCommand{Username: "u", Password: "p"}, true},
+		{common.NewPullSecretCommand{Name: "docker-hub", Registry: "docker.io", Username: "u", Password: "p"}, true},
+		{common.NewPullSecretCommand{Registry: "quay.io", Username: "u", Password: "p"}, false},
+		{common.NewPullSecretCommand{Name: "Invalid_Name", Username: "u", Password: "p"}, false},
+		{common.NewPullSecretCommand{Username: "u"}, false},
+	}
+	for _, test := range tests {
+		setPullSecretDefaults(&test.data)
+		err := validatePullSecret(test.data)
+		if test.valid && err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", test.data, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected %+v to be invalid", test.data)
+		}
+	}
+}
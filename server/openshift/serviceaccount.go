@@ -85,6 +85,15 @@ func validateNewServiceAccount(clusterId, username string, project string, servi
 	return nil
 }
 
+// validateServiceAccountAccess checks if the user may manage the existing service account
+func validateServiceAccountAccess(clusterId, username string, project string, serviceAccountName string) error {
+	if len(serviceAccountName) == 0 {
+		return errors.New("Service account name must be provided")
+	}
+
+	return checkAdminPermissions(clusterId, username, project)
+}
+
 func createNewServiceAccount(clusterId, username, project, serviceaccount string) error {
 	p := newObjectRequest("ServiceAccount", serviceaccount, "v1")
 
@@ -242,6 +251,21 @@ func createEditRoleBinding(clusterId, namespace, serviceaccount string) error {
 	return nil
 }
 
+// waitForServiceAccount waits until the service account exists
+func waitForServiceAccount(clusterId, namespace, serviceaccount string) error {
+	for i := 0; i < 10; i++ {
+		saJson, err := getServiceAccount(clusterId, namespace, serviceaccount)
+		if err != nil {
+			return err
+		}
+		if saJson.Exists("metadata", "name") {
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return fmt.Errorf("The service account %v doesn't exist", serviceaccount)
+}
+
 func getServiceAccount(clusterId, namespace, serviceaccount string) (*gabs.Container, error) {
 	url := fmt.Sprintf("api/v1/namespaces/%v/serviceaccounts/%v", namespace, serviceaccount)
 	resp, err := getOseHTTPClient("GET", clusterId, url, nil)
@@ -355,3 +379,277 @@ func createJenkinsCredential(clusterId, project, serviceaccount, organizationKey
 
 	return nil
 }
+
+func getServiceAccounts(clusterId, namespace string) ([]string, error) {
+	url := fmt.Sprintf("api/v1/namespaces/%v/serviceaccounts", namespace)
+	resp, err := getOseHTTPClient("GET", clusterId, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		log.WithFields(log.Fields{
+			"cluster":    clusterId,
+			"namespace":  namespace,
+			"statuscode": resp.StatusCode,
+			"err":        string(bodyBytes),
+		}).Error("Error getting serviceaccounts")
+		return nil, errors.New(genericAPIError)
+	}
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, errors.New(genericAPIError)
+	}
+	items, _ := json.S("items").Children()
+	serviceaccounts := []string{}
+	for _, item := range items {
+		if name, ok := item.Path("metadata.name").Data().(string); ok {
+			serviceaccounts = append(serviceaccounts, name)
+		}
+	}
+	return serviceaccounts, nil
+}
+
+func deleteServiceAccount(clusterId, username, namespace, serviceaccount string) error {
+	url := fmt.Sprintf("api/v1/namespaces/%v/serviceaccounts/%v", namespace, serviceaccount)
+	resp, err := getOseHTTPClient("DELETE", clusterId, url, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("The service account %v doesn't exist", serviceaccount)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		log.WithFields(log.Fields{
+			"cluster":        clusterId,
+			"namespace":      namespace,
+			"serviceaccount": serviceaccount,
+			"statuscode":     resp.StatusCode,
+			"err":            string(bodyBytes),
+		}).Error("Error deleting serviceaccount")
+		return errors.New(genericAPIError)
+	}
+
+	log.WithFields(log.Fields{
+		"cluster":        clusterId,
+		"username":       username,
+		"serviceaccount": serviceaccount,
+		"project":        namespace,
+	}).Info("Serviceaccount was deleted")
+	return nil
+}
+
+// Roles which can always be granted to service accounts.
+// More roles can be allowed with openshift_serviceaccount_roles.
+var defaultServiceAccountRoles = []string{"view", "edit", "admin"}
+
+func getServiceAccountsHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	clusterId := c.Query("clusterid")
+	project := c.Query("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	names, err := getServiceAccounts(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	rolebindings, err := getRoleBindings(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	roles := getServiceAccountRoles(rolebindings, project)
+
+	serviceaccounts := []common.ServiceAccount{}
+	for _, name := range names {
+		r := roles[name]
+		if r == nil {
+			r = []string{}
+		}
+		serviceaccounts = append(serviceaccounts, common.ServiceAccount{Name: name, Roles: r})
+	}
+	c.JSON(http.StatusOK, serviceaccounts)
+}
+
+func deleteServiceAccountHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+	serviceaccount := params.Get("serviceaccount")
+
+	if err := validateServiceAccountAccess(clusterId, username, project, serviceaccount); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := deleteServiceAccount(clusterId, username, project, serviceaccount); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	// The rolebindings would otherwise grant the permissions
+	// to a new service account with the same name
+	if err := removeServiceAccountFromRoleBindings(clusterId, project, serviceaccount); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The service account %v has been deleted", serviceaccount),
+	})
+}
+
+func grantServiceAccountRoleHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.ServiceAccountRoleCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateServiceAccountAccess(data.ClusterId, username, data.Project, data.ServiceAccount); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if !isAllowedServiceAccountRole(data.Role) {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{
+			Message: fmt.Sprintf("The role %v can't be granted. Allowed roles: %v", data.Role, strings.Join(getAllowedServiceAccountRoles(), ", ")),
+		})
+		return
+	}
+
+	if err := addProjectRoleSubject(data.ClusterId, data.Project, data.Role, subjectKindServiceAccount, data.ServiceAccount); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"cluster":        data.ClusterId,
+		"username":       username,
+		"serviceaccount": data.ServiceAccount,
+		"project":        data.Project,
+		"role":           data.Role,
+	}).Info("Role was granted to serviceaccount")
+
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The role %v has been granted to the service account %v", data.Role, data.ServiceAccount),
+	})
+}
+
+func rotateServiceAccountTokenHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.RotateServiceAccountTokenCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateServiceAccountAccess(data.ClusterId, username, data.Project, data.ServiceAccount); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := rotateServiceAccountToken(data.ClusterId, data.Project, data.ServiceAccount); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"cluster":        data.ClusterId,
+		"username":       username,
+		"serviceaccount": data.ServiceAccount,
+		"project":        data.Project,
+	}).Info("Token of serviceaccount was rotated")
+
+	if len(data.OrganizationKey) > 0 {
+		if err := createJenkinsCredential(data.ClusterId, data.Project, data.ServiceAccount, data.OrganizationKey); err != nil {
+			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, common.ApiResponse{
+			Message: fmt.Sprintf("The token of the service account %v has been rotated and stored as a new Jenkins credential", data.ServiceAccount),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("The token of the service account %v has been rotated", data.ServiceAccount),
+	})
+}
+
+func getAllowedServiceAccountRoles() []string {
+	return append(defaultServiceAccountRoles, config.Config().GetStringSlice("openshift_serviceaccount_roles")...)
+}
+
+func isAllowedServiceAccountRole(role string) bool {
+	for _, r := range getAllowedServiceAccountRoles() {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// getServiceAccountTokenSecrets returns the names of the token secrets
+func getServiceAccountTokenSecrets(serviceaccount *gabs.Container) []string {
+	secrets := []string{}
+	children, _ := serviceaccount.S("secrets").Children()
+	for _, s := range children {
+		name, _ := s.S("name").Data().(string)
+		if name != "" && strings.Contains(name, "-token-") {
+			secrets = append(secrets, name)
+		}
+	}
+	return secrets
+}
+
+// rotateServiceAccountToken deletes the token secrets of the service account
+// and waits until OpenShift has created a new one
+func rotateServiceAccountToken(clusterId, namespace, serviceaccount string) error {
+	saJson, err := getServiceAccount(clusterId, namespace, serviceaccount)
+	if err != nil {
+		return err
+	}
+	if !saJson.Exists("metadata", "name") {
+		return fmt.Errorf("The service account %v doesn't exist", serviceaccount)
+	}
+
+	oldSecrets := getServiceAccountTokenSecrets(saJson)
+	for _, secret := range oldSecrets {
+		if err := deleteSecret(clusterId, namespace, secret); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		time.Sleep(500 * time.Millisecond)
+		saJson, err := getServiceAccount(clusterId, namespace, serviceaccount)
+		if err != nil {
+			return err
+		}
+		for _, secret := range getServiceAccountTokenSecrets(saJson) {
+			if !common.ContainsStringI(oldSecrets, secret) {
+				return nil
+			}
+		}
+	}
+	return errors.New("The new token of the service account has not been created yet. Please try again later")
+}
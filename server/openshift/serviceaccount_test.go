@@ -0,0 +1,65 @@
+package openshift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestIsAllowedServiceAccountRole(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("openshift_serviceaccount_roles", []string{"registry-editor"})
+	defer config.Config().Set("openshift_serviceaccount_roles", nil)
+
+	var tests = []struct {
+		role    string
+		allowed bool
+	}{
+		{"view", true},
+		{"edit", true},
+		{"admin", true},
+		{"registry-editor", true},
+		{"cluster-admin", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if isAllowedServiceAccountRole(test.role) != test.allowed {
+			t.Errorf("isAllowedServiceAccountRole(%v): expected %v", test.role, test.allowed)
+		}
+	}
+}
+
+func TestWaitForServiceAccount(t *testing.T) {
+	config.Init("bla")
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind": "Status", "reason": "NotFound"}`))
+			return
+		}
+		w.Write([]byte(`{"metadata": {"name": "default"}}`))
+	}))
+	defer server.Close()
+
+	config.Config().Set("openshift", []map[string]interface{}{{"id": "c1", "name": "C1", "url": server.URL, "token": "token"}})
+	defer config.Config().Set("openshift", nil)
+
+	if err := waitForServiceAccount("c1", "app", "default"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected the service account to be requested twice, got %v", requests)
+	}
+}
+
+func TestValidateServiceAccountAccess(t *testing.T) {
+	err := validateServiceAccountAccess("c1", "u123456", "app", "")
+	if err == nil || err.Error() != "Service account name must be provided" {
+		t.Errorf("Expected the missing name to be reported, got %v", err)
+	}
+}
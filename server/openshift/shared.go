@@ -1,6 +1,7 @@
 package openshift
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -27,20 +28,67 @@ const (
 func RegisterRoutes(r *gin.RouterGroup) {
 	// OpenShift
 	r.POST("/ose/project", newProjectHandler)
+	r.POST("/ose/project/apply", applyProjectManifestHandler)
+	r.DELETE("/ose/project", deleteProjectHandler)
+	r.POST("/ose/project/restore", restoreProjectHandler)
+	r.POST("/ose/project/migrate", migrateProjectHandler)
 	r.GET("/ose/projects", getProjectsHandler)
+	r.GET("/ose/projects/all", getAllProjectsHandler)
 	r.GET("/ose/project/admins", getProjectAdminsHandler)
 	r.POST("/ose/project/admins", addProjectAdminHandler)
+	r.DELETE("/ose/project/admins", removeProjectAdminHandler)
+	r.POST("/ose/project/admins/group", addProjectAdminGroupHandler)
+	r.DELETE("/ose/project/admins/group", removeProjectAdminGroupHandler)
+	r.POST("/ose/project/owner", transferProjectOwnershipHandler)
+	r.GET("/ose/project/rolebindings", getProjectRoleBindingsHandler)
+	r.POST("/ose/project/rolebindings", addProjectRoleHandler)
+	r.DELETE("/ose/project/rolebindings", removeProjectRoleHandler)
 	r.POST("/ose/testproject", newTestProjectHandler)
+	r.POST("/ose/testproject/extend", extendTestProjectHandler)
 	r.POST("/ose/serviceaccount", newServiceAccountHandler)
+	r.GET("/ose/serviceaccounts", getServiceAccountsHandler)
+	r.DELETE("/ose/serviceaccount", deleteServiceAccountHandler)
+	r.POST("/ose/serviceaccount/role", grantServiceAccountRoleHandler)
+	r.POST("/ose/serviceaccount/rotate", rotateServiceAccountTokenHandler)
+	r.GET("/ose/project/metadata", getProjectMetadataSchemaHandler)
+	r.GET("/ose/project/blueprints", getProjectBlueprintsHandler)
+	r.POST("/ose/project/blueprint", applyProjectBlueprintHandler)
+	r.GET("/ose/project/networkpolicies", getNetworkPoliciesHandler)
+	r.POST("/ose/project/networkpolicies", newNetworkPolicyHandler)
+	r.DELETE("/ose/project/networkpolicies", deleteNetworkPolicyHandler)
+	r.GET("/ose/networkpolicy/presets", getNetworkPolicyPresetsHandler)
 	r.GET("/ose/project/info", getProjectInformationHandler)
 	r.POST("/ose/project/info", updateProjectInformationHandler)
 	r.GET("/ose/quotas", getQuotasHandler)
 	r.POST("/ose/quotas", editQuotasHandler)
+	r.GET("/ose/quotas/usage", getQuotaUsageHandler)
+	r.GET("/ose/project/quotas", getProjectQuotasHandler)
+	r.POST("/ose/project/quotas", editProjectQuotasHandler)
 	r.POST("/ose/secret/pull", newPullSecretHandler)
-
-	// Volumes (Gluster and NFS)
+	r.PUT("/ose/secret/pull", updatePullSecretHandler)
+	r.DELETE("/ose/secret/pull", deletePullSecretHandler)
+	r.GET("/ose/secrets/pull", getPullSecretsHandler)
+	r.POST("/ose/secret/pull/link", linkPullSecretHandler)
+	r.GET("/ose/project/secrets", getProjectSecretsHandler)
+	r.POST("/ose/project/secret", newProjectSecretHandler)
+	r.PUT("/ose/project/secret", updateProjectSecretHandler)
+	r.DELETE("/ose/project/secret", deleteProjectSecretHandler)
+	r.GET("/ose/secrets/certificates", getCertificateExpiryHandler)
+	r.GET("/ose/project/events", getProjectEventsHandler)
+	r.GET("/ose/projects/problems", getProjectProblemsHandler)
+	r.GET("/ose/project/routes", getProjectRoutesHandler)
+	r.GET("/ose/routes", getRoutesHandler)
+	r.POST("/ose/project/route", newRouteHandler)
+	r.PUT("/ose/project/route", updateRouteHandler)
+
+	// Volumes (Gluster, NFS and dynamic provisioning)
+	r.GET("/ose/volumes", getVolumesHandler)
 	r.POST("/ose/volume", newVolumeHandler)
 	r.POST("/ose/volume/grow", growVolumeHandler)
+	r.DELETE("/ose/volume", deleteVolumeHandler)
+	r.GET("/ose/volume/usage", getVolumeUsageHandler)
+	r.POST("/ose/volume/alerting", updateVolumeAlertingHandler)
+	r.GET("/ose/volume/status", getVolumeStatusHandler)
 	r.POST("/ose/volume/gluster/fix", fixVolumeHandler)
 	// Get job status for NFS volumes because it takes a while
 	r.GET("/ose/volume/jobs", jobStatusHandler)
@@ -52,20 +100,17 @@ func getProjectAdminsAndOperators(clusterId, project string) ([]string, []string
 	if err != nil {
 		return nil, nil, err
 	}
+	return getAdminsAndOperators(clusterId, adminRoleBinding)
+}
 
+func getAdminsAndOperators(clusterId string, adminRoleBinding *gabs.Container) ([]string, []string, error) {
 	var admins []string
-	hasOperatorGroup := false
-	for _, g := range adminRoleBinding.Path("groupNames").Children() {
-		if strings.ToLower(g.Data().(string)) == "operator" {
-			hasOperatorGroup = true
-		}
-	}
 	for _, u := range adminRoleBinding.Path("userNames").Children() {
 		admins = append(admins, strings.ToLower(u.Data().(string)))
 	}
 
 	var operators []string
-	if hasOperatorGroup {
+	if hasOperatorGroup(adminRoleBinding) {
 		// Going to add the operator group to the admins
 		json, err := getOperatorGroup(clusterId)
 		if err != nil {
@@ -81,10 +126,27 @@ func getProjectAdminsAndOperators(clusterId, project string) ([]string, []string
 	return common.RemoveDuplicates(admins), operators, nil
 }
 
+// getAdminGroups returns the LDAP groups with admin permissions.
+// The operator group is handled separately.
+func getAdminGroups(adminRoleBinding *gabs.Container) []string {
+	var groups []string
+	for _, g := range adminRoleBinding.Path("groupNames").Children() {
+		group := g.Data().(string)
+		if strings.ToLower(group) != "operator" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
 func checkAdminPermissions(clusterId, username, project string) error {
 	// Check if user has admin-access
 	hasAccess := false
-	admins, operators, err := getProjectAdminsAndOperators(clusterId, project)
+	adminRoleBinding, err := getAdminRoleBinding(clusterId, project)
+	if err != nil {
+		return err
+	}
+	admins, operators, err := getAdminsAndOperators(clusterId, adminRoleBinding)
 	if err != nil {
 		return err
 	}
@@ -105,10 +167,27 @@ func checkAdminPermissions(clusterId, username, project string) error {
 		}
 	}
 
+	// Access for members of admin groups
+	groups := getAdminGroups(adminRoleBinding)
+	if !hasAccess && len(groups) > 0 {
+		userGroups, err := getLdapGroupsOfUser(username)
+		if err != nil {
+			log.Printf("Error getting LDAP groups of %v: %v", username, err)
+		}
+		for _, g := range groups {
+			if common.ContainsStringI(userGroups, g) {
+				hasAccess = true
+			}
+		}
+	}
+
 	if hasAccess {
 		return nil
 	}
 
+	if len(groups) > 0 {
+		return fmt.Errorf("You don't have admin permissions on the project: %v. The following users and groups have admin permissions: %v", project, strings.Join(append(admins, groups...), ", "))
+	}
 	return fmt.Errorf("You don't have admin permissions on the project: %v. The following users have admin permissions: %v", project, strings.Join(admins, ", "))
 }
 
@@ -156,11 +235,19 @@ func getAdminRoleBinding(clusterId, project string) (*gabs.Container, error) {
 			if adminRoleBinding == nil {
 				adminRoleBinding = role
 			}
-			for _, name := range role.Path("subjects").Children() {
-				userNames = append(userNames, strings.ToLower(name.Path("name").Data().(string)))
+			// Service accounts with the admin role are not project admins.
+			// LDAP groups are case sensitive, so their name is kept.
+			for _, subject := range role.Path("subjects").Children() {
+				name := subject.Path("name").Data().(string)
+				switch subject.Path("kind").Data() {
+				case "Group":
+					groupNames = appendGroupName(groupNames, name)
+				case "User":
+					userNames = append(userNames, strings.ToLower(name))
+				}
 			}
 			for _, name := range role.Path("groupNames").Children() {
-				groupNames = append(groupNames, strings.ToLower(name.Data().(string)))
+				groupNames = appendGroupName(groupNames, name.Data().(string))
 			}
 		}
 	}
@@ -170,7 +257,6 @@ func getAdminRoleBinding(clusterId, project string) (*gabs.Container, error) {
 	for _, name := range userNames {
 		adminRoleBinding.ArrayAppend(name, "userNames")
 	}
-	groupNames = common.RemoveDuplicates(groupNames)
 	adminRoleBinding.Array("groupNames")
 	for _, name := range groupNames {
 		adminRoleBinding.ArrayAppend(name, "groupNames")
@@ -179,7 +265,21 @@ func getAdminRoleBinding(clusterId, project string) (*gabs.Container, error) {
 	return adminRoleBinding, nil
 }
 
+// appendGroupName appends the group unless it is already contained
+// in another case
+func appendGroupName(groups []string, group string) []string {
+	if common.ContainsStringI(groups, group) {
+		return groups
+	}
+	return append(groups, group)
+}
+
 func getOseHTTPClient(method string, clusterId string, endURL string, body io.Reader) (*http.Response, error) {
+	return getOseHTTPClientWithContext(context.Background(), method, clusterId, endURL, body)
+}
+
+// getOseHTTPClientWithContext cancels the request when the context is done
+func getOseHTTPClientWithContext(ctx context.Context, method string, clusterId string, endURL string, body io.Reader) (*http.Response, error) {
 	cluster, err := getOpenshiftCluster(clusterId)
 	if err != nil {
 		return nil, err
@@ -202,6 +302,7 @@ func getOseHTTPClient(method string, clusterId string, endURL string, body io.Re
 	client := &http.Client{Transport: tr}
 
 	req, _ := http.NewRequest(method, base+"/"+endURL, body)
+	req = req.WithContext(ctx)
 
 	log.Debugf("Calling %v", req.URL.String())
 
@@ -247,7 +348,7 @@ func getWZUBackendClient(method string, endUrl string, body io.Reader) (*http.Re
 	return resp, nil
 }
 
-func getGlusterHTTPClient(clusterId string, url string, body io.Reader) (*http.Response, error) {
+func getGlusterHTTPClient(method, clusterId, url string, body io.Reader) (*http.Response, error) {
 	cluster, err := getOpenshiftCluster(clusterId)
 	if err != nil {
 		return nil, err
@@ -267,7 +368,7 @@ func getGlusterHTTPClient(clusterId string, url string, body io.Reader) (*http.R
 	}
 
 	client := &http.Client{}
-	req, _ := http.NewRequest("POST", fmt.Sprintf("%v/%v", apiUrl, url), body)
+	req, _ := http.NewRequest(method, fmt.Sprintf("%v/%v", apiUrl, url), body)
 
 	log.Debugf("Calling %v", req.URL.String())
 
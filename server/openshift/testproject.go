@@ -0,0 +1,255 @@
+package openshift
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/ldap"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testProjectDaysAnnotation       = "openshift.io/testproject-daystodeletion"
+	testProjectExtensionsAnnotation = "openshift.io/testproject-extensions"
+	testProjectNotifiedAnnotation   = "openshift.io/testproject-notified"
+	requesterAnnotation             = "openshift.io/requester"
+	descriptionAnnotation           = "openshift.io/description"
+
+	defaultTestProjectNotificationDays = 7
+	defaultTestProjectExtensionDays    = 30
+	defaultTestProjectMaxExtensions    = 2
+)
+
+type testProjectAction int
+
+const (
+	testProjectKeep testProjectAction = iota
+	testProjectNotify
+	testProjectDelete
+)
+
+// extendTestProjectHandler postpones the deletion of a test project
+func extendTestProjectHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.OpenshiftBase
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+
+	if err := validateAdminAccess(data.ClusterId, username, data.Project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	expiry, err := extendTestProject(data.ClusterId, data.Project, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.ApiResponse{
+		Message: fmt.Sprintf("Das Testprojekt %v wird neu am %v gelöscht", data.Project, expiry.Format("02.01.2006")),
+	})
+}
+
+func extendTestProject(clusterId, project, username string) (time.Time, error) {
+	namespace, err := getNamespace(clusterId, project)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	expiry, isTestProject, err := getTestProjectExpiry(namespace)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !isTestProject {
+		return time.Time{}, fmt.Errorf("Das Projekt %v ist kein Testprojekt", project)
+	}
+
+	extensions, _ := strconv.Atoi(getAnnotation(namespace, testProjectExtensionsAnnotation))
+	maxExtensions := getConfigIntOrDefault("testproject_max_extensions", defaultTestProjectMaxExtensions)
+	if extensions >= maxExtensions {
+		return time.Time{}, fmt.Errorf("Das Testprojekt %v wurde bereits %v mal verlängert. Es kann nicht mehr verlängert werden", project, extensions)
+	}
+
+	days, _ := strconv.Atoi(getAnnotation(namespace, testProjectDaysAnnotation))
+	extensionDays := getConfigIntOrDefault("testproject_extension_days", defaultTestProjectExtensionDays)
+	days += extensionDays
+	expiry = expiry.AddDate(0, 0, extensionDays)
+
+	annotations := namespace.Path("metadata.annotations")
+	annotations.Set(strconv.Itoa(days), testProjectDaysAnnotation)
+	annotations.Set(strconv.Itoa(extensions+1), testProjectExtensionsAnnotation)
+	annotations.Set(fmt.Sprintf("Dieses Testprojekt wird am %v automatisch gelöscht!", expiry.Format("02.01.2006")), descriptionAnnotation)
+	// A new notification is sent before the new expiry date
+	annotations.Delete(testProjectNotifiedAnnotation)
+
+	if err := updateNamespace(clusterId, project, namespace); err != nil {
+		return time.Time{}, err
+	}
+
+	log.Printf("%v extended the test project %v on cluster %v until %v", username, project, clusterId, expiry)
+	return expiry, nil
+}
+
+// getTestProjectExpiry returns the date when a test project will be deleted.
+// The second return value is false, if the project is not a test project.
+func getTestProjectExpiry(namespace *gabs.Container) (time.Time, bool, error) {
+	daysToDeletion := getAnnotation(namespace, testProjectDaysAnnotation)
+	if daysToDeletion == "" {
+		return time.Time{}, false, nil
+	}
+	days, err := strconv.Atoi(daysToDeletion)
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("Invalid value for %v: %v", testProjectDaysAnnotation, daysToDeletion)
+	}
+	created, _ := namespace.Path("metadata.creationTimestamp").Data().(string)
+	creationTime, err := time.Parse(time.RFC3339, created)
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("Invalid creation timestamp: %v", created)
+	}
+	return creationTime.AddDate(0, 0, days), true, nil
+}
+
+// getTestProjectAction decides what happens with a test project and returns the date of its deletion.
+// A test project is only deleted notificationDays after the requester has been notified,
+// even if it has already expired (e.g. because the notification failed).
+func getTestProjectAction(namespace *gabs.Container, now time.Time, notificationDays int) (testProjectAction, time.Time, error) {
+	expiry, isTestProject, err := getTestProjectExpiry(namespace)
+	if err != nil || !isTestProject {
+		return testProjectKeep, expiry, err
+	}
+
+	notified, err := time.Parse(time.RFC3339, getAnnotation(namespace, testProjectNotifiedAnnotation))
+	if err != nil {
+		if now.AddDate(0, 0, notificationDays).After(expiry) {
+			return testProjectNotify, latestTime(expiry, now.AddDate(0, 0, notificationDays)), nil
+		}
+		return testProjectKeep, expiry, nil
+	}
+
+	deletionDate := latestTime(expiry, notified.AddDate(0, 0, notificationDays))
+	if now.After(deletionDate) {
+		return testProjectDelete, deletionDate, nil
+	}
+	return testProjectKeep, deletionDate, nil
+}
+
+func latestTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func getAnnotation(namespace *gabs.Container, key string) string {
+	value, _ := namespace.Search("metadata", "annotations", key).Data().(string)
+	return value
+}
+
+func getConfigIntOrDefault(key string, defaultValue int) int {
+	value := config.Config().GetInt(key)
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func runTestProjectReaper() {
+	notificationDays := getConfigIntOrDefault("testproject_notification_days", defaultTestProjectNotificationDays)
+	for _, cluster := range getOpenshiftClusters("") {
+		namespaces, err := getNamespaces(cluster.ID)
+		if err != nil {
+			log.Printf("Error getting namespaces on cluster %v: %v", cluster.ID, err)
+			continue
+		}
+		for _, namespace := range namespaces {
+			project, _ := namespace.Path("metadata.name").Data().(string)
+			action, expiry, err := getTestProjectAction(namespace, time.Now(), notificationDays)
+			if err != nil {
+				log.Printf("WARNING: Test project %v on cluster %v: %v", project, cluster.ID, err)
+				continue
+			}
+			switch action {
+			case testProjectNotify:
+				if err := notifyTestProjectExpiry(cluster.ID, project, namespace, expiry); err != nil {
+					log.Printf("Error notifying the requester of test project %v on cluster %v: %v", project, cluster.ID, err)
+				}
+			case testProjectDelete:
+				if err := deleteProjectAndVolumes(cluster.ID, project, schedulerUsername); err != nil {
+					log.Printf("Error deleting test project %v on cluster %v: %v", project, cluster.ID, err)
+				}
+			}
+		}
+	}
+}
+
+func getNamespaces(clusterId string) ([]*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "api/v1/namespaces", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Println("error decoding json:", err, resp.StatusCode)
+		return nil, errors.New(genericAPIError)
+	}
+	return json.S("items").Children(), nil
+}
+
+func notifyTestProjectExpiry(clusterId, project string, namespace *gabs.Container, expiry time.Time) error {
+	requester := getAnnotation(namespace, requesterAnnotation)
+	if requester == "" {
+		return errors.New("The project has no requester")
+	}
+
+	l, err := ldap.New()
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	mail, err := l.GetEmailOfUser(requester)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Test project '%v' on OpenShift will be deleted on %v", project, expiry.Format("02.01.2006"))
+	body := fmt.Sprintf(`
+	Dear %v,
+	<br><br>
+	The following test project will be deleted automatically on %v:
+	<br><br>
+	Cluster: %v<br>
+	Project name:	%v
+	<br><br>
+	All data of the project including its volumes will be lost.
+	If you still need the project, you can extend it in the Cloud Self Service Portal.
+	<br><br>
+	Kind regards<br>
+	Your Cloud Team<br>
+	IT-OM-SDL-CLP
+	`, requester, expiry.Format("02.01.2006"), clusterId, project)
+
+	if err := sendMail(mail, subject, body); err != nil {
+		return err
+	}
+
+	// Remember the notification, so that it is only sent once
+	namespace.Set(time.Now().Format(time.RFC3339), "metadata", "annotations", testProjectNotifiedAnnotation)
+	if err := updateNamespace(clusterId, project, namespace); err != nil {
+		return err
+	}
+	log.Printf("Notified %v about the expiry of test project %v on cluster %v", requester, project, clusterId)
+	return nil
+}
@@ -0,0 +1,71 @@
+package openshift
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+)
+
+func TestGetTestProjectAction(t *testing.T) {
+	now := time.Date(2020, 8, 31, 12, 0, 0, 0, time.UTC)
+	var namespaces = []struct {
+		json     string
+		expected testProjectAction
+	}{
+		// No test project
+		{`{"metadata":{"creationTimestamp":"2020-01-01T00:00:00Z","annotations":{}}}`, testProjectKeep},
+		// Expires in 21 days
+		{`{"metadata":{"creationTimestamp":"2020-08-21T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"30"}}}`, testProjectKeep},
+		// Expires in 5 days
+		{`{"metadata":{"creationTimestamp":"2020-08-05T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"30"}}}`, testProjectNotify},
+		// Expires in 5 days, requester already notified
+		{`{"metadata":{"creationTimestamp":"2020-08-05T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"30","openshift.io/testproject-notified":"2020-08-29T12:00:00Z"}}}`, testProjectKeep},
+		// Expired, but the requester was never notified
+		{`{"metadata":{"creationTimestamp":"2020-07-01T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"30"}}}`, testProjectNotify},
+		// Expired, but the requester was only notified 2 days ago
+		{`{"metadata":{"creationTimestamp":"2020-07-01T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"30","openshift.io/testproject-notified":"2020-08-29T12:00:00Z"}}}`, testProjectKeep},
+		// Expired and notified 8 days ago
+		{`{"metadata":{"creationTimestamp":"2020-07-01T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"30","openshift.io/testproject-notified":"2020-08-23T12:00:00Z"}}}`, testProjectDelete},
+		// Expired, invalid notification date
+		{`{"metadata":{"creationTimestamp":"2020-07-01T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"30","openshift.io/testproject-notified":"yes"}}}`, testProjectNotify},
+		// Expired, but extended
+		{`{"metadata":{"creationTimestamp":"2020-07-01T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"90"}}}`, testProjectKeep},
+	}
+	for _, n := range namespaces {
+		namespace, err := gabs.ParseJSON([]byte(n.json))
+		if err != nil {
+			t.Fatal(err)
+		}
+		action, _, err := getTestProjectAction(namespace, now, 7)
+		if err != nil {
+			t.Errorf("Unexpected error for %v: %v", n.json, err)
+		}
+		if action != n.expected {
+			t.Errorf("Expected action %v for %v, got %v", n.expected, n.json, action)
+		}
+	}
+}
+
+func TestGetTestProjectActionDeletionDate(t *testing.T) {
+	now := time.Date(2020, 8, 31, 12, 0, 0, 0, time.UTC)
+
+	// The requester of an expired project gets the full notification period
+	expired, _ := gabs.ParseJSON([]byte(`{"metadata":{"creationTimestamp":"2020-07-01T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"30"}}}`))
+	if _, date, _ := getTestProjectAction(expired, now, 7); !date.Equal(now.AddDate(0, 0, 7)) {
+		t.Errorf("Expected the deletion in 7 days, got %v", date)
+	}
+
+	// A notified project is deleted when it expires
+	notified, _ := gabs.ParseJSON([]byte(`{"metadata":{"creationTimestamp":"2020-08-05T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"30","openshift.io/testproject-notified":"2020-08-25T12:00:00Z"}}}`))
+	if _, date, _ := getTestProjectAction(notified, now, 7); !date.Equal(time.Date(2020, 9, 4, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the deletion on 04.09.2020, got %v", date)
+	}
+}
+
+func TestGetTestProjectActionInvalidDays(t *testing.T) {
+	namespace, _ := gabs.ParseJSON([]byte(`{"metadata":{"creationTimestamp":"2020-07-01T12:00:00Z","annotations":{"openshift.io/testproject-daystodeletion":"thirty"}}}`))
+	if _, _, err := getTestProjectAction(namespace, time.Now(), 7); err == nil {
+		t.Error("Expected an error for an invalid number of days")
+	}
+}
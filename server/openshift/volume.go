@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"strings"
 	"time"
 
@@ -41,7 +42,7 @@ func newVolumeHandler(c *gin.Context) {
 		}
 
 		// try to get storageclass
-		storageclass, err := getStorageClass(data.ClusterId, data.Technology)
+		storageclass, err := getVolumeStorageClass(data.ClusterId, data.Technology, data.StorageClass)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 			return
@@ -52,7 +53,12 @@ func newVolumeHandler(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
 			return
 		}
-		if data.Technology == "nfs" {
+		if data.Technology == technologyDynamic {
+			c.JSON(http.StatusOK, common.NewVolumeApiResponse{
+				Message: "The volume has been requested. It can be used as soon as it is bound.",
+				Data:    *newVolumeResponse,
+			})
+		} else if data.Technology == "nfs" {
 			// Don't send a message because this only starts a job
 			// and the client polls the server to get the current progress
 			c.JSON(http.StatusOK, common.NewVolumeApiResponse{
@@ -137,6 +143,41 @@ func growVolumeHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, common.ApiResponse{Message: "Volume has been expanded."})
 }
 
+// getVolumesHandler lists the pvcs of the project with their bound pv.
+// Gluster volumes also contain their current usage.
+func getVolumesHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	volumes, err := getProjectVolumes(clusterId, project)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	for i, v := range volumes {
+		if v.Technology != "glusterfs" {
+			continue
+		}
+		// The list is still useful without the usage
+		usage, err := getGlusterVolumeUsage(clusterId, v.PvName)
+		if err != nil {
+			log.Printf("Error getting usage of gluster volume %v on cluster %v: %v", v.PvName, clusterId, err)
+			continue
+		}
+		volumes[i].Usage = usage
+	}
+
+	c.JSON(http.StatusOK, volumes)
+}
+
 func validateNewVolume(clusterId, project, size, pvcName, mode, technology, username string) error {
 	// Required fields
 	if len(project) == 0 || len(pvcName) == 0 || len(size) == 0 || len(mode) == 0 {
@@ -260,20 +301,29 @@ func validateSize(size string) error {
 	return nil
 }
 
-func checkPvcName(clusterId, project, pvcName string) error {
+func getPvcs(clusterId, project string) (*gabs.Container, error) {
 	resp, err := getOseHTTPClient("GET", clusterId, fmt.Sprintf("api/v1/namespaces/%v/persistentvolumeclaims", project), nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	json, err := gabs.ParseJSONBuffer(resp.Body)
 	if err != nil {
 		log.Println("error parsing body of response:", err)
-		return errors.New(genericAPIError)
+		return nil, errors.New(genericAPIError)
+	}
+
+	return json.S("items"), nil
+}
+
+func checkPvcName(clusterId, project, pvcName string) error {
+	pvcs, err := getPvcs(clusterId, project)
+	if err != nil {
+		return err
 	}
 
-	for _, v := range json.S("items").Children() {
+	for _, v := range pvcs.Children() {
 		if v.Path("metadata.name").Data().(string) == pvcName {
 			return fmt.Errorf("The requested persistent volume claim(PVC) name %v already exists.", pvcName)
 		}
@@ -286,13 +336,18 @@ func checkTechnology(technology string) error {
 	switch technology {
 	case
 		"nfs",
-		"gluster":
+		"gluster",
+		technologyDynamic:
 		return nil
 	}
-	return errors.New("Invalid technology. Must be either nfs or gluster")
+	return errors.New("Invalid technology. Must be nfs, gluster or dynamic")
 }
 
 func createNewVolume(clusterId, project, size, pvcName, mode, technology, username, storageclass string) (*common.NewVolumeResponse, error) {
+	if technology == technologyDynamic {
+		return createDynamicVolume(clusterId, project, size, pvcName, mode, username, storageclass)
+	}
+
 	var newVolumeResponse *common.NewVolumeResponse
 	var err error
 	if technology == "nfs" {
@@ -339,7 +394,7 @@ func createGlusterVolume(clusterId, project string, size string, username string
 		return nil, errors.New(genericAPIError)
 	}
 
-	resp, err := getGlusterHTTPClient(clusterId, "sec/volume", b)
+	resp, err := getGlusterHTTPClient("POST", clusterId, "sec/volume", b)
 	if err != nil {
 		return nil, err
 	}
@@ -515,17 +570,53 @@ func growExistingVolume(clusterId string, pv *gabs.Container, newSize string, us
 		if err := growGlusterVolume(clusterId, pv, newSize, username); err != nil {
 			return err
 		}
-		return nil
+		return updatePVCapacity(clusterId, pv, newSize)
 	}
 	if pv.ExistsP("spec.nfs") {
 		if err := growNfsVolume(clusterId, pv, newSize, username); err != nil {
 			return err
 		}
-		return nil
+		return updatePVCapacity(clusterId, pv, newSize)
+	}
+	if isDynamicVolume(clusterId, pv) {
+		return growDynamicVolume(clusterId, pv, newSize, username)
 	}
 	return errors.New("Wrong pv name")
 }
 
+// updatePVCapacity sets the new size on the pv after the backend volume has grown.
+// The pvc of a static volume can't be changed, so the pv holds the current size.
+func updatePVCapacity(clusterId string, pv *gabs.Container, newSize string) error {
+	pv.SetP(newSize, "spec.capacity.storage")
+	return updateOpenshiftPV(clusterId, pv)
+}
+
+// parseVolumeSize parses the size of a volume. The sizes of gluster and nfs volumes
+// are given in M and G, but the backends use binary units, so they are read as Mi and Gi.
+func parseVolumeSize(size string) (float64, error) {
+	size = strings.TrimSpace(size)
+	if strings.HasSuffix(size, "M") || strings.HasSuffix(size, "G") {
+		size += "i"
+	}
+	return parseQuantity(size)
+}
+
+// normalizeVolumeSize converts a PVC size (e.g. 10Gi or 1500M) to the
+// format of new volumes: Megabytes up to 1024M and Gigabytes above.
+// NFS volumes only support Gigabytes. The size is rounded up to the
+// next unit and to the minimum size of 500M.
+func normalizeVolumeSize(size, technology string) (string, error) {
+	value, err := parseVolumeSize(size)
+	if err != nil {
+		return "", err
+	}
+	const mb, gb = 1 << 20, 1 << 30
+	if technology == "nfs" || value > 1024*mb || (value > 0 && math.Mod(value, gb) == 0) {
+		return fmt.Sprintf("%vG", math.Ceil(value/gb)), nil
+	}
+	return fmt.Sprintf("%vM", math.Max(500, math.Ceil(value/mb))), nil
+}
+
 func growNfsVolume(clusterId string, pv *gabs.Container, newSize string, username string) error {
 	nfsPath, ok := pv.Path("spec.nfs.path").Data().(string)
 	if !ok {
@@ -541,7 +632,7 @@ func growNfsVolume(clusterId string, pv *gabs.Container, newSize string, usernam
 		UserInputValues: []common.WorkflowKeyValue{
 			{
 				Key:   "Projectname",
-				Value: strings.Replace(nfsPath, "/v004_0/", "", 1),
+				Value: getNfsProjectName(nfsPath),
 			},
 			{
 				Key:   "newSize",
@@ -592,6 +683,11 @@ func growNfsVolume(clusterId string, pv *gabs.Container, newSize string, usernam
 	return nil
 }
 
+// getNfsProjectName returns the name of the nfs volume used by the workflows
+func getNfsProjectName(nfsPath string) string {
+	return strings.Replace(nfsPath, "/v004_0/", "", 1)
+}
+
 func growGlusterVolume(clusterId string, pv *gabs.Container, newSize string, username string) error {
 	glusterfsPath, ok := pv.Path("spec.glusterfs.path").Data().(string)
 	if !ok {
@@ -614,7 +710,7 @@ func growGlusterVolume(clusterId string, pv *gabs.Container, newSize string, use
 		return errors.New(genericAPIError)
 	}
 
-	resp, err := getGlusterHTTPClient(clusterId, "sec/volume/grow", b)
+	resp, err := getGlusterHTTPClient("POST", clusterId, "sec/volume/grow", b)
 	if err != nil {
 		return err
 	}
@@ -807,3 +903,159 @@ func getGlusterEndpointsContainer(clusterId string) (*gabs.Container, error) {
 
 	return p, nil
 }
+
+// getVolumeBackend returns the storage technology of the pv and where
+// the data is stored (gluster volume or nfs export)
+func getVolumeBackend(pv *gabs.Container) (string, string) {
+	if pv.ExistsP("spec.glusterfs") {
+		path, _ := pv.Path("spec.glusterfs.path").Data().(string)
+		return "glusterfs", path
+	}
+	if pv.ExistsP("spec.nfs") {
+		server, _ := pv.Path("spec.nfs.server").Data().(string)
+		path, _ := pv.Path("spec.nfs.path").Data().(string)
+		return "nfs", fmt.Sprintf("%v:%v", server, path)
+	}
+	storageclass, _ := pv.Path("spec.storageClassName").Data().(string)
+	return "storageclass", storageclass
+}
+
+// getProjectVolumes returns all pvcs of the project with their bound pv
+func getProjectVolumes(clusterId, project string) ([]common.ProjectVolume, error) {
+	pvcs, err := getPvcs(clusterId, project)
+	if err != nil {
+		return nil, err
+	}
+	volumes := []common.ProjectVolume{}
+	for _, pvc := range pvcs.Children() {
+		volume, err := newProjectVolume(clusterId, pvc)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
+
+func newProjectVolume(clusterId string, pvc *gabs.Container) (common.ProjectVolume, error) {
+	volume := common.ProjectVolume{}
+	volume.PvcName, _ = pvc.Path("metadata.name").Data().(string)
+	volume.PvName, _ = pvc.Path("spec.volumeName").Data().(string)
+	volume.Size, _ = pvc.Path("spec.resources.requests.storage").Data().(string)
+	volume.Capacity, _ = pvc.Path("status.capacity.storage").Data().(string)
+	volume.AccessModes = []string{}
+	for _, mode := range pvc.Path("spec.accessModes").Children() {
+		if m, ok := mode.Data().(string); ok {
+			volume.AccessModes = append(volume.AccessModes, m)
+		}
+	}
+	if volume.PvName != "" {
+		pv, err := getOpenshiftPV(clusterId, volume.PvName)
+		if err != nil {
+			return volume, err
+		}
+		volume.Technology, volume.Backend = getVolumeBackend(pv)
+	}
+	return volume, nil
+}
+
+func getGlusterVolumeUsage(clusterId, pvName string) (*common.VolumeUsage, error) {
+	resp, err := getGlusterHTTPClient("GET", clusterId, "volume/"+pvName, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error getting gluster volume info: %v %v", resp.StatusCode, string(errMsg))
+		return nil, fmt.Errorf("Error message from GlusterFS API: %v", string(errMsg))
+	}
+
+	var volInfo models.VolInfo
+	if err := json.NewDecoder(resp.Body).Decode(&volInfo); err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+	return newVolumeUsage(volInfo), nil
+}
+
+// checkGlusterVolumeUsage calls the check endpoint of the gluster api.
+// It answers with 400 if the usage is above the threshold, but returns the usage in both cases.
+func checkGlusterVolumeUsage(clusterId, pvName string, threshold int) (*common.VolumeUsage, error) {
+	resp, err := getGlusterHTTPClient("GET", clusterId, fmt.Sprintf("volume/%v/check?threshold=%v", pvName, threshold), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var check struct {
+		Message string `json:"message"`
+		models.VolInfo
+	}
+	bodyBytes, _ := ioutil.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &check); err != nil {
+		log.Printf("Error checking gluster volume usage: %v %v", resp.StatusCode, string(bodyBytes))
+		return nil, errors.New(genericAPIError)
+	}
+	if check.TotalKiloBytes == 0 {
+		log.Printf("Error checking gluster volume usage: %v %v", resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("Error message from GlusterFS API: %v", check.Message)
+	}
+	return newVolumeUsage(check.VolInfo), nil
+}
+
+func newVolumeUsage(volInfo models.VolInfo) *common.VolumeUsage {
+	usage := &common.VolumeUsage{
+		TotalKiloBytes: volInfo.TotalKiloBytes,
+		UsedKiloBytes:  volInfo.UsedKiloBytes,
+	}
+	if volInfo.TotalKiloBytes > 0 {
+		usage.UsedPercent = math.Round(float64(volInfo.UsedKiloBytes)/float64(volInfo.TotalKiloBytes)*1000) / 10
+	}
+	return usage
+}
+
+func deleteOpenshiftPV(clusterId, pvName, username string) error {
+	resp, err := getOseHTTPClient("DELETE", clusterId, fmt.Sprintf("api/v1/persistentvolumes/%v", pvName), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error deleting pv %v: %v %v", pvName, resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+
+	log.Printf("%v deleted the pv %v on cluster %v", username, pvName, clusterId)
+	return nil
+}
+
+func deleteGlusterVolume(clusterId, volName, username string) error {
+	cmd := models.DeleteVolumeCommand{
+		LvName: volName,
+	}
+
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(cmd); err != nil {
+		log.Println(err.Error())
+		return errors.New(genericAPIError)
+	}
+
+	resp, err := getGlusterHTTPClient("POST", clusterId, "sec/volume/delete", b)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error deleting gluster volume: %v %v", resp.StatusCode, string(errMsg))
+		return fmt.Errorf("Error message from GlusterFS API: %v", string(errMsg))
+	}
+
+	log.Printf("%v deleted gluster volume %v on cluster %v", username, volName, clusterId)
+	return nil
+}
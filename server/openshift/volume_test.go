@@ -0,0 +1,185 @@
+package openshift
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/glusterapi/models"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestGetProjectVolumes(t *testing.T) {
+	config.Init("bla")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/namespaces/app/persistentvolumeclaims":
+			w.Write([]byte(`{"items": [
+				{"metadata": {"name": "data"}, "spec": {"volumeName": "gl-app-pv1", "accessModes": ["ReadWriteMany"],
+				  "resources": {"requests": {"storage": "5G"}}}, "status": {"capacity": {"storage": "5G"}}},
+				{"metadata": {"name": "cache"}, "spec": {"accessModes": ["ReadWriteOnce"],
+				  "resources": {"requests": {"storage": "1G"}}}, "status": {"phase": "Pending"}}
+			]}`))
+		case "/api/v1/persistentvolumes/gl-app-pv1":
+			w.Write([]byte(`{"spec": {"glusterfs": {"path": "vol_app_pv1"}}}`))
+		case "/volume/gl-app-pv1":
+			w.Write([]byte(`{"totalKiloBytes": 5000, "usedKiloBytes": 1234}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	clusters := []map[string]interface{}{
+		{"id": "c1", "name": "C1", "url": server.URL, "token": "token",
+			"glusterapi": map[string]interface{}{"url": server.URL, "secret": "secret"}},
+	}
+	config.Config().Set("openshift", clusters)
+	defer config.Config().Set("openshift", nil)
+
+	volumes, err := getProjectVolumes("c1", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != 2 {
+		t.Fatalf("expected 2 volumes, got %+v", volumes)
+	}
+	data := volumes[0]
+	if data.PvName != "gl-app-pv1" || data.Technology != "glusterfs" || data.Backend != "vol_app_pv1" || data.Capacity != "5G" {
+		t.Errorf("unexpected volume %+v", data)
+	}
+	if len(data.AccessModes) != 1 || data.AccessModes[0] != "ReadWriteMany" {
+		t.Errorf("expected access mode ReadWriteMany, got %v", data.AccessModes)
+	}
+	if cache := volumes[1]; cache.PvName != "" || cache.Technology != "" || cache.Capacity != "" {
+		t.Errorf("expected an unbound volume, got %+v", cache)
+	}
+
+	usage, err := getGlusterVolumeUsage("c1", "gl-app-pv1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.UsedKiloBytes != 1234 || usage.TotalKiloBytes != 5000 || usage.UsedPercent != 24.7 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if _, err := getGlusterVolumeUsage("c1", "gl-other-pv1"); err == nil {
+		t.Error("expected an error for an unknown volume")
+	}
+}
+
+func TestCheckGlusterVolumeUsage(t *testing.T) {
+	config.Init("bla")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/volume/gl-app-pv1/check":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message": "Error used 90 is bigger than threshold: ` + r.URL.Query().Get("threshold") + `", "totalKiloBytes": 1000, "usedKiloBytes": 900}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message": "Error getting volume usage"}`))
+		}
+	}))
+	defer server.Close()
+
+	clusters := []map[string]interface{}{
+		{"id": "c1", "name": "C1", "url": server.URL, "token": "token",
+			"glusterapi": map[string]interface{}{"url": server.URL, "secret": "secret"}},
+	}
+	config.Config().Set("openshift", clusters)
+	defer config.Config().Set("openshift", nil)
+
+	usage, err := checkGlusterVolumeUsage("c1", "gl-app-pv1", 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.UsedKiloBytes != 900 || usage.UsedPercent != 90 {
+		t.Errorf("expected the usage above the threshold, got %+v", usage)
+	}
+	if _, err := checkGlusterVolumeUsage("c1", "gl-other-pv1", 80); err == nil {
+		t.Error("expected an error without usage")
+	}
+}
+
+func TestNewVolumeUsage(t *testing.T) {
+	var tests = []struct {
+		total    int
+		used     int
+		expected float64
+	}{
+		{1000, 500, 50},
+		{3000, 1000, 33.3},
+		{1000, 0, 0},
+		{0, 0, 0},
+	}
+	for _, test := range tests {
+		usage := newVolumeUsage(models.VolInfo{TotalKiloBytes: test.total, UsedKiloBytes: test.used})
+		if usage.UsedPercent != test.expected {
+			t.Errorf("newVolumeUsage(%v, %v): expected %v%%, got %v%%", test.total, test.used, test.expected, usage.UsedPercent)
+		}
+	}
+}
+
+func TestGrowGlusterVolumeUpdatesCapacity(t *testing.T) {
+	config.Init("bla")
+
+	var saved string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/sec/volume/grow":
+			w.Write([]byte(`{}`))
+		case r.Method == "PUT" && r.URL.Path == "/api/v1/persistentvolumes/gl-app-pv1":
+			body, _ := ioutil.ReadAll(r.Body)
+			saved = string(body)
+			w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	clusters := []map[string]interface{}{
+		{"id": "c1", "name": "C1", "url": server.URL, "token": "token",
+			"glusterapi": map[string]interface{}{"url": server.URL, "secret": "secret"}},
+	}
+	config.Config().Set("openshift", clusters)
+	defer config.Config().Set("openshift", nil)
+
+	pv, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "gl-app-pv1"}, "spec": {"capacity": {"storage": "1G"}, "glusterfs": {"path": "vol_app_pv1"}}}`))
+	if err := growExistingVolume("c1", pv, "2G", "u123456"); err != nil {
+		t.Fatal(err)
+	}
+	savedPv, _ := gabs.ParseJSON([]byte(saved))
+	if capacity := savedPv.Path("spec.capacity.storage").Data(); capacity != "2G" {
+		t.Errorf("expected the pv capacity to be 2G, got %v", capacity)
+	}
+}
+
+func TestNormalizeVolumeSize(t *testing.T) {
+	var tests = []struct {
+		size       string
+		technology string
+		expected   string
+	}{
+		{"10Gi", "gluster", "10G"},
+		{"10G", "gluster", "10G"},
+		{"1500M", "gluster", "2G"},
+		{"500Mi", "gluster", "500M"},
+		{"100Mi", "dynamic", "500M"},
+		{"1Gi", "dynamic", "1G"},
+		{"500M", "nfs", "1G"},
+		{"5368709120", "nfs", "5G"},
+	}
+	for _, test := range tests {
+		size, err := normalizeVolumeSize(test.size, test.technology)
+		if err != nil || size != test.expected {
+			t.Errorf("normalizeVolumeSize(%v, %v): expected %v, got %v, %v", test.size, test.technology, test.expected, size, err)
+		}
+	}
+	if _, err := normalizeVolumeSize("lots", "gluster"); err == nil {
+		t.Error("expected an error for an invalid size")
+	}
+}
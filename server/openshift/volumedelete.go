@@ -0,0 +1,296 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Set on the pv while its volume is deleted. The value is the project of the pvc.
+	deletedVolumeLabel = "openshift.io/deleted-volume"
+	// Set on the pv as soon as the gluster or nfs volume is deleted.
+	// The value is the id of the nfs delete job.
+	volumeBackendDeletedAnnotation = "openshift.io/volume-backend-deleted"
+)
+
+// deleteVolumeHandler deletes the pvc, its pv and the gluster or nfs volume behind it.
+// The volume must not be mounted by a pod, unless force=true.
+// A failed deletion is resumed by deleting the pvc again.
+func deleteVolumeHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+	pvcName := params.Get("pvcName")
+	force := params.Get("force") == "true"
+
+	if pvcName == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "PVC name must be provided"})
+		return
+	}
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	volume, err := getVolumeToDelete(clusterId, project, pvcName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	if err := validateDeleteVolume(clusterId, project, volume, force); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	response, err := deleteVolume(clusterId, project, volume, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// getVolumeToDelete returns the volume of the pvc. If the pvc is already
+// gone, the volume of a failed deletion is returned.
+func getVolumeToDelete(clusterId, project, pvcName string) (common.ProjectVolume, error) {
+	pvs, err := getProjectObjects(clusterId, "api/v1/persistentvolumes?labelSelector="+deletedVolumeLabel+"="+project)
+	if err != nil {
+		return common.ProjectVolume{}, err
+	}
+	for _, pv := range pvs {
+		if claimName, _ := pv.Path("spec.claimRef.name").Data().(string); claimName == pvcName {
+			volume := common.ProjectVolume{PvcName: pvcName}
+			volume.PvName, _ = pv.Path("metadata.name").Data().(string)
+			volume.Technology, volume.Backend = getVolumeBackend(pv)
+			return volume, nil
+		}
+	}
+
+	pvc, err := getPvc(clusterId, project, pvcName)
+	if err != nil {
+		return common.ProjectVolume{}, err
+	}
+	return newProjectVolume(clusterId, pvc)
+}
+
+// validateDeleteVolume checks everything which can be checked before
+// anything is deleted, so the volume isn't left half deleted
+func validateDeleteVolume(clusterId, project string, volume common.ProjectVolume, force bool) error {
+	if volume.Technology == "nfs" {
+		if _, err := getNfsDeleteWorkflow(clusterId); err != nil {
+			return err
+		}
+	}
+
+	if force {
+		return nil
+	}
+	pods, err := getProjectObjects(clusterId, "api/v1/namespaces/"+project+"/pods")
+	if err != nil {
+		return err
+	}
+	if mountedBy := getPodsUsingPvc(pods, volume.PvcName); len(mountedBy) > 0 {
+		return fmt.Errorf("The PVC %v is still mounted by the pods %v. Scale down the deployments or delete it with force.", volume.PvcName, strings.Join(mountedBy, ", "))
+	}
+	return nil
+}
+
+// getPodsUsingPvc returns the names of the running pods which mount the pvc
+func getPodsUsingPvc(pods []*gabs.Container, pvcName string) []string {
+	names := []string{}
+	for _, pod := range pods {
+		phase, _ := pod.Path("status.phase").Data().(string)
+		if phase == "Succeeded" || phase == "Failed" {
+			continue
+		}
+		for _, v := range pod.Path("spec.volumes").Children() {
+			if claimName, _ := v.Path("persistentVolumeClaim.claimName").Data().(string); claimName == pvcName {
+				name, _ := pod.Path("metadata.name").Data().(string)
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// deleteVolume deletes the pvc, the backend volume and the pv in this order.
+// The state is stored on the pv, so a failed deletion can be resumed
+// without deleting the backend volume twice.
+// Dynamically provisioned pvs are cleaned up by their storage class.
+func deleteVolume(clusterId, project string, volume common.ProjectVolume, username string) (*common.DeleteVolumeResponse, error) {
+	response := &common.DeleteVolumeResponse{
+		PvcName:    volume.PvcName,
+		PvName:     volume.PvName,
+		Technology: volume.Technology,
+		Backend:    volume.Backend,
+	}
+
+	if volume.PvName == "" || volume.Technology == "storageclass" {
+		if err := deleteOpenshiftPVC(clusterId, project, volume.PvcName, username); err != nil {
+			return nil, err
+		}
+		response.Message = fmt.Sprintf("The PVC %v has been deleted.", volume.PvcName)
+		return response, nil
+	}
+
+	pv, err := markDeletedVolume(clusterId, project, volume.PvName, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := deleteOpenshiftPVC(clusterId, project, volume.PvcName, username); err != nil {
+		return nil, err
+	}
+
+	jobId, deleted := getDeletedVolumeBackend(pv)
+	if !deleted {
+		jobId, err = deleteVolumeBackend(clusterId, volume, username)
+		if err != nil {
+			return nil, fmt.Errorf("The PVC %v has been deleted, but not the volume %v: %v. Delete the PVC again to retry.", volume.PvcName, volume.Backend, err.Error())
+		}
+		pv.Set(strconv.Itoa(jobId), "metadata", "annotations", volumeBackendDeletedAnnotation)
+		if err := updateOpenshiftPV(clusterId, pv); err != nil {
+			log.Printf("WARNING: The deleted volume %v couldn't be marked on the pv %v: %v", volume.Backend, volume.PvName, err)
+		}
+	}
+
+	if err := deleteOpenshiftPV(clusterId, volume.PvName, username); err != nil {
+		return nil, fmt.Errorf("The PVC %v and the volume %v have been deleted, but not the PV %v: %v. Delete the PVC again to retry.", volume.PvcName, volume.Backend, volume.PvName, err.Error())
+	}
+	response.JobId = jobId
+	if jobId != 0 {
+		response.Message = fmt.Sprintf("The PVC %v and the PV %v have been deleted. The nfs volume is being deleted.", volume.PvcName, volume.PvName)
+	} else {
+		response.Message = fmt.Sprintf("The PVC %v, the PV %v and the volume %v have been deleted.", volume.PvcName, volume.PvName, volume.Backend)
+	}
+	return response, nil
+}
+
+// markDeletedVolume labels the pv of a volume being deleted and returns it
+func markDeletedVolume(clusterId, project, pvName, username string) (*gabs.Container, error) {
+	pv, err := getOpenshiftPV(clusterId, pvName)
+	if err != nil {
+		return nil, err
+	}
+	if pv.Exists("metadata", "labels", deletedVolumeLabel) {
+		return pv, nil
+	}
+	pv.Set(project, "metadata", "labels", deletedVolumeLabel)
+	pv.Set(username, "metadata", "annotations", deletionRequesterAnnotation)
+	if err := updateOpenshiftPV(clusterId, pv); err != nil {
+		return nil, err
+	}
+	// The resource version has changed
+	return getOpenshiftPV(clusterId, pvName)
+}
+
+// getDeletedVolumeBackend returns whether the backend volume of the pv
+// has already been deleted and the id of the nfs delete job
+func getDeletedVolumeBackend(pv *gabs.Container) (int, bool) {
+	value, ok := pv.Search("metadata", "annotations", volumeBackendDeletedAnnotation).Data().(string)
+	if !ok {
+		return 0, false
+	}
+	jobId, _ := strconv.Atoi(value)
+	return jobId, true
+}
+
+// deleteVolumeBackend deletes the gluster volume or starts the job
+// deleting the nfs volume. The id of the nfs job is returned.
+func deleteVolumeBackend(clusterId string, volume common.ProjectVolume, username string) (int, error) {
+	switch volume.Technology {
+	case "glusterfs":
+		return 0, deleteGlusterVolume(clusterId, volume.Backend, username)
+	case "nfs":
+		return deleteNfsVolume(clusterId, volume.Backend, username)
+	}
+	return 0, nil
+}
+
+func getNfsDeleteWorkflow(clusterId string) (string, error) {
+	cluster, err := getOpenshiftCluster(clusterId)
+	if err != nil {
+		return "", err
+	}
+	if cluster.NfsApi == nil || cluster.NfsApi.DeleteWorkflowUuid == "" {
+		return "", fmt.Errorf("NFS volumes can't be deleted automatically on cluster %v. Please contact the storage team.", clusterId)
+	}
+	return cluster.NfsApi.DeleteWorkflowUuid, nil
+}
+
+// deleteNfsVolume starts the delete workflow for the nfs export (server:path)
+func deleteNfsVolume(clusterId, export, username string) (int, error) {
+	workflow, err := getNfsDeleteWorkflow(clusterId)
+	if err != nil {
+		return 0, err
+	}
+
+	nfsPath := export[strings.Index(export, ":")+1:]
+	cmd := common.WorkflowCommand{
+		UserInputValues: []common.WorkflowKeyValue{
+			{
+				Key:   "Projectname",
+				Value: getNfsProjectName(nfsPath),
+			},
+		},
+	}
+
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(cmd); err != nil {
+		log.Println(err.Error())
+		return 0, errors.New(genericAPIError)
+	}
+
+	resp, err := getNfsHTTPClient("POST", clusterId, fmt.Sprintf("workflows/%v/jobs", workflow), body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error deleting nfs volume: %v %v", resp.StatusCode, string(errMsg))
+		return 0, errors.New(genericAPIError)
+	}
+
+	job := &common.WorkflowJob{}
+	bodyBytes, _ := ioutil.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, job); err != nil {
+		log.Println("Error unmarshalling workflow job", err.Error())
+		return 0, errors.New(genericAPIError)
+	}
+
+	log.Printf("%v is deleting the nfs volume %v on cluster %v. Job: %v", username, export, clusterId, job.JobId)
+	return job.JobId, nil
+}
+
+func deleteOpenshiftPVC(clusterId, project, pvcName, username string) error {
+	resp, err := getOseHTTPClient("DELETE", clusterId, fmt.Sprintf("api/v1/namespaces/%v/persistentvolumeclaims/%v", project, pvcName), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error deleting pvc %v: %v %v", pvcName, resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+
+	log.Printf("%v deleted the pvc %v in project %v on cluster %v", username, pvcName, project, clusterId)
+	return nil
+}
@@ -0,0 +1,179 @@
+package openshift
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestGetPodsUsingPvc(t *testing.T) {
+	pods, _ := gabs.ParseJSON([]byte(`[
+		{"metadata": {"name": "app-1"}, "status": {"phase": "Running"}, "spec": {"volumes": [
+			{"name": "config", "configMap": {"name": "app"}},
+			{"name": "data", "persistentVolumeClaim": {"claimName": "data"}}
+		]}},
+		{"metadata": {"name": "app-build"}, "status": {"phase": "Succeeded"}, "spec": {"volumes": [
+			{"name": "data", "persistentVolumeClaim": {"claimName": "data"}}
+		]}},
+		{"metadata": {"name": "cache-1"}, "status": {"phase": "Pending"}, "spec": {"volumes": [
+			{"name": "cache", "persistentVolumeClaim": {"claimName": "cache"}}
+		]}}
+	]`))
+
+	var tests = []struct {
+		pvcName  string
+		expected []string
+	}{
+		{"data", []string{"app-1"}},
+		{"cache", []string{"cache-1"}},
+		{"other", []string{}},
+	}
+	for _, test := range tests {
+		names := getPodsUsingPvc(pods.Children(), test.pvcName)
+		if strings.Join(names, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("getPodsUsingPvc(%v): expected %v, got %v", test.pvcName, test.expected, names)
+		}
+	}
+}
+
+func TestDeleteVolume(t *testing.T) {
+	config.Init("bla")
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/api/v1/persistentvolumes/gl-app-pv1":
+			w.Write([]byte(`{"metadata": {"name": "gl-app-pv1"}}`))
+		case "/api/v1/namespaces/app/pods":
+			w.Write([]byte(`{"items": [{"metadata": {"name": "app-1"}, "status": {"phase": "Running"},
+				"spec": {"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "data"}}]}}]}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	clusters := []map[string]interface{}{
+		{"id": "c1", "name": "C1", "url": server.URL, "token": "token",
+			"glusterapi": map[string]interface{}{"url": server.URL, "secret": "secret"}},
+	}
+	config.Config().Set("openshift", clusters)
+	defer config.Config().Set("openshift", nil)
+
+	gluster := common.ProjectVolume{PvcName: "data", PvName: "gl-app-pv1", Technology: "glusterfs", Backend: "vol_app_pv1"}
+	if err := validateDeleteVolume("c1", "app", gluster, false); err == nil || !strings.Contains(err.Error(), "app-1") {
+		t.Errorf("expected the mounted volume to be refused, got %v", err)
+	}
+	if err := validateDeleteVolume("c1", "app", gluster, true); err != nil {
+		t.Errorf("expected a forced deletion to be allowed, got %v", err)
+	}
+	nfs := common.ProjectVolume{PvcName: "files", PvName: "nfs-app-1", Technology: "nfs", Backend: "nfs01:/v004_0/app_1"}
+	if err := validateDeleteVolume("c1", "app", nfs, true); err == nil {
+		t.Error("expected nfs volumes to be refused without delete workflow")
+	}
+
+	requests = nil
+	response, err := deleteVolume("c1", "app", gluster, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"GET /api/v1/persistentvolumes/gl-app-pv1",
+		"PUT /api/v1/persistentvolumes/gl-app-pv1",
+		"GET /api/v1/persistentvolumes/gl-app-pv1",
+		"DELETE /api/v1/namespaces/app/persistentvolumeclaims/data",
+		"POST /sec/volume/delete",
+		"PUT /api/v1/persistentvolumes/gl-app-pv1",
+		"DELETE /api/v1/persistentvolumes/gl-app-pv1",
+	}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected the requests %v, got %v", expected, requests)
+	}
+	if response.PvName != "gl-app-pv1" || response.JobId != 0 {
+		t.Errorf("unexpected response %+v", response)
+	}
+
+	requests = nil
+	dynamic := common.ProjectVolume{PvcName: "db", PvName: "pvc-123", Technology: "storageclass", Backend: "csi-standard"}
+	if _, err := deleteVolume("c1", "app", dynamic, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Errorf("expected only the pvc of a dynamic volume to be deleted, got %v", requests)
+	}
+}
+
+func TestResumeDeleteVolume(t *testing.T) {
+	config.Init("bla")
+
+	pv, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "gl-app-pv1"},
+		"spec": {"glusterfs": {"path": "vol_app_pv1"}, "claimRef": {"namespace": "app", "name": "data"}}}`))
+	glusterDown := true
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.URL.Path == "/api/v1/persistentvolumes":
+			if r.URL.Query().Get("labelSelector") == deletedVolumeLabel+"=app" && pv.Exists("metadata", "labels", deletedVolumeLabel) {
+				w.Write([]byte(`{"items": [` + pv.String() + `]}`))
+			} else {
+				w.Write([]byte(`{"items": []}`))
+			}
+		case r.URL.Path == "/api/v1/persistentvolumes/gl-app-pv1" && r.Method == "PUT":
+			pv, _ = gabs.ParseJSONBuffer(r.Body)
+			w.Write(pv.Bytes())
+		case r.URL.Path == "/api/v1/persistentvolumes/gl-app-pv1" && r.Method == "GET":
+			w.Write(pv.Bytes())
+		case r.URL.Path == "/api/v1/namespaces/app/persistentvolumeclaims/data" && r.Method == "GET":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/sec/volume/delete" && glusterDown:
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	clusters := []map[string]interface{}{
+		{"id": "c1", "name": "C1", "url": server.URL, "token": "token",
+			"glusterapi": map[string]interface{}{"url": server.URL, "secret": "secret"}},
+	}
+	config.Config().Set("openshift", clusters)
+	defer config.Config().Set("openshift", nil)
+
+	gluster := common.ProjectVolume{PvcName: "data", PvName: "gl-app-pv1", Technology: "glusterfs", Backend: "vol_app_pv1"}
+	if _, err := deleteVolume("c1", "app", gluster, "user"); err == nil {
+		t.Fatal("expected an error if the gluster volume can't be deleted")
+	}
+	if contains(requests, "DELETE /api/v1/persistentvolumes/gl-app-pv1") {
+		t.Error("expected the pv to be kept if the gluster volume can't be deleted")
+	}
+
+	// The pvc is gone, the volume is found by the label of the pv
+	volume, err := getVolumeToDelete("c1", "app", "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(volume, gluster) {
+		t.Errorf("expected the volume %+v, got %+v", gluster, volume)
+	}
+
+	glusterDown = false
+	requests = nil
+	if _, err := deleteVolume("c1", "app", volume, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if !contains(requests, "POST /sec/volume/delete") || !contains(requests, "DELETE /api/v1/persistentvolumes/gl-app-pv1") {
+		t.Errorf("expected the gluster volume and the pv to be deleted, got %v", requests)
+	}
+	if backendDeleted, _ := pv.Search("metadata", "annotations", volumeBackendDeletedAnnotation).Data().(string); backendDeleted != "0" {
+		t.Errorf("expected the deleted gluster volume to be marked on the pv, got %v", pv)
+	}
+}
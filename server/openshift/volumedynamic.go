@@ -0,0 +1,230 @@
+package openshift
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// With dynamic provisioning, only the PVC is created and the
+// storage class of the cluster provisions the volume
+const technologyDynamic = "dynamic"
+
+// getVolumeStatusHandler returns the binding and resize status of a PVC
+func getVolumeStatusHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+	project := params.Get("project")
+	pvcName := params.Get("pvcName")
+
+	if pvcName == "" {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "PVC name must be provided"})
+		return
+	}
+
+	if err := validateAdminAccess(clusterId, username, project); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	pvc, err := getPvc(clusterId, project, pvcName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, getVolumeStatus(pvc))
+}
+
+// getDynamicStorageClass checks that the storage class is allowed on the cluster.
+// Without storage class, the first allowed one is used.
+func getDynamicStorageClass(clusterId, storageclass string) (string, error) {
+	cluster, err := getOpenshiftCluster(clusterId)
+	if err != nil {
+		return "", err
+	}
+	if len(cluster.DynamicStorageClasses) == 0 {
+		return "", fmt.Errorf("Dynamic provisioning is not available on cluster %v", clusterId)
+	}
+	if storageclass == "" {
+		return cluster.DynamicStorageClasses[0], nil
+	}
+	if !contains(cluster.DynamicStorageClasses, storageclass) {
+		return "", fmt.Errorf("The storage class %v is not allowed. Allowed storage classes: %v", storageclass, strings.Join(cluster.DynamicStorageClasses, ", "))
+	}
+	return storageclass, nil
+}
+
+// getVolumeStorageClass returns the storage class for new volumes of the technology
+func getVolumeStorageClass(clusterId, technology, storageclass string) (string, error) {
+	if technology == technologyDynamic {
+		return getDynamicStorageClass(clusterId, storageclass)
+	}
+	return getStorageClass(clusterId, technology)
+}
+
+func isDynamicVolume(clusterId string, pv *gabs.Container) bool {
+	if pv.Exists("spec", "glusterfs") || pv.Exists("spec", "nfs") {
+		return false
+	}
+	cluster, err := getOpenshiftCluster(clusterId)
+	if err != nil {
+		return false
+	}
+	storageclass, _ := pv.Path("spec.storageClassName").Data().(string)
+	return contains(cluster.DynamicStorageClasses, storageclass)
+}
+
+func createDynamicVolume(clusterId, project, size, pvcName, mode, username, storageclass string) (*common.NewVolumeResponse, error) {
+	if err := createOpenShiftPVC(clusterId, project, size, pvcName, mode, username, storageclass); err != nil {
+		return nil, err
+	}
+	return &common.NewVolumeResponse{}, nil
+}
+
+// growDynamicVolume increases the requested size of the PVC.
+// The storage class must allow volume expansion.
+func growDynamicVolume(clusterId string, pv *gabs.Container, newSize string, username string) error {
+	storageclass, _ := pv.Path("spec.storageClassName").Data().(string)
+	project, _ := pv.Path("spec.claimRef.namespace").Data().(string)
+	pvcName, _ := pv.Path("spec.claimRef.name").Data().(string)
+
+	expandable, err := isStorageClassExpandable(clusterId, storageclass)
+	if err != nil {
+		return err
+	}
+	if !expandable {
+		return fmt.Errorf("The storage class %v doesn't support volume expansion", storageclass)
+	}
+
+	pvc, err := getPvc(clusterId, project, pvcName)
+	if err != nil {
+		return err
+	}
+	currentSize, _ := pvc.Path("spec.resources.requests.storage").Data().(string)
+	if err := validateNewVolumeSize(currentSize, newSize); err != nil {
+		return err
+	}
+
+	patch := []common.JsonPatch{
+		{
+			Operation: "replace",
+			Path:      "/spec/resources/requests/storage",
+			Value:     newSize,
+		},
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		log.Printf("Error marshalling patch: %v", err)
+		return errors.New(genericAPIError)
+	}
+
+	url := fmt.Sprintf("api/v1/namespaces/%v/persistentvolumeclaims/%v", project, pvcName)
+	resp, err := getOseHTTPClient("PATCH", clusterId, url, bytes.NewBuffer(patchBytes))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error expanding pvc %v in project %v: %v %v", pvcName, project, resp.StatusCode, string(errMsg))
+		return errors.New(genericAPIError)
+	}
+
+	log.Printf("%v expanded the pvc %v in project %v on cluster %v from %v to %v", username, pvcName, project, clusterId, currentSize, newSize)
+	return nil
+}
+
+// validateNewVolumeSize checks that the volume is grown
+func validateNewVolumeSize(currentSize, newSize string) error {
+	current, err := parseQuantity(currentSize)
+	if err != nil {
+		return err
+	}
+	wanted, err := parseQuantity(newSize)
+	if err != nil {
+		return err
+	}
+	if wanted <= current {
+		return fmt.Errorf("The new size must be bigger than the current size of %v", currentSize)
+	}
+	return nil
+}
+
+func isStorageClassExpandable(clusterId, storageclass string) (bool, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, "apis/storage.k8s.io/v1/storageclasses/"+storageclass, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error getting storage class %v: %v %v", storageclass, resp.StatusCode, string(errMsg))
+		return false, errors.New(genericAPIError)
+	}
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return false, errors.New(genericAPIError)
+	}
+	expandable, _ := json.S("allowVolumeExpansion").Data().(bool)
+	return expandable, nil
+}
+
+func getPvc(clusterId, project, pvcName string) (*gabs.Container, error) {
+	resp, err := getOseHTTPClient("GET", clusterId, fmt.Sprintf("api/v1/namespaces/%v/persistentvolumeclaims/%v", project, pvcName), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("The PVC %v doesn't exist", pvcName)
+	}
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := ioutil.ReadAll(resp.Body)
+		log.Printf("Error getting pvc %v: %v %v", pvcName, resp.StatusCode, string(errMsg))
+		return nil, errors.New(genericAPIError)
+	}
+
+	json, err := gabs.ParseJSONBuffer(resp.Body)
+	if err != nil {
+		log.Printf(jsonDecodingError, err)
+		return nil, errors.New(genericAPIError)
+	}
+	return json, nil
+}
+
+// getVolumeStatus describes whether the PVC is bound and whether a resize is in progress
+func getVolumeStatus(pvc *gabs.Container) common.VolumeStatus {
+	status := common.VolumeStatus{}
+	status.PvcName, _ = pvc.Path("metadata.name").Data().(string)
+	status.Phase, _ = pvc.Path("status.phase").Data().(string)
+	status.PvName, _ = pvc.Path("spec.volumeName").Data().(string)
+	status.StorageClass, _ = pvc.Path("spec.storageClassName").Data().(string)
+	status.RequestedSize, _ = pvc.Path("spec.resources.requests.storage").Data().(string)
+	status.Capacity, _ = pvc.Path("status.capacity.storage").Data().(string)
+	for _, condition := range pvc.Path("status.conditions").Children() {
+		t, _ := condition.S("type").Data().(string)
+		if t == "Resizing" || t == "FileSystemResizePending" {
+			status.Resizing = true
+			status.Message, _ = condition.S("message").Data().(string)
+		}
+	}
+	if status.Phase == "Pending" && status.Message == "" {
+		status.Message = "The volume is being provisioned. Some storage classes only provision it when a pod uses the PVC."
+	}
+	return status
+}
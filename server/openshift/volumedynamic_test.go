@@ -0,0 +1,100 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestGetDynamicStorageClass(t *testing.T) {
+	config.Init("bla")
+	clusters := []map[string]interface{}{
+		{"id": "dynamic", "dynamicstorageclasses": []string{"csi-standard", "csi-fast"}},
+		{"id": "static"},
+	}
+	config.Config().Set("openshift", clusters)
+	defer config.Config().Set("openshift", nil)
+
+	var tests = []struct {
+		clusterId    string
+		storageclass string
+		expected     string
+		valid        bool
+	}{
+		{"dynamic", "", "csi-standard", true},
+		{"dynamic", "csi-fast", "csi-fast", true},
+		{"dynamic", "glusterfs-storage", "", false},
+		{"static", "", "", false},
+	}
+	for _, test := range tests {
+		storageclass, err := getDynamicStorageClass(test.clusterId, test.storageclass)
+		if (err == nil) != test.valid || storageclass != test.expected {
+			t.Errorf("getDynamicStorageClass(%v, %v): expected %q (valid=%v), got %q, %v", test.clusterId, test.storageclass, test.expected, test.valid, storageclass, err)
+		}
+	}
+
+	pv := func(j string) *gabs.Container {
+		c, err := gabs.ParseJSON([]byte(j))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	if !isDynamicVolume("dynamic", pv(`{"spec": {"storageClassName": "csi-fast", "csi": {}}}`)) {
+		t.Error("expected a volume of an allowed storage class to be dynamic")
+	}
+	if isDynamicVolume("dynamic", pv(`{"spec": {"storageClassName": "csi-fast", "nfs": {"path": "/x"}}}`)) {
+		t.Error("expected an nfs volume not to be dynamic")
+	}
+	if isDynamicVolume("dynamic", pv(`{"spec": {"storageClassName": "other"}}`)) {
+		t.Error("expected a volume of another storage class not to be dynamic")
+	}
+}
+
+func TestValidateNewVolumeSize(t *testing.T) {
+	var tests = []struct {
+		current string
+		wanted  string
+		valid   bool
+	}{
+		{"1G", "2G", true},
+		{"500M", "1G", true},
+		{"10Gi", "20G", true},
+		{"2G", "2G", false},
+		{"2G", "1G", false},
+		{"1G", "abc", false},
+	}
+	for _, test := range tests {
+		if err := validateNewVolumeSize(test.current, test.wanted); (err == nil) != test.valid {
+			t.Errorf("validateNewVolumeSize(%v, %v): expected valid=%v, got %v", test.current, test.wanted, test.valid, err)
+		}
+	}
+}
+
+func TestGetVolumeStatus(t *testing.T) {
+	pending, _ := gabs.ParseJSON([]byte(`{
+		"metadata": {"name": "data"},
+		"spec": {"storageClassName": "csi-standard", "resources": {"requests": {"storage": "1G"}}},
+		"status": {"phase": "Pending"}
+	}`))
+	status := getVolumeStatus(pending)
+	if status.PvcName != "data" || status.Phase != "Pending" || status.StorageClass != "csi-standard" || status.Message == "" {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	resizing, _ := gabs.ParseJSON([]byte(`{
+		"metadata": {"name": "data"},
+		"spec": {"volumeName": "pvc-123", "resources": {"requests": {"storage": "2G"}}},
+		"status": {"phase": "Bound", "capacity": {"storage": "1G"}, "conditions": [
+			{"type": "FileSystemResizePending", "message": "Waiting for user to (re-)start a pod"}
+		]}
+	}`))
+	status = getVolumeStatus(resizing)
+	if !status.Resizing || status.PvName != "pvc-123" || status.Capacity != "1G" || status.RequestedSize != "2G" {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if status.Message != "Waiting for user to (re-)start a pod" {
+		t.Errorf("expected the condition message, got %v", status.Message)
+	}
+}
@@ -0,0 +1,379 @@
+package openshift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/ldap"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Comma separated percentages replacing volume_usage_thresholds for the volume
+	volumeUsageThresholdsAnnotation = "openshift.io/volume-usage-thresholds"
+	volumeUsageSilencedAnnotation   = "openshift.io/volume-usage-silenced-until"
+	// The highest threshold the admins have been notified about
+	volumeUsageNotifiedAnnotation = "openshift.io/volume-usage-notified"
+	// The last samples as json array
+	volumeUsageHistoryAnnotation = "openshift.io/volume-usage-history"
+
+	defaultVolumeUsageHistorySize = 168
+)
+
+var defaultVolumeUsageThresholds = []int{80, 90}
+
+// getVolumeUsageHandler returns the usage history and alerting settings of a gluster volume
+func getVolumeUsageHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+	params := c.Request.URL.Query()
+	clusterId := params.Get("clusterid")
+
+	pv, err := getVolumeForAlerting(clusterId, params.Get("pvName"), username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, newVolumeUsageReport(pv))
+}
+
+// updateVolumeAlertingHandler overrides the thresholds of a volume and silences its alerts
+func updateVolumeAlertingHandler(c *gin.Context) {
+	username := common.GetUserName(c)
+
+	var data common.VolumeAlertingCommand
+	if c.BindJSON(&data) != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: wrongAPIUsageError})
+		return
+	}
+	if err := validateVolumeUsageThresholds(data.Thresholds); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+	if data.SilenceHours < 0 {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: "The silence duration can't be negative"})
+		return
+	}
+
+	pv, err := getVolumeForAlerting(data.ClusterId, data.PvName, username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	setVolumeAlerting(pv, data.Thresholds, data.SilenceHours, time.Now())
+	if err := updateOpenshiftPV(data.ClusterId, pv); err != nil {
+		c.JSON(http.StatusBadRequest, common.ApiResponse{Message: err.Error()})
+		return
+	}
+
+	log.Printf("%v changed the usage alerting of pv %v on cluster %v: thresholds %v, silenced for %v hours", username, data.PvName, data.ClusterId, data.Thresholds, data.SilenceHours)
+	c.JSON(http.StatusOK, newVolumeUsageReport(pv))
+}
+
+// getVolumeForAlerting returns the gluster pv if the user administers its project
+func getVolumeForAlerting(clusterId, pvName, username string) (*gabs.Container, error) {
+	if clusterId == "" || pvName == "" {
+		return nil, errors.New("Cluster and PV name must be provided")
+	}
+	pv, err := getOpenshiftPV(clusterId, pvName)
+	if err != nil {
+		return nil, err
+	}
+	if !pv.Exists("spec", "glusterfs") {
+		return nil, errors.New("The usage is only monitored for gluster volumes")
+	}
+	project, ok := pv.Path("spec.claimRef.namespace").Data().(string)
+	if !ok {
+		return nil, fmt.Errorf("The PV %v is not bound to a project", pvName)
+	}
+	if err := checkAdminPermissions(clusterId, username, project); err != nil {
+		return nil, err
+	}
+	return pv, nil
+}
+
+func newVolumeUsageReport(pv *gabs.Container) common.VolumeUsageReport {
+	pvName, _ := pv.Path("metadata.name").Data().(string)
+	report := common.VolumeUsageReport{
+		PvName:            pvName,
+		Thresholds:        getVolumeUsageThresholds(pv),
+		NotifiedThreshold: getVolumeUsageNotified(pv),
+		History:           getVolumeUsageHistory(pv),
+	}
+	report.Project, _ = pv.Path("spec.claimRef.namespace").Data().(string)
+	if silencedUntil := getVolumeUsageSilencedUntil(pv); !silencedUntil.IsZero() {
+		report.SilencedUntil = &silencedUntil
+	}
+	return report
+}
+
+// setVolumeAlerting stores the thresholds and the end of the silence in the annotations of the pv.
+// Without thresholds, the default thresholds are used. Without silence hours, the alerts are enabled again.
+func setVolumeAlerting(pv *gabs.Container, thresholds []int, silenceHours int, now time.Time) {
+	if !pv.Exists("metadata", "annotations") {
+		pv.Set(map[string]interface{}{}, "metadata", "annotations")
+	}
+	annotations := pv.Path("metadata.annotations")
+
+	if len(thresholds) == 0 {
+		annotations.Delete(volumeUsageThresholdsAnnotation)
+	} else {
+		values := []string{}
+		for _, t := range thresholds {
+			values = append(values, strconv.Itoa(t))
+		}
+		annotations.Set(strings.Join(values, ","), volumeUsageThresholdsAnnotation)
+	}
+
+	if silenceHours == 0 {
+		annotations.Delete(volumeUsageSilencedAnnotation)
+	} else {
+		annotations.Set(now.Add(time.Duration(silenceHours)*time.Hour).Format(time.RFC3339), volumeUsageSilencedAnnotation)
+	}
+}
+
+func validateVolumeUsageThresholds(thresholds []int) error {
+	for _, t := range thresholds {
+		if t <= 0 || t > 100 {
+			return fmt.Errorf("Invalid threshold %v. Thresholds must be percentages between 1 and 100", t)
+		}
+	}
+	return nil
+}
+
+func parseVolumeUsageThresholds(values []string) ([]int, error) {
+	thresholds := []int{}
+	for _, v := range values {
+		t, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid threshold %v", v)
+		}
+		thresholds = append(thresholds, t)
+	}
+	if err := validateVolumeUsageThresholds(thresholds); err != nil {
+		return nil, err
+	}
+	sort.Ints(thresholds)
+	return thresholds, nil
+}
+
+// getVolumeUsageThresholds returns the thresholds of the pv or else the configured ones
+func getVolumeUsageThresholds(pv *gabs.Container) []int {
+	if value := getAnnotation(pv, volumeUsageThresholdsAnnotation); value != "" {
+		thresholds, err := parseVolumeUsageThresholds(strings.Split(value, ","))
+		if err == nil && len(thresholds) > 0 {
+			return thresholds
+		}
+		pvName, _ := pv.Path("metadata.name").Data().(string)
+		log.Printf("WARNING: Invalid value for %v on pv %v: %v", volumeUsageThresholdsAnnotation, pvName, value)
+	}
+	thresholds, err := parseVolumeUsageThresholds(config.Config().GetStringSlice("volume_usage_thresholds"))
+	if err != nil {
+		log.Printf("WARNING: Invalid value for volume_usage_thresholds: %v", err)
+		return defaultVolumeUsageThresholds
+	}
+	if len(thresholds) == 0 {
+		return defaultVolumeUsageThresholds
+	}
+	return thresholds
+}
+
+func getVolumeUsageSilencedUntil(pv *gabs.Container) time.Time {
+	silencedUntil, err := time.Parse(time.RFC3339, getAnnotation(pv, volumeUsageSilencedAnnotation))
+	if err != nil {
+		return time.Time{}
+	}
+	return silencedUntil
+}
+
+func getVolumeUsageNotified(pv *gabs.Container) int {
+	notified, _ := strconv.Atoi(getAnnotation(pv, volumeUsageNotifiedAnnotation))
+	return notified
+}
+
+// getVolumeUsageAlert returns the highest threshold the usage has crossed and whether
+// the admins must be notified about it. They are notified once per threshold.
+// If the usage drops below the threshold, they are notified again when it is crossed again.
+func getVolumeUsageAlert(usedPercent float64, thresholds []int, notified int, silencedUntil, now time.Time) (int, bool) {
+	crossed := 0
+	for _, t := range thresholds {
+		if usedPercent >= float64(t) {
+			crossed = t
+		}
+	}
+	if crossed <= notified {
+		return crossed, false
+	}
+	if now.Before(silencedUntil) {
+		// Notify when the silence is over
+		return notified, false
+	}
+	return crossed, true
+}
+
+// addVolumeUsageSample stores the sample in the annotations of the pv.
+// Only the last volume_usage_history_size samples are kept.
+func addVolumeUsageSample(pv *gabs.Container, sample common.VolumeUsageSample) {
+	size := getConfigIntOrDefault("volume_usage_history_size", defaultVolumeUsageHistorySize)
+	samples := append(getVolumeUsageHistory(pv), sample)
+	if len(samples) > size {
+		samples = samples[len(samples)-size:]
+	}
+	history, _ := json.Marshal(samples)
+	pv.Set(string(history), "metadata", "annotations", volumeUsageHistoryAnnotation)
+}
+
+func getVolumeUsageHistory(pv *gabs.Container) []common.VolumeUsageSample {
+	samples := []common.VolumeUsageSample{}
+	value := getAnnotation(pv, volumeUsageHistoryAnnotation)
+	if value == "" {
+		return samples
+	}
+	if err := json.Unmarshal([]byte(value), &samples); err != nil {
+		pvName, _ := pv.Path("metadata.name").Data().(string)
+		log.Printf("WARNING: Invalid value for %v on pv %v: %v", volumeUsageHistoryAnnotation, pvName, err)
+		return []common.VolumeUsageSample{}
+	}
+	return samples
+}
+
+// runVolumeUsageMonitor records the usage of all bound gluster volumes
+// and notifies the project admins when a threshold is crossed.
+// NFS volumes are not monitored, because the NFS API doesn't provide their usage.
+func runVolumeUsageMonitor() {
+	for _, cluster := range getOpenshiftClusters("") {
+		if cluster.GlusterApi == nil {
+			continue
+		}
+		pvs, err := getProjectObjects(cluster.ID, "api/v1/persistentvolumes")
+		if err != nil {
+			log.Printf("Error getting pvs on cluster %v: %v", cluster.ID, err)
+			continue
+		}
+		for _, pv := range pvs {
+			phase, _ := pv.Path("status.phase").Data().(string)
+			if !pv.Exists("spec", "glusterfs") || phase != "Bound" {
+				continue
+			}
+			if err := checkVolumeUsage(cluster.ID, pv, time.Now()); err != nil {
+				pvName, _ := pv.Path("metadata.name").Data().(string)
+				log.Printf("Error checking the usage of pv %v on cluster %v: %v", pvName, cluster.ID, err)
+			}
+		}
+	}
+}
+
+func checkVolumeUsage(clusterId string, pv *gabs.Container, now time.Time) error {
+	pvName, _ := pv.Path("metadata.name").Data().(string)
+	project, _ := pv.Path("spec.claimRef.namespace").Data().(string)
+	pvcName, _ := pv.Path("spec.claimRef.name").Data().(string)
+
+	// The lowest threshold is checked, the higher ones are compared with the returned usage
+	thresholds := getVolumeUsageThresholds(pv)
+	usage, err := checkGlusterVolumeUsage(clusterId, pvName, thresholds[0])
+	if err != nil {
+		return err
+	}
+	addVolumeUsageSample(pv, common.VolumeUsageSample{
+		Time:           now,
+		TotalKiloBytes: usage.TotalKiloBytes,
+		UsedKiloBytes:  usage.UsedKiloBytes,
+		UsedPercent:    usage.UsedPercent,
+	})
+
+	notified := getVolumeUsageNotified(pv)
+	crossed, notify := getVolumeUsageAlert(usage.UsedPercent, thresholds, notified, getVolumeUsageSilencedUntil(pv), now)
+	if notify {
+		sent, err := notifyVolumeUsage(clusterId, project, pvcName, pvName, usage, crossed)
+		if err != nil {
+			return err
+		}
+		if !sent {
+			// The admins are notified as soon as the project has some
+			return updateOpenshiftPV(clusterId, pv)
+		}
+	}
+
+	// Remember the notification, so that it is only sent once per threshold
+	if crossed == 0 {
+		pv.Path("metadata.annotations").Delete(volumeUsageNotifiedAnnotation)
+	} else if crossed != notified {
+		pv.Set(strconv.Itoa(crossed), "metadata", "annotations", volumeUsageNotifiedAnnotation)
+	}
+	return updateOpenshiftPV(clusterId, pv)
+}
+
+// notifyVolumeUsage sends the usage alert to the admins of the project.
+// It returns false if the project has no admins to notify.
+func notifyVolumeUsage(clusterId, project, pvcName, pvName string, usage *common.VolumeUsage, threshold int) (bool, error) {
+	admins, _, err := getProjectAdminsAndOperators(clusterId, project)
+	if err != nil {
+		return false, err
+	}
+	if len(admins) == 0 {
+		log.Printf("WARNING: The project %v on cluster %v has no admins to notify about the usage of pv %v: %v%%", project, clusterId, pvName, usage.UsedPercent)
+		return false, nil
+	}
+
+	l, err := ldap.New()
+	if err != nil {
+		return false, err
+	}
+	defer l.Close()
+
+	subject := fmt.Sprintf("Volume '%v' of project '%v' on OpenShift is %v%% full", pvcName, project, usage.UsedPercent)
+	var errs []string
+	for _, admin := range admins {
+		mail, err := l.GetEmailOfUser(admin)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", admin, err.Error()))
+			continue
+		}
+		body := fmt.Sprintf(`
+	Dear %v,
+	<br><br>
+	The following volume has crossed the usage threshold of %v%%:
+	<br><br>
+	Cluster: %v<br>
+	Project name: %v<br>
+	PVC: %v<br>
+	PV: %v<br>
+	Usage: %v%% (%v of %v MB)
+	<br><br>
+	You can increase the size of the volume or silence this alert in the Cloud Self Service Portal.
+	<br><br>
+	Kind regards<br>
+	Your Cloud Team<br>
+	IT-OM-SDL-CLP
+	`, admin, threshold, clusterId, project, pvcName, pvName, usage.UsedPercent, usage.UsedKiloBytes/1024, usage.TotalKiloBytes/1024)
+
+		if err := sendMail(mail, subject, body); err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", admin, err.Error()))
+		}
+	}
+	if len(errs) == len(admins) {
+		return false, fmt.Errorf("No admin could be notified: %v", strings.Join(errs, ", "))
+	}
+	// The others are not notified again to avoid sending the mail twice
+	if len(errs) > 0 {
+		log.Printf("WARNING: The following admins could not be notified: %v", strings.Join(errs, ", "))
+	}
+
+	log.Printf("Notified the admins of project %v on cluster %v about the usage of pv %v: %v%%", project, clusterId, pvName, usage.UsedPercent)
+	return true, nil
+}
+
+func updateOpenshiftPV(clusterId string, pv *gabs.Container) error {
+	pvName, _ := pv.Path("metadata.name").Data().(string)
+	return saveObject(clusterId, "PUT", "api/v1/persistentvolumes/"+pvName, pv)
+}
@@ -0,0 +1,203 @@
+package openshift
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+)
+
+func TestGetVolumeUsageAlert(t *testing.T) {
+	now := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	thresholds := []int{80, 90}
+
+	var tests = []struct {
+		usedPercent   float64
+		notified      int
+		silencedUntil time.Time
+		crossed       int
+		notify        bool
+	}{
+		{50, 0, time.Time{}, 0, false},
+		{80, 0, time.Time{}, 80, true},
+		{85, 80, time.Time{}, 80, false},
+		{95, 80, time.Time{}, 90, true},
+		{95, 90, time.Time{}, 90, false},
+		{85, 90, time.Time{}, 80, false},
+		{50, 90, time.Time{}, 0, false},
+		{95, 0, now.Add(time.Hour), 0, false},
+		{95, 80, now.Add(time.Hour), 80, false},
+		{95, 0, now.Add(-time.Hour), 90, true},
+	}
+	for _, test := range tests {
+		crossed, notify := getVolumeUsageAlert(test.usedPercent, thresholds, test.notified, test.silencedUntil, now)
+		if crossed != test.crossed || notify != test.notify {
+			t.Errorf("getVolumeUsageAlert(%v, notified=%v, silenced=%v): expected %v/%v, got %v/%v", test.usedPercent, test.notified, test.silencedUntil, test.crossed, test.notify, crossed, notify)
+		}
+	}
+}
+
+func TestGetVolumeUsageThresholds(t *testing.T) {
+	config.Init("bla")
+	pv := func(annotations string) *gabs.Container {
+		c, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "gl-app-pv1", "annotations": ` + annotations + `}}`))
+		return c
+	}
+
+	var tests = []struct {
+		configured  []string
+		annotations string
+		expected    []int
+	}{
+		{nil, `{}`, defaultVolumeUsageThresholds},
+		{[]string{"95", "75"}, `{}`, []int{75, 95}},
+		{[]string{"75", "abc"}, `{}`, defaultVolumeUsageThresholds},
+		{[]string{"75"}, `{"openshift.io/volume-usage-thresholds": "60, 98"}`, []int{60, 98}},
+		{[]string{"75"}, `{"openshift.io/volume-usage-thresholds": "120"}`, []int{75}},
+	}
+	for _, test := range tests {
+		config.Config().Set("volume_usage_thresholds", test.configured)
+		if thresholds := getVolumeUsageThresholds(pv(test.annotations)); !reflect.DeepEqual(thresholds, test.expected) {
+			t.Errorf("getVolumeUsageThresholds(%v, %v): expected %v, got %v", test.configured, test.annotations, test.expected, thresholds)
+		}
+	}
+	config.Config().Set("volume_usage_thresholds", nil)
+}
+
+func TestSetVolumeAlerting(t *testing.T) {
+	now := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	pv, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "gl-app-pv1"}}`))
+
+	setVolumeAlerting(pv, []int{70, 95}, 24, now)
+	if thresholds := getVolumeUsageThresholds(pv); !reflect.DeepEqual(thresholds, []int{70, 95}) {
+		t.Errorf("expected the thresholds 70, 95, got %v", thresholds)
+	}
+	if silencedUntil := getVolumeUsageSilencedUntil(pv); !silencedUntil.Equal(now.Add(24 * time.Hour)) {
+		t.Errorf("expected a silence of 24 hours, got %v", silencedUntil)
+	}
+
+	setVolumeAlerting(pv, nil, 0, now)
+	if pv.Exists("metadata", "annotations", volumeUsageThresholdsAnnotation) || pv.Exists("metadata", "annotations", volumeUsageSilencedAnnotation) {
+		t.Errorf("expected the annotations to be removed, got %v", pv.Path("metadata.annotations"))
+	}
+}
+
+func TestAddVolumeUsageSample(t *testing.T) {
+	config.Init("bla")
+	config.Config().Set("volume_usage_history_size", 3)
+	defer config.Config().Set("volume_usage_history_size", nil)
+
+	pv, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "gl-history-pv1"}}`))
+	if history := getVolumeUsageHistory(pv); len(history) != 0 {
+		t.Errorf("expected no history, got %+v", history)
+	}
+	for i := 1; i <= 5; i++ {
+		addVolumeUsageSample(pv, common.VolumeUsageSample{UsedPercent: float64(i)})
+	}
+	history := getVolumeUsageHistory(pv)
+	if len(history) != 3 || history[0].UsedPercent != 3 || history[2].UsedPercent != 5 {
+		t.Errorf("expected the last 3 samples, got %+v", history)
+	}
+
+	invalid, _ := gabs.ParseJSON([]byte(`{"metadata": {"name": "gl-history-pv1", "annotations": {"openshift.io/volume-usage-history": "broken"}}}`))
+	if history := getVolumeUsageHistory(invalid); len(history) != 0 {
+		t.Errorf("expected an invalid history to be ignored, got %+v", history)
+	}
+}
+
+func TestCheckVolumeUsage(t *testing.T) {
+	config.Init("bla")
+
+	var saved string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/volume/gl-app-pv2/check" && r.URL.Query().Get("threshold") == "80":
+			w.Write([]byte(`{"message": "Usage is below threshold", "totalKiloBytes": 1000, "usedKiloBytes": 500}`))
+		case r.Method == "PUT" && r.URL.Path == "/api/v1/persistentvolumes/gl-app-pv2":
+			body, _ := ioutil.ReadAll(r.Body)
+			saved = string(body)
+			w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	clusters := []map[string]interface{}{
+		{"id": "c1", "name": "C1", "url": server.URL, "token": "token",
+			"glusterapi": map[string]interface{}{"url": server.URL, "secret": "secret"}},
+	}
+	config.Config().Set("openshift", clusters)
+	defer config.Config().Set("openshift", nil)
+
+	// The usage dropped below the notified threshold, so the notification is reset
+	pv, _ := gabs.ParseJSON([]byte(`{
+		"metadata": {"name": "gl-app-pv2", "annotations": {"openshift.io/volume-usage-notified": "80"}},
+		"spec": {"glusterfs": {"path": "vol_app_pv2"}, "claimRef": {"namespace": "app", "name": "data"}}
+	}`))
+	now := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	if err := checkVolumeUsage("c1", pv, now); err != nil {
+		t.Fatal(err)
+	}
+	if saved == "" || strings.Contains(saved, volumeUsageNotifiedAnnotation) {
+		t.Errorf("expected the notification to be reset, got %v", saved)
+	}
+	savedPv, _ := gabs.ParseJSON([]byte(saved))
+	history := getVolumeUsageHistory(savedPv)
+	if len(history) != 1 || history[0].UsedPercent != 50 || !history[0].Time.Equal(now) {
+		t.Errorf("expected the sample to be recorded, got %+v", history)
+	}
+}
+
+func TestCheckVolumeUsageWithoutAdmins(t *testing.T) {
+	config.Init("bla")
+
+	var saved string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/volume/gl-app-pv2/check":
+			w.WriteHeader(http.StatusInsufficientStorage)
+			w.Write([]byte(`{"message": "Usage is above threshold", "totalKiloBytes": 1000, "usedKiloBytes": 900}`))
+		case r.URL.Path == "/apis/rbac.authorization.k8s.io/v1/namespaces/app/rolebindings":
+			w.Write([]byte(`{"items": [{"metadata": {"name": "admin"}, "roleRef": {"name": "admin"},
+				"subjects": [{"kind": "ServiceAccount", "name": "deployer", "namespace": "app"}]}]}`))
+		case r.Method == "PUT" && r.URL.Path == "/api/v1/persistentvolumes/gl-app-pv2":
+			body, _ := ioutil.ReadAll(r.Body)
+			saved = string(body)
+			w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	clusters := []map[string]interface{}{
+		{"id": "c1", "name": "C1", "url": server.URL, "token": "token",
+			"glusterapi": map[string]interface{}{"url": server.URL, "secret": "secret"}},
+	}
+	config.Config().Set("openshift", clusters)
+	defer config.Config().Set("openshift", nil)
+
+	pv, _ := gabs.ParseJSON([]byte(`{
+		"metadata": {"name": "gl-app-pv2"},
+		"spec": {"glusterfs": {"path": "vol_app_pv2"}, "claimRef": {"namespace": "app", "name": "data"}}
+	}`))
+	if err := checkVolumeUsage("c1", pv, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// The admins are notified as soon as the project has some
+	if saved == "" || strings.Contains(saved, volumeUsageNotifiedAnnotation) {
+		t.Errorf("expected the history to be saved without notification, got %v", saved)
+	}
+	savedPv, _ := gabs.ParseJSON([]byte(saved))
+	if history := getVolumeUsageHistory(savedPv); len(history) != 1 || history[0].UsedPercent != 90 {
+		t.Errorf("expected the sample to be recorded, got %+v", history)
+	}
+}
@@ -0,0 +1,90 @@
+package sematext
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/common"
+	"github.com/SchweizerischeBundesbahnen/ssp-backend/server/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// FindLogseneAppsByBilling returns the Logsene apps with the accounting number.
+// It is stored in the description as "<accounting number> / <project>".
+func FindLogseneAppsByBilling(billing string) ([]common.BillingResource, error) {
+	// getSematextHTTPClient exits if sematext is not configured
+	cfg := config.Config()
+	if cfg.GetString("sematext_api_token") == "" || cfg.GetString("sematext_base_url") == "" {
+		return nil, errors.New(common.ConfigNotSetError)
+	}
+
+	appData, err := getAllLogseneApps()
+	if err != nil {
+		return nil, err
+	}
+
+	allApps, err := appData.Path("data.apps").Children()
+	if err != nil {
+		log.Println("error getting data inside json", err.Error())
+		return nil, errors.New(genericAPIError)
+	}
+
+	apps := []common.BillingResource{}
+	for _, app := range allApps {
+		if appType, _ := app.Path("appType").Data().(string); appType != "Logsene" {
+			continue
+		}
+		description, _ := app.Path("description").Data().(string)
+		appBilling, _ := parseLogseneBilling(description)
+		if appBilling == "" || !strings.EqualFold(appBilling, billing) {
+			continue
+		}
+		name, _ := app.Path("name").Data().(string)
+		id, _ := app.Path("id").Data().(float64)
+		apps = append(apps, common.BillingResource{
+			Type:     common.BillingResourceLogseneApp,
+			Location: "sematext",
+			Name:     name,
+			Id:       strconv.Itoa(int(id)),
+			Billing:  appBilling,
+		})
+	}
+	return apps, nil
+}
+
+// UpdateLogseneAppBilling changes the accounting number and keeps the project
+func UpdateLogseneAppBilling(username, appId, billing string) error {
+	id, err := strconv.Atoi(appId)
+	if err != nil {
+		return errors.New(wrongAPIUsageError)
+	}
+
+	appData, err := getAllLogseneApps()
+	if err != nil {
+		return err
+	}
+	allApps, err := appData.Path("data.apps").Children()
+	if err != nil {
+		log.Println("error getting data inside json", err.Error())
+		return errors.New(genericAPIError)
+	}
+	for _, app := range allApps {
+		if current, _ := app.Path("id").Data().(float64); int(current) != id {
+			continue
+		}
+		description, _ := app.Path("description").Data().(string)
+		_, project := parseLogseneBilling(description)
+		return updateLogseneBilling(username, billing, project, id)
+	}
+	return errors.New("The Logsene app doesn't exist")
+}
+
+// parseLogseneBilling splits the description into accounting number and project
+func parseLogseneBilling(description string) (string, string) {
+	parts := strings.SplitN(description, " / ", 2)
+	if len(parts) < 2 {
+		return strings.TrimSpace(parts[0]), ""
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
@@ -0,0 +1,22 @@
+package sematext
+
+import "testing"
+
+func TestParseLogseneBilling(t *testing.T) {
+	var tests = []struct {
+		description string
+		billing     string
+		project     string
+	}{
+		{"1234 / app", "1234", "app"},
+		{" 1234 /  app / logs ", "1234", "app / logs"},
+		{"1234", "1234", ""},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		billing, project := parseLogseneBilling(test.description)
+		if billing != test.billing || project != test.project {
+			t.Errorf("parseLogseneBilling(%q): expected %q, %q, got %q, %q", test.description, test.billing, test.project, billing, project)
+		}
+	}
+}